@@ -8,6 +8,11 @@
 
 package chalk
 
+import (
+	"bytes"
+	"encoding/json"
+)
+
 // Key is a string key for a item
 // instead a chalk report or chalk mark
 type Key = string
@@ -22,6 +27,30 @@ const (
 	// created from the operation. Each item
 	// should be of type [Mark]
 	KeyChalks Key = "_CHALKS"
+
+	// KeyArtifactType is the chalk mark key
+	// for the type of the artifact that was
+	// chalked, i.e. "Docker Image"
+	KeyArtifactType Key = "_OP_ARTIFACT_TYPE"
 )
 
 type Report = map[Key]any
+
+// ParseReports parses either a single JSON chalk report
+// or a JSON list of chalk reports from data
+func ParseReports(data []byte) ([]Report, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var reports []Report
+		if err := json.Unmarshal(trimmed, &reports); err != nil {
+			return nil, err
+		}
+		return reports, nil
+	}
+
+	report := make(Report)
+	if err := json.Unmarshal(trimmed, &report); err != nil {
+		return nil, err
+	}
+	return []Report{report}, nil
+}
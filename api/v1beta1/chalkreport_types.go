@@ -0,0 +1,168 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ChalkReportActionIDLabel is the label set on a [ChalkReport]
+	// with the action ID of the report, if it is a valid label value.
+	ChalkReportActionIDLabel = "chalk.ocular.crashoverride.run/action-id"
+)
+
+// ChalkReportSpec defines a chalk report that was received by chalkular.
+type ChalkReportSpec struct {
+	// ActionID is the '_ACTION_ID' of the chalk report
+	// +required
+	ActionID string `json:"actionID"`
+
+	// Source is the intake method the report was received from
+	// +optional
+	Source ChalkReportSource `json:"source,omitzero"`
+
+	// ReceivedTime is when the report was received
+	// +required
+	ReceivedTime metav1.Time `json:"receivedTime"`
+
+	// Report is the gzip compressed JSON chalk report. It is not
+	// set if the report is stored in an object store (see [ReportRef])
+	// or if the compressed report is too large to be stored.
+	// +optional
+	Report []byte `json:"report,omitempty"`
+
+	// ReportRef is a reference to the object the
+	// report was read from, if read from an object store
+	// +optional
+	ReportRef *ChalkReportObjectReference `json:"reportRef,omitempty"`
+}
+
+// ChalkReportSource is the intake method a chalk report was received from
+type ChalkReportSource struct {
+	// Method is the intake method, either 'http' or 'sqs'
+	// +optional
+	Method string `json:"method,omitempty"`
+	// User is the authenticated user that
+	// uploaded the report, when received over HTTP
+	// +optional
+	User string `json:"user,omitempty"`
+	// QueueURL is the URL of the SQS queue
+	// the report was received from
+	// +optional
+	QueueURL string `json:"queueURL,omitempty"`
+	// MessageID is the ID of the SQS
+	// message the report was received in
+	// +optional
+	MessageID string `json:"messageID,omitempty"`
+}
+
+// ChalkReportObjectReference is a reference
+// to an object stored in an object store
+type ChalkReportObjectReference struct {
+	// Bucket is the name of the bucket the object is stored in
+	// +required
+	Bucket string `json:"bucket"`
+	// Key is the key of the object in the bucket
+	// +required
+	Key string `json:"key"`
+}
+
+// ChalkReportStatus defines the observed state of ChalkReport.
+type ChalkReportStatus struct {
+	// Policies are the results of the policies that
+	// matched the report or failed to be evaluated
+	// +optional
+	Policies []ChalkReportPolicyResult `json:"policies,omitempty"`
+
+	// ProcessedTime is when the pipelines for
+	// the report finished being created
+	// +optional
+	ProcessedTime *metav1.Time `json:"processedTime,omitempty"`
+}
+
+// ChalkReportPolicyResult is the result of a
+// single [ChalkReportPolicy] for a chalk report
+type ChalkReportPolicyResult struct {
+	// Name is the name of the policy
+	// +required
+	Name string `json:"name"`
+	// Namespace is the namespace of the policy,
+	// which is where the pipelines are created
+	// +required
+	Namespace string `json:"namespace"`
+	// Pipelines are the names of the pipelines
+	// created by the policy for the report
+	// +optional
+	Pipelines []string `json:"pipelines,omitempty"`
+	// Error is set if the policy failed to be
+	// evaluated or pipelines failed to be created
+	// +optional
+	Error string `json:"error,omitempty"`
+	// Duplicate is true if no pipelines were created, since the
+	// policy already created pipelines for the report recently
+	// +optional
+	Duplicate bool `json:"duplicate,omitempty"`
+	// SkippedPipelines is the number of pipelines not created, since
+	// a pipeline with the same dedup key was created recently
+	// +optional
+	SkippedPipelines int `json:"skippedPipelines,omitempty"`
+	// DeferredPipelines is the number of pipelines added to the backlog,
+	// to be created once the active pipeline threshold or limits allow
+	// +optional
+	DeferredPipelines int `json:"deferredPipelines,omitempty"`
+	// ExcludedPipelines is the number of pipelines not created, since their
+	// target was claimed by an exclusive policy with a higher priority
+	// +optional
+	ExcludedPipelines int `json:"excludedPipelines,omitempty"`
+	// RetryingPipelines is the number of pipelines that failed to be
+	// created with a retryable error, to be created again after a backoff
+	// +optional
+	RetryingPipelines int `json:"retryingPipelines,omitempty"`
+	// AuditedPipelines is the number of pipelines the policy would have
+	// created, which were not created since the policy is in audit mode
+	// +optional
+	AuditedPipelines int `json:"auditedPipelines,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Action ID",type=string,JSONPath=`.spec.actionID`
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source.method`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +genclient
+// +genclient:nonNamespaced
+
+// ChalkReport is a record of a chalk report received by chalkular,
+// along with the policies it matched and the pipelines created for it.
+type ChalkReport struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+
+	// spec is the chalk report that was received
+	// +required
+	Spec ChalkReportSpec `json:"spec"`
+
+	// status is the result of processing the chalk report
+	// +optional
+	Status ChalkReportStatus `json:"status,omitempty,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// ChalkReportList contains a list of ChalkReport
+type ChalkReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ChalkReport `json:"items"`
+}
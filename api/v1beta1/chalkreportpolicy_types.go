@@ -13,6 +13,28 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// PipelineScheduledByLabel is the label set on every pipeline
+	// created by chalkular, with the value [PipelineScheduledByValue].
+	PipelineScheduledByLabel = "chalk.ocular.crashoverride.run/scheduled-by"
+	PipelineScheduledByValue = "chalkular-controller"
+	// PipelinePolicyUIDLabel is the label set on created pipelines with
+	// the UID of the [ChalkReportPolicy] that generated the pipeline.
+	PipelinePolicyUIDLabel = "chalk.ocular.crashoverride.run/policy-uid"
+	// PipelinePolicyLabel is the label set on created pipelines with the name of
+	// the [ChalkReportPolicy] that generated the pipeline, if it is a valid label value.
+	PipelinePolicyLabel = "chalk.ocular.crashoverride.run/policy"
+	// PipelineActionIDLabel is the label set on created pipelines with the action ID of the
+	// chalk report, if it is a valid label value. It is always set as an annotation.
+	PipelineActionIDLabel = "chalk.ocular.crashoverride.run/action-id"
+	// PipelinePolicyGenerationAnnotation is the annotation set on created pipelines with
+	// the generation of the [ChalkReportPolicy] when the pipeline was generated.
+	PipelinePolicyGenerationAnnotation = "chalk.ocular.crashoverride.run/policy-generation"
+	// PipelineEachIndexAnnotation is the annotation set on pipelines created by a policy with
+	// a 'forEach' expression, with the index of the item the pipeline was generated for.
+	PipelineEachIndexAnnotation = "chalk.ocular.crashoverride.run/each-index"
+)
+
 // ChalkReportPolicySpec defines the desired state of ChalkReportPolicy.
 // The policy uses CEL expressions to both match the policy to an incoming
 // Chalk mark event & to dervice parameters for resulting pipelines.
@@ -40,6 +62,113 @@ type ChalkReportPolicySpec struct {
 	// to the chalkular-artifacts cluster downloader.
 	// +required
 	PipelineTemplate v1beta1.PipelineTemplate `json:"pipelineTemplate"`
+
+	// DedupWindow is how long a pipeline created by this policy
+	// prevents another pipeline with the same deduplication key
+	// from being created. A pipeline that has not yet completed
+	// always prevents a duplicate. If not set, the window configured
+	// for the scheduler is used. A window of 0 disables deduplication
+	// for the policy.
+	// +optional
+	DedupWindow *metav1.Duration `json:"dedupWindow,omitempty"`
+
+	// Limits are the limits on the pipelines created by the policy.
+	// +optional
+	Limits *ChalkReportPolicyLimits `json:"limits,omitempty"`
+
+	// Priority orders the policies that match a report, from highest to lowest.
+	// Pending pipelines are also created in the order of the priority of their policy.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// MatchPolicy is either 'Additive' (the default) or 'Exclusive'. Once an
+	// exclusive policy generates a pipeline for a target, the policies in the same
+	// namespace after it (by priority) do not create pipelines for that target.
+	// Additive policies of the same priority as an exclusive policy are after it.
+	// +kubebuilder:validation:Enum=Exclusive;Additive
+	// +kubebuilder:default=Additive
+	// +optional
+	MatchPolicy MatchPolicy `json:"matchPolicy,omitempty"`
+
+	// DeletionPropagation is what happens to the pipelines created by the policy
+	// when the policy is deleted. 'Orphan' (the default) keeps the pipelines.
+	// 'Background' sets an owner reference to the policy on each pipeline, so they are
+	// garbage collected after the policy is deleted. 'Foreground' also sets the owner
+	// reference with blockOwnerDeletion, and the finalizer of the policy deletes the pipelines
+	// labeled with the UID of the policy, so the policy is only removed once they are deleted.
+	// +kubebuilder:validation:Enum=Orphan;Background;Foreground
+	// +kubebuilder:default=Orphan
+	// +optional
+	DeletionPropagation metav1.DeletionPropagation `json:"deletionPropagation,omitempty"`
+
+	// Retention is how long the completed pipelines created by the
+	// policy are kept for, before they are deleted.
+	// +optional
+	Retention *ChalkReportPolicyRetention `json:"retention,omitempty"`
+
+	// Mode is either 'Enforce' (the default) or 'Audit'. Policies in audit mode evaluate
+	// reports as usual, but only record the pipelines they would create as events and
+	// in the audit summary of their status, without creating them.
+	// +kubebuilder:validation:Enum=Enforce;Audit
+	// +kubebuilder:default=Enforce
+	// +optional
+	Mode PolicyMode `json:"mode,omitempty"`
+}
+
+// PolicyMode is whether a [ChalkReportPolicy] creates the pipelines it generates
+type PolicyMode string
+
+const (
+	// PolicyModeEnforce policies create the pipelines they generate
+	PolicyModeEnforce PolicyMode = "Enforce"
+	// PolicyModeAudit policies only record the pipelines they would create
+	PolicyModeAudit PolicyMode = "Audit"
+)
+
+// MatchPolicy is how a [ChalkReportPolicy] matches with
+// the other policies that match the same target
+type MatchPolicy string
+
+const (
+	// MatchPolicyAdditive policies create pipelines for
+	// every target, along with the other policies
+	MatchPolicyAdditive MatchPolicy = "Additive"
+	// MatchPolicyExclusive policies prevent policies with a lower
+	// priority from creating pipelines for the same targets
+	MatchPolicyExclusive MatchPolicy = "Exclusive"
+)
+
+// ChalkReportPolicyLimits are the limits on the
+// pipelines created by a [ChalkReportPolicy]
+type ChalkReportPolicyLimits struct {
+	// MaxActivePipelines is the maximum amount of pipelines created by
+	// the policy that have not yet completed. Once reached, the policy
+	// skips creating pipelines for reports, without affecting other policies.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxActivePipelines *int32 `json:"maxActivePipelines,omitempty"`
+}
+
+// ChalkReportPolicyRetention is how long the completed pipelines
+// created by a [ChalkReportPolicy] are kept for. Pipelines that have
+// not completed are never deleted, and completed pipelines are kept
+// until the [ChalkReportPolicySpec.DedupWindow] has passed.
+type ChalkReportPolicyRetention struct {
+	// SuccessfulTTL is how long a pipeline that completed
+	// successfully is kept for, after it completed.
+	// +optional
+	SuccessfulTTL *metav1.Duration `json:"successfulTTL,omitempty"`
+
+	// FailedTTL is how long a pipeline that
+	// failed is kept for, after it completed.
+	// +optional
+	FailedTTL *metav1.Duration `json:"failedTTL,omitempty"`
+
+	// MaxCompletedPipelines is the maximum amount of completed pipelines of the
+	// policy to keep. Once exceeded, the pipelines that completed first are deleted.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxCompletedPipelines *int32 `json:"maxCompletedPipelines,omitempty"`
 }
 
 type ChalkReportPolicyExtraction struct {
@@ -73,6 +202,47 @@ type ChalkReportPolicyExtraction struct {
 	// return a string map.
 	// +optional
 	ProfileParams *string `json:"profileParams,omitempty"`
+	// Labels is a CEL expression to extract labels
+	// from the chalk report to set on the pipeline.
+	// The expression should return a string map, and
+	// each key and value must be a valid label. The labels
+	// are merged with (and take precedence over) the labels
+	// of the pipeline template.
+	// +optional
+	Labels *string `json:"labels,omitempty"`
+	// Annotations is a CEL expression to extract annotations
+	// from the chalk report to set on the pipeline.
+	// The expression should return a string map, and
+	// each key must be a valid annotation key. The annotations
+	// are merged with (and take precedence over) the annotations
+	// of the pipeline template.
+	// +optional
+	Annotations *string `json:"annotations,omitempty"`
+	// ProfileRef is a CEL expression to select the profile
+	// of the pipeline from the chalk report. The expression
+	// should return a string map with the key 'name' and
+	// (optionally) 'kind'. If 'kind' is not returned, the kind
+	// of the pipeline template profile reference is used.
+	// Parameters of the pipeline template are kept.
+	// +optional
+	ProfileRef *string `json:"profileRef,omitempty"`
+	// DownloaderRef is a CEL expression to select the downloader
+	// of the pipeline from the chalk report. The expression
+	// should return a string map with the key 'name' and
+	// (optionally) 'kind', either 'Downloader' or 'ClusterDownloader'.
+	// If 'kind' is not returned, the kind of the pipeline template
+	// downloader reference is used. Parameters of the pipeline template are kept.
+	// +optional
+	DownloaderRef *string `json:"downloaderRef,omitempty"`
+	// DedupKey is a CEL expression to extract the key used to
+	// deduplicate pipelines, i.e. `each.HASH` or `each._IMAGE_DIGEST`.
+	// The expression should return a non-empty string. Pipelines
+	// of the policy with the same key are not created again within
+	// the [ChalkReportPolicySpec.DedupWindow]. If not set, pipelines
+	// are deduplicated by the action ID of the chalk report, along
+	// with the 'forEach' item and target of the pipeline.
+	// +optional
+	DedupKey *string `json:"dedupKey,omitempty"`
 }
 
 // ChalkReportPolicyStatus defines the observed state of ChalkReportPolicy.
@@ -90,6 +260,97 @@ type ChalkReportPolicyStatus struct {
 	// +listMapKey=type
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Pipelines are the outcomes of the pipelines created by
+	// the policy that have completed, since the policy was created
+	// +optional
+	Pipelines ChalkReportPolicyPipelineOutcomes `json:"pipelines,omitzero"`
+
+	// Audit summarizes the pipelines the policy would have
+	// created for the reports it matched in audit mode
+	// +optional
+	Audit ChalkReportPolicyAuditSummary `json:"audit,omitzero"`
+}
+
+// ChalkReportPolicyAuditSummary summarizes the reports a [ChalkReportPolicy]
+// in audit mode matched, and the pipelines it would have created for them
+type ChalkReportPolicyAuditSummary struct {
+	// MatchedReports is the number of reports the policy matched in audit mode
+	// +optional
+	MatchedReports int64 `json:"matchedReports,omitempty"`
+
+	// Pipelines is the number of pipelines the policy would have created
+	// +optional
+	Pipelines int64 `json:"pipelines,omitempty"`
+
+	// LastMatch is the last report the policy matched in audit mode
+	// +optional
+	LastMatch *ChalkReportPolicyAuditMatch `json:"lastMatch,omitempty"`
+}
+
+// ChalkReportPolicyAuditMatch is a report matched by a [ChalkReportPolicy] in audit mode
+type ChalkReportPolicyAuditMatch struct {
+	// ActionID is the action ID of the report
+	// +required
+	ActionID string `json:"actionID"`
+
+	// Time is when the report was matched
+	// +required
+	Time metav1.Time `json:"time"`
+
+	// Pipelines is the number of pipelines the policy would have created for the report
+	// +optional
+	Pipelines int `json:"pipelines,omitempty"`
+
+	// Targets are the targets of the pipelines the policy would have created,
+	// formatted as 'identifier@version', limited to the first 20 targets
+	// +optional
+	Targets []string `json:"targets,omitempty"`
+}
+
+// ChalkReportPolicyPipelineOutcomes are the outcomes of the
+// completed pipelines created by a [ChalkReportPolicy]
+type ChalkReportPolicyPipelineOutcomes struct {
+	// Succeeded is the number of pipelines that completed successfully
+	// +optional
+	Succeeded int64 `json:"succeeded,omitempty"`
+
+	// Failed is the number of pipelines that completed with a failure
+	// +optional
+	Failed int64 `json:"failed,omitempty"`
+
+	// LastSuccessTime is when the last successful pipeline completed
+	// +optional
+	LastSuccessTime *metav1.Time `json:"lastSuccessTime,omitempty"`
+
+	// LastFailure is the last pipeline that completed with a failure
+	// +optional
+	LastFailure *ChalkReportPolicyPipelineFailure `json:"lastFailure,omitempty"`
+
+	// LastDuration is how long the last completed pipeline ran for,
+	// from when it started until it completed
+	// +optional
+	LastDuration *metav1.Duration `json:"lastDuration,omitempty"`
+}
+
+// ChalkReportPolicyPipelineFailure is a pipeline
+// created by a [ChalkReportPolicy] that failed
+type ChalkReportPolicyPipelineFailure struct {
+	// Pipeline is the name of the pipeline
+	// +required
+	Pipeline string `json:"pipeline"`
+
+	// CompletionTime is when the pipeline completed
+	// +required
+	CompletionTime metav1.Time `json:"completionTime"`
+
+	// Reason is the reason of the pipeline's 'Complete' condition, if set
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is the message of the pipeline's 'Complete' condition, if set
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
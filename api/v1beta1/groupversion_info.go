@@ -38,6 +38,7 @@ var (
 
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
+		&ChalkReport{}, &ChalkReportList{},
 		&ChalkReportPolicy{}, &ChalkReportPolicyList{},
 	)
 
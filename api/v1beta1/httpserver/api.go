@@ -8,7 +8,12 @@
 
 package httpserver
 
-import "errors"
+import (
+	"errors"
+	"time"
+
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+)
 
 var (
 	ErrUnauthenticated = errors.New("unable to authenticate user")
@@ -22,7 +27,99 @@ type APIResponse[T any] struct {
 	Message  string `json:"message,omitempty,omitzero" yaml:"message,omitempty,omitzero"`
 }
 
+// ReportResults are the results of processing uploaded chalk reports.
 type ReportResults struct {
-	Success []string
-	Failed  []string
+	// Success are the action IDs of the reports that were processed without errors
+	Success []string `json:"success" yaml:"success"`
+	// Failed are the action IDs of the reports that had a policy fail
+	// to be evaluated or fail to create pipelines. Reports without
+	// an action ID are only included in Reports.
+	Failed []string `json:"failed" yaml:"failed"`
+	// Reports are the results of each report, in the order they were uploaded
+	Reports []ReportResult `json:"reports" yaml:"reports"`
+}
+
+// ReportResult is the result of processing a single chalk report.
+type ReportResult struct {
+	ActionID string         `json:"actionID,omitempty" yaml:"actionID,omitempty"`
+	Policies []PolicyResult `json:"policies,omitempty" yaml:"policies,omitempty"`
+	Error    string         `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ReportStatus is the scheduling status of a chalk report received by chalkular.
+// ProcessedTime is not set until the pipelines for the report have been created.
+type ReportStatus struct {
+	// Name is the name of the ChalkReport resource recording the report
+	Name          string         `json:"name" yaml:"name"`
+	ActionID      string         `json:"actionID" yaml:"actionID"`
+	Source        string         `json:"source,omitempty" yaml:"source,omitempty"`
+	ReceivedTime  time.Time      `json:"receivedTime" yaml:"receivedTime"`
+	ProcessedTime *time.Time     `json:"processedTime,omitempty" yaml:"processedTime,omitempty"`
+	Policies      []PolicyResult `json:"policies,omitempty" yaml:"policies,omitempty"`
+}
+
+// PolicyResult is the result of a single ChalkReportPolicy that
+// matched a chalk report, or failed to be evaluated against it.
+type PolicyResult struct {
+	Policy    string   `json:"policy" yaml:"policy"`
+	Namespace string   `json:"namespace" yaml:"namespace"`
+	Pipelines []string `json:"pipelines,omitempty" yaml:"pipelines,omitempty"`
+	Error     string   `json:"error,omitempty" yaml:"error,omitempty"`
+	// Duplicate is true if the policy skipped the report, since
+	// it already created pipelines for the report recently
+	Duplicate bool `json:"duplicate,omitempty" yaml:"duplicate,omitempty"`
+	// SkippedPipelines is the number of pipelines the policy
+	// skipped, since their dedup key was used recently
+	SkippedPipelines int `json:"skippedPipelines,omitempty" yaml:"skippedPipelines,omitempty"`
+	// DeferredPipelines is the number of pipelines added to the backlog,
+	// to be created once there is capacity
+	DeferredPipelines int `json:"deferredPipelines,omitempty" yaml:"deferredPipelines,omitempty"`
+	// ExcludedPipelines is the number of pipelines the policy did not
+	// create, since their target was claimed by an exclusive policy
+	ExcludedPipelines int `json:"excludedPipelines,omitempty" yaml:"excludedPipelines,omitempty"`
+	// RetryingPipelines is the number of pipelines that failed to be
+	// created, which are created again after a backoff
+	RetryingPipelines int `json:"retryingPipelines,omitempty" yaml:"retryingPipelines,omitempty"`
+	// AuditedPipelines is the number of pipelines the policy would
+	// have created, since it is in audit mode
+	AuditedPipelines int `json:"auditedPipelines,omitempty" yaml:"auditedPipelines,omitempty"`
+}
+
+// PolicyEvaluation is the result of a dry-run evaluation of a
+// single ChalkReportPolicy against a single chalk report.
+type PolicyEvaluation struct {
+	ActionID  string `json:"actionID" yaml:"actionID"`
+	Policy    string `json:"policy" yaml:"policy"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Matched   bool   `json:"matched" yaml:"matched"`
+	// Audit is true if the policy is in audit mode,
+	// in which case the pipelines would not be created
+	Audit     bool                     `json:"audit,omitempty" yaml:"audit,omitempty"`
+	Values    []PipelineValues         `json:"values,omitempty" yaml:"values,omitempty"`
+	Pipelines []ocularv1beta1.Pipeline `json:"pipelines,omitempty" yaml:"pipelines,omitempty"`
+	Error     string                   `json:"error,omitempty" yaml:"error,omitempty"`
+	// ExcludedBy are the exclusive policies that claimed the targets of
+	// the values removed from the evaluation, which are not in Values
+	ExcludedBy []string `json:"excludedBy,omitempty" yaml:"excludedBy,omitempty"`
+}
+
+// PipelineValues are the values extracted by a ChalkReportPolicy
+// for a single pipeline.
+type PipelineValues struct {
+	Target           ocularv1beta1.Target             `json:"target" yaml:"target"`
+	DownloaderParams []ocularv1beta1.ParameterSetting `json:"downloaderParams,omitempty" yaml:"downloaderParams,omitempty"`
+	ProfileParams    []ocularv1beta1.ParameterSetting `json:"profileParams,omitempty" yaml:"profileParams,omitempty"`
+	Labels           map[string]string                `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations      map[string]string                `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	ProfileRef       *ObjectReference                 `json:"profileRef,omitempty" yaml:"profileRef,omitempty"`
+	DownloaderRef    *ObjectReference                 `json:"downloaderRef,omitempty" yaml:"downloaderRef,omitempty"`
+	DedupKey         string                           `json:"dedupKey,omitempty" yaml:"dedupKey,omitempty"`
+	EachIndex        *int                             `json:"eachIndex,omitempty" yaml:"eachIndex,omitempty"`
+}
+
+// ObjectReference is a profile or downloader
+// reference extracted by a ChalkReportPolicy.
+type ObjectReference struct {
+	Name string `json:"name" yaml:"name"`
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
 }
@@ -17,6 +17,80 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChalkReport) DeepCopyInto(out *ChalkReport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReport.
+func (in *ChalkReport) DeepCopy() *ChalkReport {
+	if in == nil {
+		return nil
+	}
+	out := new(ChalkReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ChalkReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChalkReportList) DeepCopyInto(out *ChalkReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ChalkReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportList.
+func (in *ChalkReportList) DeepCopy() *ChalkReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(ChalkReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ChalkReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChalkReportObjectReference) DeepCopyInto(out *ChalkReportObjectReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportObjectReference.
+func (in *ChalkReportObjectReference) DeepCopy() *ChalkReportObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ChalkReportObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ChalkReportPolicy) DeepCopyInto(out *ChalkReportPolicy) {
 	*out = *in
@@ -44,6 +118,47 @@ func (in *ChalkReportPolicy) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChalkReportPolicyAuditMatch) DeepCopyInto(out *ChalkReportPolicyAuditMatch) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportPolicyAuditMatch.
+func (in *ChalkReportPolicyAuditMatch) DeepCopy() *ChalkReportPolicyAuditMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(ChalkReportPolicyAuditMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChalkReportPolicyAuditSummary) DeepCopyInto(out *ChalkReportPolicyAuditSummary) {
+	*out = *in
+	if in.LastMatch != nil {
+		in, out := &in.LastMatch, &out.LastMatch
+		*out = new(ChalkReportPolicyAuditMatch)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportPolicyAuditSummary.
+func (in *ChalkReportPolicyAuditSummary) DeepCopy() *ChalkReportPolicyAuditSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(ChalkReportPolicyAuditSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ChalkReportPolicyExtraction) DeepCopyInto(out *ChalkReportPolicyExtraction) {
 	*out = *in
@@ -62,6 +177,31 @@ func (in *ChalkReportPolicyExtraction) DeepCopyInto(out *ChalkReportPolicyExtrac
 		*out = new(string)
 		**out = **in
 	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = new(string)
+		**out = **in
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = new(string)
+		**out = **in
+	}
+	if in.ProfileRef != nil {
+		in, out := &in.ProfileRef, &out.ProfileRef
+		*out = new(string)
+		**out = **in
+	}
+	if in.DownloaderRef != nil {
+		in, out := &in.DownloaderRef, &out.DownloaderRef
+		*out = new(string)
+		**out = **in
+	}
+	if in.DedupKey != nil {
+		in, out := &in.DedupKey, &out.DedupKey
+		*out = new(string)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportPolicyExtraction.
@@ -74,6 +214,26 @@ func (in *ChalkReportPolicyExtraction) DeepCopy() *ChalkReportPolicyExtraction {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChalkReportPolicyLimits) DeepCopyInto(out *ChalkReportPolicyLimits) {
+	*out = *in
+	if in.MaxActivePipelines != nil {
+		in, out := &in.MaxActivePipelines, &out.MaxActivePipelines
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportPolicyLimits.
+func (in *ChalkReportPolicyLimits) DeepCopy() *ChalkReportPolicyLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(ChalkReportPolicyLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ChalkReportPolicyList) DeepCopyInto(out *ChalkReportPolicyList) {
 	*out = *in
@@ -106,11 +266,121 @@ func (in *ChalkReportPolicyList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChalkReportPolicyPipelineFailure) DeepCopyInto(out *ChalkReportPolicyPipelineFailure) {
+	*out = *in
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportPolicyPipelineFailure.
+func (in *ChalkReportPolicyPipelineFailure) DeepCopy() *ChalkReportPolicyPipelineFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(ChalkReportPolicyPipelineFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChalkReportPolicyPipelineOutcomes) DeepCopyInto(out *ChalkReportPolicyPipelineOutcomes) {
+	*out = *in
+	if in.LastSuccessTime != nil {
+		in, out := &in.LastSuccessTime, &out.LastSuccessTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastFailure != nil {
+		in, out := &in.LastFailure, &out.LastFailure
+		*out = new(ChalkReportPolicyPipelineFailure)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastDuration != nil {
+		in, out := &in.LastDuration, &out.LastDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportPolicyPipelineOutcomes.
+func (in *ChalkReportPolicyPipelineOutcomes) DeepCopy() *ChalkReportPolicyPipelineOutcomes {
+	if in == nil {
+		return nil
+	}
+	out := new(ChalkReportPolicyPipelineOutcomes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChalkReportPolicyResult) DeepCopyInto(out *ChalkReportPolicyResult) {
+	*out = *in
+	if in.Pipelines != nil {
+		in, out := &in.Pipelines, &out.Pipelines
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportPolicyResult.
+func (in *ChalkReportPolicyResult) DeepCopy() *ChalkReportPolicyResult {
+	if in == nil {
+		return nil
+	}
+	out := new(ChalkReportPolicyResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChalkReportPolicyRetention) DeepCopyInto(out *ChalkReportPolicyRetention) {
+	*out = *in
+	if in.SuccessfulTTL != nil {
+		in, out := &in.SuccessfulTTL, &out.SuccessfulTTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.FailedTTL != nil {
+		in, out := &in.FailedTTL, &out.FailedTTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxCompletedPipelines != nil {
+		in, out := &in.MaxCompletedPipelines, &out.MaxCompletedPipelines
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportPolicyRetention.
+func (in *ChalkReportPolicyRetention) DeepCopy() *ChalkReportPolicyRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(ChalkReportPolicyRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ChalkReportPolicySpec) DeepCopyInto(out *ChalkReportPolicySpec) {
 	*out = *in
 	in.Extraction.DeepCopyInto(&out.Extraction)
 	in.PipelineTemplate.DeepCopyInto(&out.PipelineTemplate)
+	if in.DedupWindow != nil {
+		in, out := &in.DedupWindow, &out.DedupWindow
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = new(ChalkReportPolicyLimits)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(ChalkReportPolicyRetention)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportPolicySpec.
@@ -133,6 +403,8 @@ func (in *ChalkReportPolicyStatus) DeepCopyInto(out *ChalkReportPolicyStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	in.Pipelines.DeepCopyInto(&out.Pipelines)
+	in.Audit.DeepCopyInto(&out.Audit)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportPolicyStatus.
@@ -144,3 +416,71 @@ func (in *ChalkReportPolicyStatus) DeepCopy() *ChalkReportPolicyStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChalkReportSource) DeepCopyInto(out *ChalkReportSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportSource.
+func (in *ChalkReportSource) DeepCopy() *ChalkReportSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ChalkReportSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChalkReportSpec) DeepCopyInto(out *ChalkReportSpec) {
+	*out = *in
+	out.Source = in.Source
+	in.ReceivedTime.DeepCopyInto(&out.ReceivedTime)
+	if in.Report != nil {
+		in, out := &in.Report, &out.Report
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReportRef != nil {
+		in, out := &in.ReportRef, &out.ReportRef
+		*out = new(ChalkReportObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportSpec.
+func (in *ChalkReportSpec) DeepCopy() *ChalkReportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ChalkReportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChalkReportStatus) DeepCopyInto(out *ChalkReportStatus) {
+	*out = *in
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]ChalkReportPolicyResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ProcessedTime != nil {
+		in, out := &in.ProcessedTime, &out.ProcessedTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChalkReportStatus.
+func (in *ChalkReportStatus) DeepCopy() *ChalkReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ChalkReportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
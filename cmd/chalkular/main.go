@@ -0,0 +1,248 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	"github.com/crashappsec/chalkular/api/v1beta1/httpserver"
+	"github.com/crashappsec/chalkular/internal/policy"
+	"github.com/crashappsec/chalkular/internal/reports"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/diff"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	version   = "unknown"
+	buildTime = "unknown"
+	gitCommit = "unknown"
+)
+
+const usage = `chalkular is a CLI for working with chalkular resources outside of a cluster.
+
+Usage:
+  chalkular policy test [flags]   evaluate ChalkReportPolicies against chalk report files
+  chalkular version               print the version information
+`
+
+// stringsFlag is a [flag.Value] that can be set multiple times
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func main() {
+	args := os.Args[1:]
+	switch {
+	case len(args) >= 2 && args[0] == "policy" && args[1] == "test":
+		if err := policyTest(args[2:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+	case len(args) == 1 && args[0] == "version":
+		fmt.Printf("version: %s\nbuild-time: %s\ngit-commit: %s\n", version, buildTime, gitCommit)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// policyTest evaluates all the policies against all the reports read
+// from the files given by args. If an expected file is given, the results
+// are compared against its contents and an error is returned if they differ.
+func policyTest(args []string, out io.Writer) error {
+	var policyFiles, reportFiles stringsFlag
+	var expectedFile, outputFile, sourceFile string
+	var maxPipelinesPerPolicy int
+	var costLimit uint64
+	var evalTimeout time.Duration
+	var allowUnknownKeys bool
+
+	fs := flag.NewFlagSet("policy test", flag.ContinueOnError)
+	fs.Var(&policyFiles, "policy",
+		"Path to a YAML file containing one or more ChalkReportPolicy resources. Can be repeated.")
+	fs.Var(&reportFiles, "report",
+		"Path to a JSON file containing a chalk report or list of chalk reports. Can be repeated.")
+	fs.StringVar(&expectedFile, "expected", "",
+		"Path to a YAML file containing the expected results. If set, the command "+
+			"will fail if the results differ.")
+	fs.StringVar(&sourceFile, "source", "",
+		"Path to a YAML or JSON file containing the source the reports are evaluated as being "+
+			"received from (the CEL variable 'source'). If not set, the source is empty.")
+	fs.StringVar(&outputFile, "output", "",
+		"Path to write the results to, instead of stdout. "+
+			"The written file can be used as the expected results for future runs.")
+	fs.IntVar(&maxPipelinesPerPolicy, "max-pipelines-per-policy", 20,
+		"Set the limit to the amount of pipelines one policy can generated (max length of forEach result)."+
+			"A negative number or 0 indicates no maximum should exist.")
+	fs.Uint64Var(&costLimit, "policy-cost-limit", 0,
+		"The maximum CEL runtime cost of evaluating a single policy expression. 0 indicates no limit.")
+	fs.DurationVar(&evalTimeout, "policy-eval-timeout", 0,
+		"The maximum time to evaluate the expressions of a policy for a single chalk report. 0 indicates no timeout.")
+	fs.BoolVar(&allowUnknownKeys, "policy-allow-unknown-keys", false,
+		"Declare the report variable of policies as map(string, dyn) instead of typing it with the well-known chalk keys, "+
+			"so that keys that are not well-known (i.e. report._MY_KEY) do not fail to compile.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if len(policyFiles) == 0 || len(reportFiles) == 0 {
+		return errors.New("at least one --policy and one --report file must be given")
+	}
+
+	var policies []chalkularv1beta1.ChalkReportPolicy
+	for _, f := range policyFiles {
+		ps, err := readPolicies(f)
+		if err != nil {
+			return fmt.Errorf("reading policy file %s: %w", f, err)
+		}
+		policies = append(policies, ps...)
+	}
+
+	var chalkReports []chalk.Report
+	for _, f := range reportFiles {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("reading report file %s: %w", f, err)
+		}
+		rs, err := chalk.ParseReports(data)
+		if err != nil {
+			return fmt.Errorf("parsing report file %s: %w", f, err)
+		}
+		chalkReports = append(chalkReports, rs...)
+	}
+
+	var source policy.Source
+	if sourceFile != "" {
+		data, err := os.ReadFile(sourceFile)
+		if err != nil {
+			return fmt.Errorf("reading source file %s: %w", sourceFile, err)
+		}
+		if err = yaml.Unmarshal(data, &source); err != nil {
+			return fmt.Errorf("parsing source file %s: %w", sourceFile, err)
+		}
+	}
+
+	compiler, err := policy.NewCompiler(len(policies), policy.CompilerOptions{
+		CostLimit:        costLimit,
+		EvalTimeout:      evalTimeout,
+		AllowUnknownKeys: allowUnknownKeys,
+	})
+	if err != nil {
+		return fmt.Errorf("creating policy compiler: %w", err)
+	}
+
+	// compile all the policies first, so an invalid policy fails the
+	// command instead of only being reported in its evaluations
+	for i := range policies {
+		if _, err = compiler.Compile(&policies[i]); err != nil {
+			return fmt.Errorf("compiling policy %s: %w", policies[i].Name, err)
+		}
+	}
+
+	received := make([]reports.ReceivedReport, 0, len(chalkReports))
+	for _, report := range chalkReports {
+		received = append(received, reports.ReceivedReport{Report: report, Source: source})
+	}
+
+	// the policies are read from files, so are not reconciled and the
+	// profiles and downloaders of the pipelines can not be validated
+	evaluator := reports.NewEvaluator(compiler, reports.EvaluatorOptions{
+		MaxPipelinesPerPolicy: maxPipelinesPerPolicy,
+		IgnoreReady:           true,
+	})
+	evaluations, err := evaluator.Evaluate(context.Background(), policies, received)
+	if err != nil {
+		return err
+	}
+	results := make([]httpserver.PolicyEvaluation, 0, len(evaluations))
+	for _, e := range evaluations {
+		results = append(results, e.ToAPI())
+	}
+
+	// round trip the results to match what would be
+	// read from the expected file
+	output, err := yaml.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("marshalling results: %w", err)
+	}
+
+	if outputFile != "" {
+		if err = os.WriteFile(outputFile, output, 0o644); err != nil {
+			return fmt.Errorf("writing output file %s: %w", outputFile, err)
+		}
+	} else if _, err = out.Write(output); err != nil {
+		return err
+	}
+
+	if expectedFile == "" {
+		return nil
+	}
+
+	var actual, expected []httpserver.PolicyEvaluation
+	if err = yaml.Unmarshal(output, &actual); err != nil {
+		return fmt.Errorf("parsing results: %w", err)
+	}
+	expectedData, err := os.ReadFile(expectedFile)
+	if err != nil {
+		return fmt.Errorf("reading expected file %s: %w", expectedFile, err)
+	}
+	if err = yaml.Unmarshal(expectedData, &expected); err != nil {
+		return fmt.Errorf("parsing expected file %s: %w", expectedFile, err)
+	}
+
+	if !equality.Semantic.DeepEqual(expected, actual) {
+		return fmt.Errorf("results do not match expected results in %s (-expected +actual):\n%s",
+			expectedFile, diff.Diff(expected, actual))
+	}
+	return nil
+}
+
+// readPolicies reads all ChalkReportPolicy resources
+// from a (possibly multi-document) YAML file
+func readPolicies(path string) ([]chalkularv1beta1.ChalkReportPolicy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var policies []chalkularv1beta1.ChalkReportPolicy
+	decoder := utilyaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		var p chalkularv1beta1.ChalkReportPolicy
+		if err = decoder.Decode(&p); err != nil {
+			if errors.Is(err, io.EOF) {
+				return policies, nil
+			}
+			return nil, err
+		}
+		if p.Kind != "" && p.Kind != "ChalkReportPolicy" {
+			continue
+		}
+		policies = append(policies, p)
+	}
+}
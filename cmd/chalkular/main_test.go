@@ -0,0 +1,155 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/api/v1beta1/httpserver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+)
+
+const testPolicies = `
+apiVersion: chalk.ocular.crashoverride.run/v1beta1
+kind: ChalkReportPolicy
+metadata:
+  name: images
+  namespace: scans
+spec:
+  matchCondition: "true"
+  extraction:
+    target: "{'identifier': report._ACTION_ID, 'version': 'latest'}"
+  pipelineTemplate:
+    spec:
+      profileRef:
+        name: profile
+      downloaderRef:
+        name: downloader
+---
+apiVersion: chalk.ocular.crashoverride.run/v1beta1
+kind: ChalkReportPolicy
+metadata:
+  name: audit
+  namespace: scans
+spec:
+  mode: Audit
+  matchCondition: "true"
+  extraction:
+    forEach: "['a', 'b', 'c']"
+    target: "{'identifier': each, 'version': 'latest'}"
+  pipelineTemplate:
+    spec:
+      profileRef:
+        name: profile
+      downloaderRef:
+        name: downloader
+`
+
+const testReport = `{"_ACTION_ID": "a1b2c3d4"}`
+
+// writeTestFile writes the contents to a file in a temporary directory, returning its path
+func writeTestFile(name, contents string) string {
+	path := filepath.Join(GinkgoT().TempDir(), name)
+	Expect(os.WriteFile(path, []byte(contents), 0o644)).To(Succeed())
+	return path
+}
+
+var _ = Describe("policy test", func() {
+	var policyFile, reportFile string
+
+	BeforeEach(func() {
+		policyFile = writeTestFile("policies.yaml", testPolicies)
+		reportFile = writeTestFile("report.json", testReport)
+	})
+
+	It("should print the evaluation of each policy against the report", func() {
+		out := &bytes.Buffer{}
+		Expect(policyTest([]string{"--policy", policyFile, "--report", reportFile}, out)).To(Succeed())
+
+		var results []httpserver.PolicyEvaluation
+		Expect(yaml.Unmarshal(out.Bytes(), &results)).To(Succeed())
+		Expect(results).To(HaveLen(2))
+
+		By("rendering the pipelines as the scheduler would")
+		Expect(results[0]).To(And(
+			HaveField("Policy", "images"),
+			HaveField("ActionID", "a1b2c3d4"),
+			HaveField("Matched", true),
+			HaveField("Audit", false),
+		))
+		Expect(results[0].Pipelines).To(HaveLen(1))
+		Expect(results[0].Pipelines[0].Labels).To(And(
+			HaveKeyWithValue(chalkularv1beta1.PipelineScheduledByLabel, chalkularv1beta1.PipelineScheduledByValue),
+			HaveKey("chalk.ocular.crashoverride.run/dedup-key"),
+		))
+
+		By("marking policies in audit mode")
+		Expect(results[1]).To(And(
+			HaveField("Policy", "audit"),
+			HaveField("Audit", true),
+			HaveField("Pipelines", HaveLen(3)),
+		))
+	})
+
+	It("should report a policy that exceeds the pipeline limit", func() {
+		out := &bytes.Buffer{}
+		Expect(policyTest([]string{"--policy", policyFile, "--report", reportFile,
+			"--max-pipelines-per-policy", "2"}, out)).To(Succeed())
+
+		var results []httpserver.PolicyEvaluation
+		Expect(yaml.Unmarshal(out.Bytes(), &results)).To(Succeed())
+		Expect(results[1].Error).To(Equal("3 pipelines were generated for action a1b2c3d4, which exceeds the limit of 2"))
+	})
+
+	It("should fail for a policy that does not compile", func() {
+		invalid := writeTestFile("invalid.yaml", `
+apiVersion: chalk.ocular.crashoverride.run/v1beta1
+kind: ChalkReportPolicy
+metadata:
+  name: invalid
+spec:
+  matchCondition: "report._ACTION_ID +"
+`)
+		err := policyTest([]string{"--policy", invalid, "--report", reportFile}, &bytes.Buffer{})
+		Expect(err).To(MatchError(ContainSubstring("compiling policy invalid")))
+	})
+
+	It("should fail for a report without an action ID", func() {
+		report := writeTestFile("report.json", `{"_CHALKS": []}`)
+		err := policyTest([]string{"--policy", policyFile, "--report", report}, &bytes.Buffer{})
+		Expect(err).To(MatchError(ContainSubstring("report 0 is missing or has an invalid key _ACTION_ID")))
+	})
+
+	Context("with expected results", func() {
+		var expectedFile string
+
+		BeforeEach(func() {
+			expectedFile = filepath.Join(GinkgoT().TempDir(), "expected.yaml")
+			Expect(policyTest([]string{"--policy", policyFile, "--report", reportFile,
+				"--output", expectedFile}, &bytes.Buffer{})).To(Succeed())
+		})
+
+		It("should succeed if the results match the written output", func() {
+			Expect(policyTest([]string{"--policy", policyFile, "--report", reportFile,
+				"--expected", expectedFile}, &bytes.Buffer{})).To(Succeed())
+		})
+
+		It("should fail if the results differ", func() {
+			report := writeTestFile("report.json", `{"_ACTION_ID": "e5f6a7b8"}`)
+			err := policyTest([]string{"--policy", policyFile, "--report", report,
+				"--expected", expectedFile}, &bytes.Buffer{})
+			Expect(err).To(MatchError(ContainSubstring("results do not match expected results")))
+		})
+	})
+})
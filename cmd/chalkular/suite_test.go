@@ -0,0 +1,34 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package main
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// These tests use Ginkgo (BDD-style Go testing framework). Refer to
+// http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
+
+func TestCLI(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	// Create custom configs
+	suiteConfig, reporterConfig := GinkgoConfiguration()
+
+	reporterConfig.Verbose = true
+
+	reporterConfig.FullTrace = true
+
+	// reporterConfig.VeryVerbose = true
+
+	RunSpecs(t, "CLI Suite", suiteConfig, reporterConfig)
+}
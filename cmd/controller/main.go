@@ -11,9 +11,12 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -78,7 +81,18 @@ func main() {
 	var secureReportHTTP bool
 	var sqsQueueURL, sqsParser string
 	var rejectReportPipelineThreshold int
+	var policyCostLimit uint64
+	var policyEvalTimeout time.Duration
+	var policyAllowUnknownKeys bool
 	var schedulerMaxPipelinesPerPolicy int
+	var schedulerWorkers int
+	var dedupWindow time.Duration
+	var reportHistoryTTL time.Duration
+	var reportQueueBackend, reportQueueDir, reportQueueNamespace string
+	var maxPendingPipelines int
+	var allowMemoryBacklog bool
+	var pipelineCreateRetries int
+	var pipelineCreateRetryBackoff time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -127,6 +141,47 @@ func main() {
 		"Set the limit to the amount of pipelines one policy can generated (max length of forEach result)."+
 			"A negative number or 0 indicates no maximum should exist.",
 	)
+	flag.IntVar(&schedulerWorkers, "scheduler-workers", 1,
+		"The amount of queued reports the scheduler processes concurrently. Reports with the same action ID, "+
+			"or that match the same policy, still have their pipelines created in the order they were received.")
+	flag.DurationVar(&dedupWindow, "dedup-window", 0,
+		"The duration after a policy creates a pipeline, that pipelines with the same dedup key "+
+			"(by default the action ID and target) are not created again, for policies that do not set spec.dedupWindow. "+
+			"0 disables deduplication.")
+	flag.DurationVar(&reportHistoryTTL, "report-history-ttl", 7*24*time.Hour,
+		"The duration after a report is processed that its ChalkReport recording it is deleted. "+
+			"0 keeps recorded reports forever.")
+	flag.StringVar(&reportQueueBackend, "report-queue", reports.QueueBackendMemory,
+		"The queue that received reports are stored in until processed. Either \"memory\" "+
+			"(reports are lost if the controller restarts), \"file\" (stored in --report-queue-dir) or "+
+			"\"configmap\" (stored as ConfigMaps in --report-queue-namespace)")
+	flag.StringVar(&reportQueueDir, "report-queue-dir", "/var/lib/chalkular/queue",
+		"The directory to store queued reports in when --report-queue=file, i.e. a mounted persistent volume")
+	flag.StringVar(&reportQueueNamespace, "report-queue-namespace", "chalkular-system",
+		"The namespace to store queued reports in when --report-queue=configmap")
+	flag.IntVar(&maxPendingPipelines, "max-pending-pipelines", 0,
+		"The maximum amount of pipelines deferred until the active pipeline threshold or limits allow them to be "+
+			"created. Pending pipelines are stored in the same way as --report-queue. Once reached, new reports "+
+			"are rejected. 0 disables deferring, so that reports are rejected at the threshold.")
+	flag.BoolVar(&allowMemoryBacklog, "allow-memory-backlog", false,
+		"If set, pipelines can be deferred with --report-queue=memory, in which case pending pipelines are lost "+
+			"if the controller restarts. SQS messages with deferred pipelines are then left on the queue to be redelivered.")
+	flag.IntVar(&pipelineCreateRetries, "pipeline-create-retries", 5,
+		"The maximum amount of times creating a pipeline is retried after a retryable error, such as a conflict, "+
+			"timeout or unavailable webhook. Pipelines that fail with a permanent error (i.e. an invalid spec) "+
+			"or exceed the retries are abandoned. 0 disables retries.")
+	flag.DurationVar(&pipelineCreateRetryBackoff, "pipeline-create-retry-backoff", time.Second,
+		"The delay before the first retry of creating a pipeline, which doubles for each retry up to 5 minutes.")
+	flag.Uint64Var(&policyCostLimit, "policy-cost-limit", 0,
+		"The maximum CEL runtime cost of evaluating a single policy expression. "+
+			"Expressions estimated to always exceed the limit are rejected. 0 indicates no limit.")
+	flag.DurationVar(&policyEvalTimeout, "policy-eval-timeout", 0,
+		"The maximum time to evaluate the expressions of a policy for a single chalk report. "+
+			"0 indicates no timeout.")
+	flag.BoolVar(&policyAllowUnknownKeys, "policy-allow-unknown-keys", false,
+		"Declare the report variable of policies as map(string, dyn) instead of typing it with the well-known chalk keys, "+
+			"so that keys that are not well-known (i.e. report._MY_KEY) are not rejected. "+
+			"Keeps policies written before chalk reports were typed working.")
 	opts := zap.Options{}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
@@ -219,16 +274,47 @@ func main() {
 		os.Exit(1)
 	}
 
-	policyCompiler, err := policy.NewCompiler(1024)
+	policyCompiler, err := policy.NewCompiler(1024, policy.CompilerOptions{
+		CostLimit:        policyCostLimit,
+		EvalTimeout:      policyEvalTimeout,
+		AllowUnknownKeys: policyAllowUnknownKeys,
+	})
 	if err != nil {
 		setupLog.Error(err, "unable to construct policy compiler")
 		os.Exit(1)
 	}
 
+	reportQueue, err := configureReportQueue(mgr, reportQueueBackend, reportQueueDir, reportQueueNamespace)
+	if err != nil {
+		setupLog.Error(err, "unable to construct report queue")
+		os.Exit(1)
+	}
+
+	backlogStore, err := configureBacklogStore(mgr, reportQueueBackend, reportQueueDir, reportQueueNamespace)
+	if err != nil {
+		setupLog.Error(err, "unable to construct pending pipeline backlog")
+		os.Exit(1)
+	}
+	if maxPendingPipelines > 0 && !reports.IsDurableBacklogStore(backlogStore) && !allowMemoryBacklog {
+		setupLog.Error(errors.New("pending pipelines would be lost if the controller restarts"),
+			"unable to defer pipelines with --report-queue=memory, set --allow-memory-backlog to allow it")
+		os.Exit(1)
+	}
+
 	scheduler, err := reports.NewScheduler(mgr,
 		policyCompiler,
-		rejectReportPipelineThreshold,
-		schedulerMaxPipelinesPerPolicy)
+		reportQueue,
+		reports.SchedulerOptions{
+			RejectPipelineThreshold: rejectReportPipelineThreshold,
+			MaxPipelinesPerPolicy:   schedulerMaxPipelinesPerPolicy,
+			Workers:                 schedulerWorkers,
+			DedupWindow:             dedupWindow,
+			ReportHistoryTTL:        reportHistoryTTL,
+			Backlog:                 backlogStore,
+			MaxPendingPipelines:     maxPendingPipelines,
+			CreateRetries:           pipelineCreateRetries,
+			CreateRetryBackoff:      pipelineCreateRetryBackoff,
+		})
 	if err != nil {
 		setupLog.Error(err, "unable to construct report scheduler")
 		os.Exit(1)
@@ -297,9 +383,24 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "ChalkReportPolicy")
 		os.Exit(1)
 	}
+	if err := (&controller.PipelineReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Pipeline")
+		os.Exit(1)
+	}
+	if err := (&controller.PipelineRetentionReconciler{
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		DedupWindow: dedupWindow,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PipelineRetention")
+		os.Exit(1)
+	}
 	// nolint:goconst
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
-		if err := webhookv1beta1.SetupChalkReportPolicyWebhookWithManager(mgr, clusterDownloaderName); err != nil {
+		if err := webhookv1beta1.SetupChalkReportPolicyWebhookWithManager(mgr, clusterDownloaderName, policyCompiler); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "ChalkReportPolicy")
 			os.Exit(1)
 		}
@@ -351,3 +452,33 @@ func configureSQSListener(cfg aws.Config, sc reports.SchedulerClient, q, p strin
 	return reportSQSListener, nil
 
 }
+
+func configureReportQueue(mgr ctrl.Manager, backend, dir, namespace string) (reports.Queue, error) {
+	setupLog.Info("configuring report queue", "backend", backend)
+
+	switch backend {
+	case reports.QueueBackendMemory:
+		return reports.NewMemoryQueue(), nil
+	case reports.QueueBackendFile:
+		return reports.NewFileQueue(dir)
+	case reports.QueueBackendConfigMap:
+		return reports.NewConfigMapQueue(mgr.GetClient(), mgr.GetAPIReader(), namespace), nil
+	default:
+		return nil, fmt.Errorf("unknown report queue %s", backend)
+	}
+}
+
+// configureBacklogStore returns the store for pending pipelines,
+// which are stored in the same way as the report queue
+func configureBacklogStore(mgr ctrl.Manager, backend, dir, namespace string) (reports.BacklogStore, error) {
+	switch backend {
+	case reports.QueueBackendMemory:
+		return reports.NewMemoryBacklogStore(), nil
+	case reports.QueueBackendFile:
+		return reports.NewFileBacklogStore(filepath.Join(dir, "pipelines"))
+	case reports.QueueBackendConfigMap:
+		return reports.NewConfigMapBacklogStore(mgr.GetClient(), mgr.GetAPIReader(), namespace), nil
+	default:
+		return nil, fmt.Errorf("unknown report queue %s", backend)
+	}
+}
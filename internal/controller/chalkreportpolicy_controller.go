@@ -10,6 +10,8 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
 	"github.com/crashappsec/chalkular/internal/policy"
@@ -48,7 +50,14 @@ func (r *ChalkReportPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
 // +kubebuilder:rbac:groups=ocular.crashoverride.run,resources=pipelines,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 
-const policyCacheFinalizer = "chalk.ocular.crashoverride.run/cel-cache"
+const (
+	// policyCacheFinalizer removes the compiled policy from the cache, and with 'Foreground'
+	// deletion propagation deletes the pipelines of the policy before it is removed
+	policyCacheFinalizer = "chalk.ocular.crashoverride.run/cel-cache"
+	// pipelineDeletionRequeue is how often a policy with foreground
+	// deletion propagation checks if its pipelines have been deleted
+	pipelineDeletionRequeue = 5 * time.Second
+)
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -64,6 +73,17 @@ func (r *ChalkReportPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Re
 	}
 
 	if !reportPolicy.DeletionTimestamp.IsZero() {
+		if reportPolicy.Spec.DeletionPropagation == metav1.DeletePropagationForeground {
+			remaining, err := r.deletePipelines(ctx, reportPolicy)
+			if err != nil {
+				l.Error(err, "failed to delete pipelines of reportPolicy")
+				return ctrl.Result{}, err
+			}
+			if remaining > 0 {
+				l.Info("waiting for pipelines of reportPolicy to be deleted", "pipelines", remaining)
+				return ctrl.Result{RequeueAfter: pipelineDeletionRequeue}, nil
+			}
+		}
 		if err := r.PolicyCompiler.Remove(reportPolicy); err != nil {
 			l.Error(err, "failed to remove compiled reportPolicy")
 			return ctrl.Result{}, err
@@ -104,3 +124,26 @@ func (r *ChalkReportPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Re
 
 	return ctrl.Result{}, nil
 }
+
+// deletePipelines deletes the pipelines created by the policy, returning
+// the number of pipelines that have not been removed yet
+func (r *ChalkReportPolicyReconciler) deletePipelines(ctx context.Context, reportPolicy *chalkularv1beta1.ChalkReportPolicy) (int, error) {
+	pipelines := &ocularv1beta1.PipelineList{}
+	if err := r.List(ctx, pipelines,
+		client.InNamespace(reportPolicy.Namespace),
+		client.MatchingLabels{chalkularv1beta1.PipelinePolicyUIDLabel: string(reportPolicy.UID)},
+	); err != nil {
+		return 0, fmt.Errorf("unable to list pipelines of policy: %w", err)
+	}
+
+	for i := range pipelines.Items {
+		pipeline := &pipelines.Items[i]
+		if !pipeline.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if err := r.Delete(ctx, pipeline, client.PropagationPolicy(metav1.DeletePropagationForeground)); client.IgnoreNotFound(err) != nil {
+			return 0, fmt.Errorf("unable to delete pipeline %s: %w", pipeline.Name, err)
+		}
+	}
+	return len(pipelines.Items), nil
+}
@@ -16,6 +16,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -81,7 +82,7 @@ var _ = Describe("ChalkReportPolicy Controller", Ordered, func() {
 			}
 
 			By("creating policy compiler")
-			policyCompiler, err = policy.NewCompiler(5)
+			policyCompiler, err = policy.NewCompiler(5, policy.CompilerOptions{})
 			Expect(err).To(Not(HaveOccurred()))
 		})
 
@@ -181,7 +182,7 @@ var _ = Describe("ChalkReportPolicy Controller", Ordered, func() {
 			}
 
 			By("creating policy compiler")
-			policyCompiler, err = policy.NewCompiler(5)
+			policyCompiler, err = policy.NewCompiler(5, policy.CompilerOptions{})
 			Expect(err).To(Not(HaveOccurred()))
 		})
 
@@ -233,4 +234,76 @@ var _ = Describe("ChalkReportPolicy Controller", Ordered, func() {
 
 		})
 	})
+
+	Context("When deleting a resource with foreground deletion propagation", func() {
+		const resourceName = "test-foreground-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		It("should delete the pipelines of the policy before removing the finalizer", func() {
+			policyCompiler, err := policy.NewCompiler(5, policy.CompilerOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			controllerReconciler := &ChalkReportPolicyReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				PolicyCompiler: policyCompiler,
+			}
+
+			By("creating the policy and adding the cache finalizer")
+			resource := &chalkularv1beta1.ChalkReportPolicy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: chalkularv1beta1.ChalkReportPolicySpec{
+					MatchCondition: "true",
+					Extraction: chalkularv1beta1.ChalkReportPolicyExtraction{
+						Target: "{'identifier': 'testing', 'version': '1'}",
+					},
+					PipelineTemplate: ocularv1beta1.PipelineTemplate{
+						Spec: ocularv1beta1.PipelineSpec{
+							ProfileRef:    ocularv1beta1.ParameterizedLocalObjectReference{Name: "test"},
+							DownloaderRef: ocularv1beta1.ParameterizedLocalObjectReference{Name: "test"},
+						},
+					},
+					DeletionPropagation: metav1.DeletePropagationForeground,
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("creating a pipeline of the policy")
+			pipeline := &ocularv1beta1.Pipeline{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-pipeline",
+					Namespace: "default",
+					Labels:    map[string]string{chalkularv1beta1.PipelinePolicyUIDLabel: string(resource.UID)},
+				},
+				Spec: resource.Spec.PipelineTemplate.Spec,
+			}
+			Expect(k8sClient.Create(ctx, pipeline)).To(Succeed())
+
+			By("deleting the policy")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(pipelineDeletionRequeue))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Finalizers).To(ContainElement(policyCacheFinalizer))
+
+			By("removing the finalizer once the pipelines are deleted")
+			Eventually(func() bool {
+				return errors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(pipeline), pipeline))
+			}).Should(BeTrue())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, resource))).To(BeTrue())
+		})
+	})
 })
@@ -0,0 +1,206 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// pipelineOutcomeAnnotation is set on a pipeline once its outcome is recorded
+	// on the status of its policy, with the value 'succeeded' or 'failed'
+	pipelineOutcomeAnnotation = "chalk.ocular.crashoverride.run/outcome-recorded"
+
+	pipelineResultSucceeded = "succeeded"
+	pipelineResultFailed    = "failed"
+)
+
+var pipelinesCompleted = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "scheduler_pipelines_completed",
+		Help: "Number of pipelines created by chalkular that completed, by policy and result",
+	},
+	[]string{"policy", "namespace", "result"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(pipelinesCompleted)
+}
+
+// PipelineReconciler records the outcome of the completed pipelines
+// created by chalkular on the status of the policy that generated them
+type PipelineReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// scheduledPipelines is a predicate for the pipelines created by chalkular
+func scheduledPipelines() (predicate.Predicate, error) {
+	return predicate.LabelSelectorPredicate(metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			chalkularv1beta1.PipelineScheduledByLabel: chalkularv1beta1.PipelineScheduledByValue,
+		},
+	})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PipelineReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	scheduled, err := scheduledPipelines()
+	if err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ocularv1beta1.Pipeline{}, builder.WithPredicates(scheduled)).
+		Named("pipeline").
+		Complete(r)
+}
+
+// +kubebuilder:rbac:groups=ocular.crashoverride.run,resources=pipelines,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=chalk.ocular.crashoverride.run,resources=chalkreportpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=chalk.ocular.crashoverride.run,resources=chalkreportpolicies/status,verbs=get;update;patch
+
+// Reconcile annotates a completed pipeline so that its outcome is only recorded once,
+// then records the outcome on the status of its policy. If the outcome cannot be
+// recorded, the annotation is removed so that the pipeline is reconciled again.
+func (r *PipelineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	l := logf.FromContext(ctx).WithValues("name", req.Name, "namespace", req.Namespace)
+
+	pipeline := &ocularv1beta1.Pipeline{}
+	if err := r.Get(ctx, req.NamespacedName, pipeline); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if pipeline.Status.CompletionTime == nil {
+		return ctrl.Result{}, nil
+	}
+	if _, recorded := pipeline.Annotations[pipelineOutcomeAnnotation]; recorded {
+		return ctrl.Result{}, nil
+	}
+
+	result := pipelineResult(pipeline)
+	l.Info("recording outcome of completed pipeline", "result", result)
+
+	// the pipeline is annotated first, so that the outcome is not
+	// recorded again if annotating the pipeline fails
+	patch := client.MergeFrom(pipeline.DeepCopy())
+	metav1.SetMetaDataAnnotation(&pipeline.ObjectMeta, pipelineOutcomeAnnotation, result)
+	if err := r.Patch(ctx, pipeline, patch); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	reportPolicy, err := r.recordOutcome(ctx, pipeline, result)
+	if err != nil {
+		unpatch := client.MergeFrom(pipeline.DeepCopy())
+		delete(pipeline.Annotations, pipelineOutcomeAnnotation)
+		if err := r.Patch(ctx, pipeline, unpatch); client.IgnoreNotFound(err) != nil {
+			l.Error(err, "unable to remove outcome annotation from pipeline, outcome will not be recorded")
+		}
+		return ctrl.Result{}, err
+	}
+	if reportPolicy == nil {
+		l.Info("policy of completed pipeline no longer exists, skipping outcome")
+		return ctrl.Result{}, nil
+	}
+	pipelinesCompleted.With(prometheus.Labels{
+		"policy":    reportPolicy.Name,
+		"namespace": reportPolicy.Namespace,
+		"result":    result,
+	}).Inc()
+	return ctrl.Result{}, nil
+}
+
+// recordOutcome records the outcome of the completed pipeline on the status of its policy,
+// retrying if the status was updated for another pipeline. The policy is returned, or nil
+// if it no longer exists.
+func (r *PipelineReconciler) recordOutcome(ctx context.Context, pipeline *ocularv1beta1.Pipeline, result string) (*chalkularv1beta1.ChalkReportPolicy, error) {
+	var reportPolicy *chalkularv1beta1.ChalkReportPolicy
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		reportPolicy, err = policyForPipeline(ctx, r, pipeline)
+		if err != nil || reportPolicy == nil {
+			return err
+		}
+		recordPipelineOutcome(&reportPolicy.Status.Pipelines, pipeline, result)
+		return r.Status().Update(ctx, reportPolicy)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reportPolicy, nil
+}
+
+// policyForPipeline returns the policy in the namespace of the pipeline with the UID of its
+// policy UID label, or nil if the pipeline has no label or the policy no longer exists.
+func policyForPipeline(ctx context.Context, r client.Reader, pipeline client.Object) (*chalkularv1beta1.ChalkReportPolicy, error) {
+	uid := pipeline.GetLabels()[chalkularv1beta1.PipelinePolicyUIDLabel]
+	if uid == "" {
+		return nil, nil
+	}
+
+	policies := &chalkularv1beta1.ChalkReportPolicyList{}
+	if err := r.List(ctx, policies, client.InNamespace(pipeline.GetNamespace())); err != nil {
+		return nil, fmt.Errorf("unable to list chalk report policies: %w", err)
+	}
+	for i := range policies.Items {
+		if string(policies.Items[i].UID) == uid {
+			return &policies.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// pipelineResult returns 'succeeded' if the pipeline
+// completed successfully, otherwise 'failed'
+func pipelineResult(pipeline *ocularv1beta1.Pipeline) string {
+	if pipeline.Status.Phase == ocularv1beta1.PipelineSucceeded {
+		return pipelineResultSucceeded
+	}
+	return pipelineResultFailed
+}
+
+// recordPipelineOutcome adds the outcome of the completed pipeline to the outcomes
+func recordPipelineOutcome(outcomes *chalkularv1beta1.ChalkReportPolicyPipelineOutcomes, pipeline *ocularv1beta1.Pipeline, result string) {
+	completed := *pipeline.Status.CompletionTime
+	if started := pipeline.Status.StartTime; started != nil {
+		outcomes.LastDuration = &metav1.Duration{Duration: completed.Sub(started.Time)}
+	}
+
+	if result == pipelineResultSucceeded {
+		outcomes.Succeeded++
+		outcomes.LastSuccessTime = &completed
+		return
+	}
+
+	outcomes.Failed++
+	failure := &chalkularv1beta1.ChalkReportPolicyPipelineFailure{
+		Pipeline:       pipeline.Name,
+		CompletionTime: completed,
+	}
+	if condition := meta.FindStatusCondition(pipeline.Status.Conditions,
+		ocularv1beta1.CompletedSuccessfullyConditionType); condition != nil {
+		failure.Reason = condition.Reason
+		failure.Message = condition.Message
+	}
+	outcomes.LastFailure = failure
+}
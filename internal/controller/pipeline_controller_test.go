@@ -0,0 +1,190 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+)
+
+var _ = Describe("Pipeline Controller", Ordered, func() {
+	Context("When a pipeline created by a policy completes", func() {
+		const resourceName = "test-outcomes"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		reportPolicy := &chalkularv1beta1.ChalkReportPolicy{}
+		var controllerReconciler *PipelineReconciler
+
+		BeforeAll(func() {
+			By("creating the custom resource for the Kind ChalkReportPolicy")
+			resource := &chalkularv1beta1.ChalkReportPolicy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: chalkularv1beta1.ChalkReportPolicySpec{
+					MatchCondition: "true",
+					Extraction: chalkularv1beta1.ChalkReportPolicyExtraction{
+						Target: "{'identifier': 'testing', 'version': '1'}",
+					},
+					PipelineTemplate: ocularv1beta1.PipelineTemplate{
+						Spec: ocularv1beta1.PipelineSpec{
+							ProfileRef:    ocularv1beta1.ParameterizedLocalObjectReference{Name: "test"},
+							DownloaderRef: ocularv1beta1.ParameterizedLocalObjectReference{Name: "test"},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, reportPolicy)).To(Succeed())
+
+			controllerReconciler = &PipelineReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+		})
+
+		AfterAll(func() {
+			By("Cleanup the specific resource instance ChalkReportPolicy")
+			Expect(k8sClient.Delete(ctx, reportPolicy)).To(Succeed())
+		})
+
+		// createCompletedPipeline creates a pipeline of the policy
+		// that ran for a minute and completed in the phase
+		createCompletedPipeline := func(name string, phase ocularv1beta1.PipelinePhase) {
+			pipeline := &ocularv1beta1.Pipeline{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "default",
+					Labels: map[string]string{
+						chalkularv1beta1.PipelineScheduledByLabel: chalkularv1beta1.PipelineScheduledByValue,
+						chalkularv1beta1.PipelinePolicyUIDLabel:   string(reportPolicy.UID),
+					},
+				},
+				Spec: reportPolicy.Spec.PipelineTemplate.Spec,
+			}
+			Expect(k8sClient.Create(ctx, pipeline)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, pipeline)).To(Succeed())
+			})
+
+			completed := metav1.Now()
+			pipeline.Status = ocularv1beta1.PipelineStatus{
+				StartTime:      &metav1.Time{Time: completed.Add(-time.Minute)},
+				CompletionTime: &completed,
+				Phase:          phase,
+				Conditions: []metav1.Condition{{
+					Type:               ocularv1beta1.CompletedSuccessfullyConditionType,
+					Status:             metav1.ConditionFalse,
+					Reason:             "ScanFailed",
+					Message:            "scan container exited with code 1",
+					LastTransitionTime: completed,
+				}},
+			}
+			if phase == ocularv1beta1.PipelineSucceeded {
+				pipeline.Status.Conditions[0].Status = metav1.ConditionTrue
+			}
+			Expect(k8sClient.Status().Update(ctx, pipeline)).To(Succeed())
+		}
+
+		// completePipeline creates a completed pipeline of the policy
+		// and reconciles it, returning the updated policy
+		completePipeline := func(name string, phase ocularv1beta1.PipelinePhase) *chalkularv1beta1.ChalkReportPolicy {
+			createCompletedPipeline(name, phase)
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: name, Namespace: "default"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			pipeline := &ocularv1beta1.Pipeline{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, pipeline)).To(Succeed())
+			Expect(pipeline.Annotations).To(HaveKey(pipelineOutcomeAnnotation))
+
+			resource := &chalkularv1beta1.ChalkReportPolicy{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			return resource
+		}
+
+		It("should record the failure on the policy status", func() {
+			resource := completePipeline("test-outcomes-failed", ocularv1beta1.PipelineFailed)
+			Expect(resource.Status.Pipelines.Failed).To(BeEquivalentTo(1))
+			Expect(resource.Status.Pipelines.Succeeded).To(BeZero())
+			Expect(resource.Status.Pipelines.LastDuration).To(Equal(&metav1.Duration{Duration: time.Minute}))
+			Expect(resource.Status.Pipelines.LastFailure).NotTo(BeNil())
+			Expect(resource.Status.Pipelines.LastFailure.Pipeline).To(Equal("test-outcomes-failed"))
+			Expect(resource.Status.Pipelines.LastFailure.Reason).To(Equal("ScanFailed"))
+
+			By("only recording the outcome once")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: "test-outcomes-failed", Namespace: "default"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Pipelines.Failed).To(BeEquivalentTo(1))
+		})
+
+		It("should record the success on the policy status", func() {
+			resource := completePipeline("test-outcomes-succeeded", ocularv1beta1.PipelineSucceeded)
+			Expect(resource.Status.Pipelines.Succeeded).To(BeEquivalentTo(1))
+			Expect(resource.Status.Pipelines.Failed).To(BeEquivalentTo(1))
+			Expect(resource.Status.Pipelines.LastSuccessTime).NotTo(BeNil())
+		})
+
+		It("should not record the outcome twice if annotating the pipeline fails", func() {
+			const name = "test-outcomes-patch-failed"
+			createCompletedPipeline(name, ocularv1beta1.PipelineFailed)
+			request := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: "default"}}
+
+			failingReconciler := &PipelineReconciler{
+				Client: &failingPatchClient{Client: k8sClient},
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := failingReconciler.Reconcile(ctx, request)
+			Expect(err).To(HaveOccurred())
+			resource := &chalkularv1beta1.ChalkReportPolicy{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Pipelines.Failed).To(BeEquivalentTo(1))
+
+			By("recording the outcome once the pipeline is annotated")
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = controllerReconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Pipelines.Failed).To(BeEquivalentTo(2))
+		})
+	})
+})
+
+// failingPatchClient is a client that fails to patch objects
+type failingPatchClient struct {
+	client.Client
+}
+
+func (c *failingPatchClient) Patch(context.Context, client.Object, client.Patch, ...client.PatchOption) error {
+	return errors.NewServiceUnavailable("patch failed")
+}
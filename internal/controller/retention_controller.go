@@ -0,0 +1,198 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	pruneReasonTTL          = "ttl"
+	pruneReasonMaxCompleted = "max-completed"
+)
+
+var pipelinesPruned = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "scheduler_pipelines_pruned",
+		Help: "Number of completed pipelines deleted by the retention of their policy, by policy and reason",
+	},
+	[]string{"policy", "namespace", "reason"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(pipelinesPruned)
+}
+
+// PipelineRetentionReconciler deletes the completed pipelines created by
+// chalkular once they exceed the retention of the policy that generated them
+type PipelineRetentionReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// DedupWindow is the dedup window of the scheduler, for policies that do not set
+	// their own. Completed pipelines are kept until their dedup window has passed.
+	DedupWindow time.Duration
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PipelineRetentionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	scheduled, err := scheduledPipelines()
+	if err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		// the retention of a policy can change after its pipelines completed
+		For(&chalkularv1beta1.ChalkReportPolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Named("pipeline-retention").
+		// pipelines are pruned by their policy, so that the pipelines
+		// of a policy are listed once for all of its completed pipelines
+		Watches(&ocularv1beta1.Pipeline{},
+			handler.EnqueueRequestsFromMapFunc(r.pipelinePolicy),
+			builder.WithPredicates(scheduled, predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				pipeline, ok := obj.(*ocularv1beta1.Pipeline)
+				return ok && isPipelineRetained(pipeline)
+			}))).
+		Complete(r)
+}
+
+// pipelinePolicy returns a request for the policy that generated the pipeline. Policies with a
+// name that is not a valid label value are found by the policy UID label of the pipeline.
+func (r *PipelineRetentionReconciler) pipelinePolicy(ctx context.Context, obj client.Object) []reconcile.Request {
+	if name := obj.GetLabels()[chalkularv1beta1.PipelinePolicyLabel]; name != "" {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}}}
+	}
+	reportPolicy, err := policyForPipeline(ctx, r, obj)
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "unable to find policy for pipeline", "pipeline", obj.GetName())
+		return nil
+	}
+	if reportPolicy == nil {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(reportPolicy)}}
+}
+
+// +kubebuilder:rbac:groups=ocular.crashoverride.run,resources=pipelines,verbs=get;list;watch;delete
+
+// Reconcile deletes the completed pipelines of the policy once their TTL has passed, and the
+// completed pipelines over the maximum to keep, requeuing for the next pipeline to expire.
+// Pipelines are only deleted once their outcome has been recorded and their dedup window has passed.
+func (r *PipelineRetentionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	l := logf.FromContext(ctx).WithValues("policy", req.Name, "namespace", req.Namespace)
+
+	reportPolicy := &chalkularv1beta1.ChalkReportPolicy{}
+	if err := r.Get(ctx, req.NamespacedName, reportPolicy); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	retention := reportPolicy.Spec.Retention
+	if retention == nil || !reportPolicy.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	list := &ocularv1beta1.PipelineList{}
+	if err := r.List(ctx, list,
+		client.InNamespace(reportPolicy.Namespace),
+		client.MatchingLabels{chalkularv1beta1.PipelinePolicyUIDLabel: string(reportPolicy.UID)},
+	); err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to list pipelines of policy: %w", err)
+	}
+	completed := slices.DeleteFunc(list.Items, func(p ocularv1beta1.Pipeline) bool {
+		return !isPipelineRetained(&p) || !p.DeletionTimestamp.IsZero()
+	})
+	// most recently completed first
+	slices.SortFunc(completed, func(a, b ocularv1beta1.Pipeline) int {
+		if c := b.Status.CompletionTime.Compare(a.Status.CompletionTime.Time); c != 0 {
+			return c
+		}
+		return b.CreationTimestamp.Compare(a.CreationTimestamp.Time)
+	})
+
+	dedupWindow := r.DedupWindow
+	if reportPolicy.Spec.DedupWindow != nil {
+		dedupWindow = reportPolicy.Spec.DedupWindow.Duration
+	}
+
+	var (
+		now     = time.Now()
+		requeue time.Duration
+	)
+	for i := range completed {
+		pipeline := &completed[i]
+		completionTime := pipeline.Status.CompletionTime.Time
+
+		reason, deleteAt := "", time.Time{}
+		if maxCompleted := retention.MaxCompletedPipelines; maxCompleted != nil && i >= int(*maxCompleted) {
+			reason, deleteAt = pruneReasonMaxCompleted, completionTime
+		} else if ttl := pipelineTTL(retention, pipeline); ttl != nil {
+			reason, deleteAt = pruneReasonTTL, completionTime.Add(ttl.Duration)
+		} else {
+			continue
+		}
+		// the pipeline is used to detect duplicates until its dedup window has passed
+		if keepUntil := completionTime.Add(dedupWindow); dedupWindow > 0 && keepUntil.After(deleteAt) {
+			deleteAt = keepUntil
+		}
+
+		if remaining := deleteAt.Sub(now); remaining > 0 {
+			if requeue == 0 || remaining < requeue {
+				requeue = remaining
+			}
+			continue
+		}
+		l.Info("deleting completed pipeline", "pipeline", pipeline.Name, "reason", reason)
+		if err := r.deletePipeline(ctx, reportPolicy, pipeline, reason); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeue}, nil
+}
+
+// pipelineTTL returns the TTL of the retention for the result of the pipeline, or nil if there is none
+func pipelineTTL(retention *chalkularv1beta1.ChalkReportPolicyRetention, pipeline *ocularv1beta1.Pipeline) *metav1.Duration {
+	if pipeline.Annotations[pipelineOutcomeAnnotation] == pipelineResultFailed {
+		return retention.FailedTTL
+	}
+	return retention.SuccessfulTTL
+}
+
+func (r *PipelineRetentionReconciler) deletePipeline(ctx context.Context, reportPolicy *chalkularv1beta1.ChalkReportPolicy, pipeline *ocularv1beta1.Pipeline, reason string) error {
+	if err := r.Delete(ctx, pipeline, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	pipelinesPruned.With(prometheus.Labels{
+		"policy":    reportPolicy.Name,
+		"namespace": reportPolicy.Namespace,
+		"reason":    reason,
+	}).Inc()
+	return nil
+}
+
+// isPipelineRetained returns true if the pipeline has completed and its
+// outcome has been recorded, so it can be deleted by the retention of its policy
+func isPipelineRetained(pipeline *ocularv1beta1.Pipeline) bool {
+	_, recorded := pipeline.Annotations[pipelineOutcomeAnnotation]
+	return pipeline.Status.CompletionTime != nil && recorded
+}
@@ -0,0 +1,141 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+)
+
+var _ = Describe("Pipeline Retention Controller", Ordered, func() {
+	Context("When a policy has a retention", func() {
+		const resourceName = "test-retention"
+
+		ctx := context.Background()
+
+		reportPolicy := &chalkularv1beta1.ChalkReportPolicy{}
+		var controllerReconciler *PipelineRetentionReconciler
+
+		BeforeAll(func() {
+			By("creating the custom resource for the Kind ChalkReportPolicy")
+			reportPolicy = &chalkularv1beta1.ChalkReportPolicy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: chalkularv1beta1.ChalkReportPolicySpec{
+					MatchCondition: "true",
+					Extraction: chalkularv1beta1.ChalkReportPolicyExtraction{
+						Target: "{'identifier': 'testing', 'version': '1'}",
+					},
+					PipelineTemplate: ocularv1beta1.PipelineTemplate{
+						Spec: ocularv1beta1.PipelineSpec{
+							ProfileRef:    ocularv1beta1.ParameterizedLocalObjectReference{Name: "test"},
+							DownloaderRef: ocularv1beta1.ParameterizedLocalObjectReference{Name: "test"},
+						},
+					},
+					Retention: &chalkularv1beta1.ChalkReportPolicyRetention{
+						FailedTTL:             &metav1.Duration{Duration: time.Hour},
+						MaxCompletedPipelines: new(int32(2)),
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, reportPolicy)).To(Succeed())
+
+			controllerReconciler = &PipelineRetentionReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+		})
+
+		AfterAll(func() {
+			By("Cleanup the specific resource instance ChalkReportPolicy")
+			Expect(k8sClient.Delete(ctx, reportPolicy)).To(Succeed())
+		})
+
+		// completedPipeline creates a pipeline of the policy that completed
+		// ago with the result, which has already had its outcome recorded
+		completedPipeline := func(name, result string, ago time.Duration) *ocularv1beta1.Pipeline {
+			pipeline := &ocularv1beta1.Pipeline{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "default",
+					Labels: map[string]string{
+						chalkularv1beta1.PipelineScheduledByLabel: chalkularv1beta1.PipelineScheduledByValue,
+						chalkularv1beta1.PipelinePolicyUIDLabel:   string(reportPolicy.UID),
+					},
+					Annotations: map[string]string{pipelineOutcomeAnnotation: result},
+				},
+				Spec: reportPolicy.Spec.PipelineTemplate.Spec,
+			}
+			Expect(k8sClient.Create(ctx, pipeline)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, pipeline))).To(Succeed())
+			})
+
+			completed := metav1.NewTime(time.Now().Add(-ago))
+			pipeline.Status = ocularv1beta1.PipelineStatus{CompletionTime: &completed}
+			Expect(k8sClient.Status().Update(ctx, pipeline)).To(Succeed())
+			return pipeline
+		}
+
+		reconcilePolicy := func() reconcile.Result {
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: resourceName, Namespace: "default"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			return result
+		}
+
+		isDeleted := func(pipeline *ocularv1beta1.Pipeline) bool {
+			return errors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(pipeline), &ocularv1beta1.Pipeline{}))
+		}
+
+		It("should delete failed pipelines once their TTL has passed", func() {
+			expired := completedPipeline("test-retention-expired", pipelineResultFailed, 2*time.Hour)
+			recent := completedPipeline("test-retention-recent", pipelineResultFailed, time.Minute)
+
+			Expect(reconcilePolicy().RequeueAfter).To(BeNumerically("~", 59*time.Minute, time.Minute))
+			Eventually(func() bool { return isDeleted(expired) }).Should(BeTrue())
+			Expect(isDeleted(recent)).To(BeFalse())
+		})
+
+		It("should keep completed pipelines until their dedup window has passed", func() {
+			controllerReconciler.DedupWindow = 3 * time.Hour
+			DeferCleanup(func() { controllerReconciler.DedupWindow = 0 })
+			expired := completedPipeline("test-retention-dedup", pipelineResultFailed, 2*time.Hour)
+
+			Expect(reconcilePolicy().RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+			Expect(isDeleted(expired)).To(BeFalse())
+		})
+
+		It("should delete the pipelines that completed first over the maximum", func() {
+			oldest := completedPipeline("test-retention-oldest", pipelineResultSucceeded, 3*time.Minute)
+			older := completedPipeline("test-retention-older", pipelineResultSucceeded, 2*time.Minute)
+			newest := completedPipeline("test-retention-newest", pipelineResultSucceeded, time.Minute)
+
+			Expect(reconcilePolicy().RequeueAfter).To(BeZero())
+			Eventually(func() bool { return isDeleted(oldest) }).Should(BeTrue())
+			Expect(isDeleted(older)).To(BeFalse())
+			Expect(isDeleted(newest)).To(BeFalse())
+		})
+	})
+})
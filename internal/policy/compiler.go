@@ -11,10 +11,13 @@ package policy
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
+	"time"
 
 	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker"
 	"github.com/google/cel-go/ext"
 	"k8s.io/utils/lru"
 )
@@ -25,17 +28,54 @@ type Compiler struct {
 	env   *cel.Env
 	cache *lru.Cache
 	mu    sync.Mutex
+	opts  CompilerOptions
 }
 
-func NewCompiler(cacheSize int) (*Compiler, error) {
+// CompilerOptions are the limits applied when
+// compiling and evaluating policy expressions
+type CompilerOptions struct {
+	// CostLimit is the maximum runtime cost of evaluating a single expression.
+	// Expressions whose minimum estimated cost is above the limit are rejected
+	// when compiled. A value of 0 indicates no limit.
+	CostLimit uint64
+	// EvalTimeout is the wall-clock budget for evaluating the expressions of a
+	// policy against a single report, for each of [CompiledPolicy.Matches] and
+	// [CompiledPolicy.Extract]. A value of 0 indicates no timeout.
+	EvalTimeout time.Duration
+	// AllowUnknownKeys declares the chalk report (and the chalk marks returned by 'chalks')
+	// as 'map(string, dyn)', as before reports were typed, instead of with the well-known
+	// chalk keys. This keeps policies written before reports were typed working.
+	AllowUnknownKeys bool
+}
+
+// interruptCheckFrequency is the number of comprehension iterations
+// between checks of the evaluation timeout
+const interruptCheckFrequency = 100
+
+func NewCompiler(cacheSize int, opts CompilerOptions) (*Compiler, error) {
+	provider, err := newChalkTypeProvider()
+	if err != nil {
+		return nil, fmt.Errorf("creating CEL type provider: %w", err)
+	}
+
+	report, mark := reportType, markType
+	if opts.AllowUnknownKeys {
+		report, mark = objectType, objectType
+	}
+
 	env, err := cel.NewEnv(
-		cel.Variable("report", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Lib(&chalkObjectLib{provider: provider}),
+		cel.Lib(chalkFunctionsLib{report: report, mark: mark}),
+		cel.Variable("report", report),
+		cel.Variable("source", sourceType),
 		cel.Variable("each", cel.NullableType(cel.DynType)),
 		ext.Bindings(),
 		ext.Strings(),
 		ext.Lists(),
 		ext.Encoders(),
 		ext.Sets(),
+		cel.OptionalTypes(),
+		ext.Regex(),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("creating CEL env: %w", err)
@@ -43,7 +83,7 @@ func NewCompiler(cacheSize int) (*Compiler, error) {
 
 	cache := lru.New(cacheSize)
 
-	return &Compiler{env: env, cache: cache}, nil
+	return &Compiler{env: env, cache: cache, opts: opts}, nil
 }
 
 func (c *Compiler) Clear() {
@@ -75,6 +115,11 @@ func (c *Compiler) Get(policyResource *chalkularv1beta1.ChalkReportPolicy) (*Com
 	return compiled, nil
 }
 
+// Compile compiles the policy without storing it in the cache.
+func (c *Compiler) Compile(policyResource *chalkularv1beta1.ChalkReportPolicy) (*CompiledPolicy, error) {
+	return c.compile(policyResource)
+}
+
 // Remove removes the compiled policy from the cache
 func (c *Compiler) Remove(policyDef *chalkularv1beta1.ChalkReportPolicy) error {
 	key := cacheKey(policyDef)
@@ -102,6 +147,7 @@ func (c *Compiler) compile(policy *chalkularv1beta1.ChalkReportPolicy) (*Compile
 		ObservedGeneration: policy.Generation,
 		MatchCondition:     match,
 		Target:             target,
+		evalTimeout:        c.opts.EvalTimeout,
 	}
 
 	if s.Extraction.DownloaderParams != nil {
@@ -125,16 +171,63 @@ func (c *Compiler) compile(policy *chalkularv1beta1.ChalkReportPolicy) (*Compile
 		}
 	}
 
+	if s.Extraction.Labels != nil {
+		compiled.Labels, err = c.program(*s.Extraction.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("extraction.labels: %w", err)
+		}
+	}
+
+	if s.Extraction.Annotations != nil {
+		compiled.Annotations, err = c.program(*s.Extraction.Annotations)
+		if err != nil {
+			return nil, fmt.Errorf("extraction.annotations: %w", err)
+		}
+	}
+
+	if s.Extraction.ProfileRef != nil {
+		compiled.ProfileRef, err = c.program(*s.Extraction.ProfileRef)
+		if err != nil {
+			return nil, fmt.Errorf("extraction.profileRef: %w", err)
+		}
+	}
+
+	if s.Extraction.DownloaderRef != nil {
+		compiled.DownloaderRef, err = c.program(*s.Extraction.DownloaderRef)
+		if err != nil {
+			return nil, fmt.Errorf("extraction.downloaderRef: %w", err)
+		}
+	}
+
+	if s.Extraction.DedupKey != nil {
+		compiled.DedupKey, err = c.program(*s.Extraction.DedupKey)
+		if err != nil {
+			return nil, fmt.Errorf("extraction.dedupKey: %w", err)
+		}
+	}
+
 	return compiled, nil
 }
 
 func (c *Compiler) program(expr string) (cel.Program, error) {
 	ast, issues := c.env.Compile(expr)
 	if issues != nil && issues.Err() != nil {
-		return nil, issues.Err()
+		return nil, compileErr(issues)
 	}
 
-	prog, err := c.env.Program(ast)
+	if err := c.checkCost(ast); err != nil {
+		return nil, err
+	}
+
+	var opts []cel.ProgramOption
+	if c.opts.CostLimit > 0 {
+		opts = append(opts, cel.CostLimit(c.opts.CostLimit))
+	}
+	if c.opts.EvalTimeout > 0 {
+		opts = append(opts, cel.InterruptCheckFrequency(interruptCheckFrequency))
+	}
+
+	prog, err := c.env.Program(ast, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -142,6 +235,46 @@ func (c *Compiler) program(expr string) (cel.Program, error) {
 	return prog, nil
 }
 
+// compileErr returns the error of the issues, explaining how to select
+// chalk keys that are not well-known if a field was not found
+func compileErr(issues *cel.Issues) error {
+	if strings.Contains(issues.String(), "undefined field") {
+		return fmt.Errorf("%w\nchalk keys that are not well-known must be prefixed with 'X_' or '_X_', "+
+			"or selected with index syntax i.e. report['KEY']", issues.Err())
+	}
+	return issues.Err()
+}
+
+// checkCost returns an error if the estimated cost of the
+// expression will always be above the configured cost limit.
+// Since the size of the report is not known, expressions that
+// iterate over it have no upper bound and are only limited at runtime.
+func (c *Compiler) checkCost(ast *cel.Ast) error {
+	if c.opts.CostLimit == 0 {
+		return nil
+	}
+	estimate, err := c.env.EstimateCost(ast, costEstimator{})
+	if err != nil {
+		return fmt.Errorf("estimating cost: %w", err)
+	}
+	if estimate.Min > c.opts.CostLimit {
+		return fmt.Errorf("estimated cost %d exceeds the cost limit of %d", estimate.Min, c.opts.CostLimit)
+	}
+	return nil
+}
+
+// costEstimator is a [checker.CostEstimator] that does not
+// provide any estimates, falling back to the cel-go defaults
+type costEstimator struct{}
+
+func (costEstimator) EstimateSize(checker.AstNode) *checker.SizeEstimate {
+	return nil
+}
+
+func (costEstimator) EstimateCallCost(string, string, *checker.AstNode, []checker.AstNode) *checker.CallEstimate {
+	return nil
+}
+
 func cacheKey(p *chalkularv1beta1.ChalkReportPolicy) string {
 	return string(p.UID)
 }
@@ -0,0 +1,246 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// imageReferenceTypeName is the object type returned by 'image.parse',
+// at runtime it is a map of the fields below
+const imageReferenceTypeName = "chalk.ImageReference"
+
+var imageReferenceType = cel.ObjectType(imageReferenceTypeName)
+
+var imageReferenceFields = map[string]*cel.Type{
+	"registry":   cel.StringType,
+	"repository": cel.StringType,
+	"tag":        cel.StringType,
+	"digest":     cel.StringType,
+}
+
+// chalkFunctionsLib is a [cel.Library] of helper functions for
+// writing policies against chalk reports:
+//
+//	image.parse(ref: string) -> chalk.ImageReference
+//	digest.normalize(digest: string) -> string
+//	semver.isValid(version: string) -> bool
+//	semver.compare(a: string, b: string) -> int
+//	semver.satisfies(version: string, constraint: string) -> bool
+//	<string>.matchesGlob(pattern: string) -> bool
+//	chalks(report: chalk.Report) -> list(chalk.Mark)
+//	chalks(report: chalk.Report, artifactType: string) -> list(chalk.Mark)
+//
+// The report and mark types are 'map(string, dyn)' if unknown keys are allowed.
+type chalkFunctionsLib struct {
+	report *cel.Type
+	mark   *cel.Type
+}
+
+func (l chalkFunctionsLib) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("image.parse",
+			cel.Overload("image_parse_string", []*cel.Type{cel.StringType}, imageReferenceType,
+				cel.UnaryBinding(parseImage))),
+		cel.Function("digest.normalize",
+			cel.Overload("digest_normalize_string", []*cel.Type{cel.StringType}, cel.StringType,
+				cel.UnaryBinding(normalizeDigest))),
+		cel.Function("semver.isValid",
+			cel.Overload("semver_is_valid_string", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(semverIsValid))),
+		cel.Function("semver.compare",
+			cel.Overload("semver_compare_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.IntType,
+				cel.BinaryBinding(semverCompare))),
+		cel.Function("semver.satisfies",
+			cel.Overload("semver_satisfies_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(semverSatisfies))),
+		cel.Function("matchesGlob",
+			cel.MemberOverload("string_matches_glob_string", []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(matchesGlob))),
+		// the report and marks are maps at runtime, so a singleton binding
+		// is used to skip the runtime type check against the object type
+		cel.Function("chalks",
+			cel.Overload("chalks_report", []*cel.Type{l.report}, cel.ListType(l.mark)),
+			cel.Overload("chalks_report_string", []*cel.Type{l.report, cel.StringType}, cel.ListType(l.mark)),
+			cel.SingletonFunctionBinding(func(args ...ref.Val) ref.Val {
+				switch len(args) {
+				case 1:
+					return filterChalks(args[0], nil)
+				case 2:
+					return filterChalks(args[0], args[1])
+				default:
+					return types.NewErr("chalks: expected 1 or 2 arguments, got %d", len(args))
+				}
+			})),
+	}
+}
+
+func (chalkFunctionsLib) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+// parseImage parses an OCI image reference into its registry, repository,
+// tag and digest. Docker Hub references are normalized, i.e. 'nginx'
+// will have the registry 'index.docker.io' and repository 'library/nginx'.
+// The tag is only defaulted to 'latest' if the reference has no digest.
+func parseImage(val ref.Val) ref.Val {
+	s, ok := val.(types.String)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(val)
+	}
+	image := string(s)
+
+	base, digest, hasDigest := strings.Cut(image, "@")
+	if hasDigest {
+		if _, err := name.NewDigest(image); err != nil {
+			return types.NewErr("image.parse: invalid image reference '%s': %s", image, err)
+		}
+	}
+	tag, err := name.NewTag(base)
+	if err != nil {
+		return types.NewErr("image.parse: invalid image reference '%s': %s", image, err)
+	}
+
+	tagStr := tag.TagStr()
+	if explicitTag := strings.Contains(base[strings.LastIndex(base, "/")+1:], ":"); hasDigest && !explicitTag {
+		tagStr = ""
+	}
+
+	return types.NewStringStringMap(types.DefaultTypeAdapter, map[string]string{
+		"registry":   tag.RegistryStr(),
+		"repository": tag.RepositoryStr(),
+		"tag":        tagStr,
+		"digest":     digest,
+	})
+}
+
+// normalizeDigest lower cases a digest and prefixes it
+// with 'sha256:' if it has no algorithm prefix
+func normalizeDigest(val ref.Val) ref.Val {
+	s, ok := val.(types.String)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(val)
+	}
+	digest := strings.ToLower(strings.TrimSpace(string(s)))
+	if digest == "" || strings.Contains(digest, ":") {
+		return types.String(digest)
+	}
+	return types.String("sha256:" + digest)
+}
+
+func semverIsValid(val ref.Val) ref.Val {
+	s, ok := val.(types.String)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(val)
+	}
+	_, err := semver.NewVersion(string(s))
+	return types.Bool(err == nil)
+}
+
+func semverCompare(lhs, rhs ref.Val) ref.Val {
+	a, err := toVersion(lhs)
+	if err != nil {
+		return types.WrapErr(err)
+	}
+	b, err := toVersion(rhs)
+	if err != nil {
+		return types.WrapErr(err)
+	}
+	return types.Int(a.Compare(b))
+}
+
+func semverSatisfies(version, constraint ref.Val) ref.Val {
+	v, err := toVersion(version)
+	if err != nil {
+		return types.WrapErr(err)
+	}
+	c, ok := constraint.(types.String)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(constraint)
+	}
+	constraints, err := semver.NewConstraint(string(c))
+	if err != nil {
+		return types.NewErr("semver.satisfies: invalid constraint '%s': %s", c, err)
+	}
+	return types.Bool(constraints.Check(v))
+}
+
+func toVersion(val ref.Val) (*semver.Version, error) {
+	s, ok := val.(types.String)
+	if !ok {
+		return nil, fmt.Errorf("no such overload: semver of type %s", val.Type())
+	}
+	v, err := semver.NewVersion(string(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid semantic version '%s': %w", s, err)
+	}
+	return v, nil
+}
+
+// matchesGlob matches the string against a glob pattern using
+// the syntax of [path.Match], so '*' will not match a '/'
+func matchesGlob(val, pattern ref.Val) ref.Val {
+	s, ok := val.(types.String)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(val)
+	}
+	p, ok := pattern.(types.String)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(pattern)
+	}
+	matched, err := path.Match(string(p), string(s))
+	if err != nil {
+		return types.NewErr("matchesGlob: invalid pattern '%s': %s", p, err)
+	}
+	return types.Bool(matched)
+}
+
+// filterChalks returns the chalk marks of the report, if artifactType is
+// not nil, only the marks with a matching '_OP_ARTIFACT_TYPE' are returned.
+// A report without any chalk marks returns an empty list.
+func filterChalks(report, artifactType ref.Val) ref.Val {
+	mapper, ok := report.(traits.Mapper)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(report)
+	}
+	marks, found := mapper.Find(types.String(chalk.KeyChalks))
+	if !found {
+		return types.NewRefValList(types.DefaultTypeAdapter, nil)
+	}
+	lister, ok := marks.(traits.Lister)
+	if !ok {
+		return types.NewErr("chalks: key %s is not a list", chalk.KeyChalks)
+	}
+	if artifactType == nil {
+		return lister
+	}
+
+	var filtered []ref.Val
+	for it := lister.Iterator(); it.HasNext() == types.True; {
+		mark := it.Next()
+		m, ok := mark.(traits.Mapper)
+		if !ok {
+			continue
+		}
+		if t, found := m.Find(types.String(chalk.KeyArtifactType)); found && t.Equal(artifactType) == types.True {
+			filtered = append(filtered, mark)
+		}
+	}
+	return types.NewRefValList(types.DefaultTypeAdapter, filtered)
+}
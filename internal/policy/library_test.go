@@ -0,0 +1,112 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package policy
+
+import (
+	"github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("chalk functions library", func() {
+	var compiler *Compiler
+
+	BeforeEach(func() {
+		var err error
+		compiler, err = NewCompiler(5, CompilerOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	report := chalk.Report{
+		"_ACTION_ID": "action-id",
+		"_CHALKS": []any{
+			map[string]any{
+				"_OP_ARTIFACT_TYPE": "Docker Image",
+				"_REPO_TAGS":        []any{"ghcr.io/crashappsec/chalkular:v1.2.3"},
+			},
+			map[string]any{
+				"_OP_ARTIFACT_TYPE": "ELF",
+			},
+			map[string]any{
+				"_OP_ARTIFACT_TYPE": "Docker Image",
+				"_REPO_TAGS":        []any{"nginx"},
+			},
+		},
+	}
+
+	matches := func(expr string) (bool, error) {
+		compiled, err := compiler.Compile(&v1beta1.ChalkReportPolicy{
+			Spec: v1beta1.ChalkReportPolicySpec{
+				MatchCondition: expr,
+				Extraction: v1beta1.ChalkReportPolicyExtraction{
+					Target: "{'identifier': 'test'}",
+				},
+			},
+		})
+		if err != nil {
+			return false, err
+		}
+		return compiled.Matches(report, Source{})
+	}
+
+	DescribeTable("should evaluate to true",
+		func(expr string) {
+			matched, err := matches(expr)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matched).To(BeTrue())
+		},
+		Entry("image.parse with tag",
+			`cel.bind(ref, image.parse('ghcr.io/crashappsec/chalkular:v1.2.3'),
+				ref.registry == 'ghcr.io' && ref.repository == 'crashappsec/chalkular' &&
+				ref.tag == 'v1.2.3' && ref.digest == '')`),
+		Entry("image.parse docker hub defaults",
+			`image.parse('nginx').registry == 'index.docker.io' &&
+				image.parse('nginx').repository == 'library/nginx' && image.parse('nginx').tag == 'latest'`),
+		Entry("image.parse with digest",
+			`image.parse('nginx@sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855').tag == '' &&
+				image.parse('nginx@sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855').digest ==
+				'sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855'`),
+		Entry("image.parse with tag and digest",
+			`image.parse('nginx:1.27@sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855').tag == '1.27'`),
+		Entry("digest.normalize without algorithm", `digest.normalize('ABCDEF') == 'sha256:abcdef'`),
+		Entry("digest.normalize with algorithm", `digest.normalize('sha256:abcdef') == 'sha256:abcdef'`),
+		Entry("semver.compare", `semver.compare('v1.2.3', '1.10.0') < 0 && semver.compare('1.2.3', 'v1.2.3') == 0`),
+		Entry("semver.satisfies", `semver.satisfies('1.2.3', '>= 1.2, < 2.0') && !semver.satisfies('2.0.0', '~1.2')`),
+		Entry("semver.isValid", `semver.isValid('1.2.3') && !semver.isValid('latest')`),
+		Entry("matchesGlob", `'ghcr.io/crashappsec/chalkular'.matchesGlob('ghcr.io/crashappsec/*') &&
+			!'ghcr.io/crashappsec/team/app'.matchesGlob('ghcr.io/crashappsec/*')`),
+		Entry("matchesGlob on a chalk key",
+			`chalks(report, 'Docker Image').exists(c, c._REPO_TAGS[0].matchesGlob('ghcr.io/crashappsec/*'))`),
+		Entry("regex.extract", `regex.extract('chalkular:v1.2.3', ':(.*)$') == optional.of('v1.2.3')`),
+		Entry("chalks with artifact type", `chalks(report, 'Docker Image').size() == 2`),
+		Entry("chalks without artifact type", `chalks(report).size() == 3`),
+		Entry("chalks returns typed marks",
+			`chalks(report, 'Docker Image').all(c, image.parse(c._REPO_TAGS[0]).registry != '')`),
+	)
+
+	DescribeTable("should fail to compile",
+		func(expr string) {
+			_, err := matches(expr)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("unknown image reference field", `image.parse('nginx').registy == ''`),
+		Entry("chalks of a non-report", `chalks({}, 'Docker Image').size() == 0`),
+	)
+
+	DescribeTable("should fail at runtime",
+		func(expr string) {
+			_, err := matches(expr)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("invalid image reference", `image.parse('INVALID:://').registry == ''`),
+		Entry("invalid semantic version", `semver.compare('latest', '1.0.0') == 0`),
+		Entry("invalid glob pattern", `'repo'.matchesGlob('[')`),
+	)
+})
@@ -9,14 +9,27 @@
 package policy
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/crashappsec/ocular/api/v1beta1"
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/interpreter"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
+// ErrEvalBudgetExceeded is returned (wrapped) when evaluating an expression
+// exceeds either the cost limit or the timeout set in [CompilerOptions]
+var ErrEvalBudgetExceeded = errors.New("CEL evaluation budget exceeded")
+
 // CompiledPolicy holds the compiled CEL programs for a single ChalkReportPolicy.
 type CompiledPolicy struct {
 	ObservedGeneration int64
@@ -28,11 +41,31 @@ type CompiledPolicy struct {
 	ForEach          cel.Program
 	DownloaderParams cel.Program
 	ProfileParams    cel.Program
+	Labels           cel.Program
+	Annotations      cel.Program
+	ProfileRef       cel.Program
+	DownloaderRef    cel.Program
+	DedupKey         cel.Program
+
+	evalTimeout time.Duration
+}
+
+// evalContext returns the context to evaluate the
+// programs of the policy for a single report with
+func (c CompiledPolicy) evalContext() (context.Context, context.CancelFunc) {
+	if c.evalTimeout > 0 {
+		return context.WithTimeout(context.Background(), c.evalTimeout)
+	}
+	return context.Background(), func() {}
 }
 
-func (c CompiledPolicy) Matches(report map[string]any) (bool, error) {
-	policyMatch, _, err := c.MatchCondition.Eval(map[string]any{
+func (c CompiledPolicy) Matches(report map[string]any, source Source) (bool, error) {
+	ctx, cancel := c.evalContext()
+	defer cancel()
+
+	policyMatch, err := eval(ctx, c.MatchCondition, map[string]any{
 		"report": report,
+		"source": source.value(),
 	})
 	if err != nil {
 		return false, err
@@ -48,12 +81,39 @@ type PipelineValues struct {
 	Target           v1beta1.Target
 	DownloaderParams []v1beta1.ParameterSetting
 	ProfileParams    []v1beta1.ParameterSetting
+	Labels           map[string]string
+	Annotations      map[string]string
+	// ProfileRef and DownloaderRef are only set if the policy
+	// has the respective extraction expression
+	ProfileRef    *ObjectReference
+	DownloaderRef *ObjectReference
+	// DedupKey is only set if the policy
+	// has a dedup key expression
+	DedupKey string
+	// EachIndex is the index of the 'forEach' item the values
+	// were extracted for, only set if the policy has a forEach expression
+	EachIndex *int
 }
 
-func (c CompiledPolicy) Extract(report map[string]any) ([]PipelineValues, error) {
+// ObjectReference is a reference to a profile
+// or downloader extracted from a chalk report.
+// An empty kind is the kind of the pipeline template
+type ObjectReference struct {
+	Name string
+	Kind string
+}
+
+func (c CompiledPolicy) Extract(report map[string]any, source Source) ([]PipelineValues, error) {
+	ctx, cancel := c.evalContext()
+	defer cancel()
+
+	sourceValue := source.value()
 	var activations []map[string]any
 	if c.ForEach != nil {
-		each, err := evalForEach(c.ForEach, report)
+		each, err := evalForEach(ctx, c.ForEach, map[string]any{
+			"report": report,
+			"source": sourceValue,
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to evaluate for each expression: %w", err)
 		}
@@ -61,47 +121,107 @@ func (c CompiledPolicy) Extract(report map[string]any) ([]PipelineValues, error)
 		for _, e := range each {
 			activations = append(activations, map[string]any{
 				"report": report,
+				"source": sourceValue,
 				"each":   e,
 			})
 		}
 
 	} else {
-		activations = append(activations, map[string]any{"report": report})
+		activations = append(activations, map[string]any{"report": report, "source": sourceValue})
 	}
 
-	values := make([]PipelineValues, len(activations))
+	var values []PipelineValues
 	for i, a := range activations {
-		target, err := evalTarget(c.Target, a)
+		targets, err := evalTargets(ctx, c.Target, a)
 		if err != nil {
 			return nil, fmt.Errorf("failed to evaluate target: %w", err)
 		}
 
-		vals := PipelineValues{
-			Target: target,
+		var vals PipelineValues
+		if c.ForEach != nil {
+			vals.EachIndex = new(i)
 		}
 
 		if c.ProfileParams != nil {
-			vals.ProfileParams, err = evalParameters(c.ProfileParams, a)
+			vals.ProfileParams, err = evalParameters(ctx, c.ProfileParams, a)
 			if err != nil {
 				return nil, fmt.Errorf("failed to evaluate profile parameters: %w", err)
 			}
 		}
 
 		if c.DownloaderParams != nil {
-			vals.DownloaderParams, err = evalParameters(c.DownloaderParams, a)
+			vals.DownloaderParams, err = evalParameters(ctx, c.DownloaderParams, a)
 			if err != nil {
 				return nil, fmt.Errorf("failed to evalulate downloader params: %w", err)
 			}
 		}
-		values[i] = vals
+
+		if c.Labels != nil {
+			vals.Labels, err = evalLabels(ctx, c.Labels, a)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate labels: %w", err)
+			}
+		}
+
+		if c.Annotations != nil {
+			vals.Annotations, err = evalAnnotations(ctx, c.Annotations, a)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate annotations: %w", err)
+			}
+		}
+
+		if c.ProfileRef != nil {
+			vals.ProfileRef, err = evalObjectReference(ctx, c.ProfileRef, a)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate profile reference: %w", err)
+			}
+		}
+
+		if c.DownloaderRef != nil {
+			vals.DownloaderRef, err = evalObjectReference(ctx, c.DownloaderRef, a)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate downloader reference: %w", err)
+			}
+		}
+
+		if c.DedupKey != nil {
+			vals.DedupKey, err = evalDedupKey(ctx, c.DedupKey, a)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate dedup key: %w", err)
+			}
+		}
+
+		// a pipeline is created for each target, with the values extracted for the same
+		// activation. The dedup key is only evaluated once for the activation, so
+		// it is qualified by the target to keep the pipelines of each target distinct.
+		dedupKey := vals.DedupKey
+		for _, target := range targets {
+			vals.Target = target
+			if dedupKey != "" && len(targets) > 1 {
+				vals.DedupKey = dedupKey + "/" + target.Identifier + "@" + target.Version
+			}
+			values = append(values, vals)
+		}
 	}
 	return values, nil
 }
 
-func evalForEach(p cel.Program, report map[string]any) ([]any, error) {
-	val, _, err := p.Eval(map[string]any{
-		"report": report,
-	})
+// eval evaluates the program, wrapping the error with
+// [ErrEvalBudgetExceeded] if the cost limit or timeout was reached
+func eval(ctx context.Context, p cel.Program, activation map[string]any) (ref.Val, error) {
+	val, _, err := p.ContextEval(ctx, activation)
+	if err == nil {
+		return val, nil
+	}
+	if _, cancelled := errors.AsType[interpreter.EvalCancelledError](err); cancelled ||
+		errors.Is(err, interpreter.InterruptError{}) {
+		return nil, fmt.Errorf("%w: %w", ErrEvalBudgetExceeded, err)
+	}
+	return nil, err
+}
+
+func evalForEach(ctx context.Context, p cel.Program, activation map[string]any) ([]any, error) {
+	val, err := eval(ctx, p, activation)
 	if err != nil {
 		return nil, err
 	}
@@ -120,48 +240,159 @@ func evalForEach(p cel.Program, report map[string]any) ([]any, error) {
 	}
 }
 
-func evalParameters(p cel.Program, activation map[string]any) ([]v1beta1.ParameterSetting, error) {
-	val, _, err := p.Eval(activation)
+func evalStringMap(ctx context.Context, p cel.Program, activation map[string]any) (map[string]string, error) {
+	val, err := eval(ctx, p, activation)
 	if err != nil {
 		return nil, err
 	}
 
-	var settings []v1beta1.ParameterSetting
 	native, err := val.ConvertToNative(reflect.TypeFor[map[string]string]())
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal parameters from %v: %w", val.Value(), err)
+		return nil, fmt.Errorf("failed to marshal string map from %v: %w", val.Value(), err)
 	}
 
 	m, ok := native.(map[string]string)
 	if !ok {
-		return nil, fmt.Errorf("failed to marshal parameters, got unexpected type %T", native)
+		return nil, fmt.Errorf("failed to marshal string map, got unexpected type %T", native)
 	}
+	return m, nil
+}
 
-	for k, v := range m {
+func evalParameters(ctx context.Context, p cel.Program, activation map[string]any) ([]v1beta1.ParameterSetting, error) {
+	m, err := evalStringMap(ctx, p, activation)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []v1beta1.ParameterSetting
+	// sort by name so that the generated
+	// pipelines are deterministic
+	for _, k := range slices.Sorted(maps.Keys(m)) {
 		settings = append(settings, v1beta1.ParameterSetting{
 			Name:  k,
-			Value: v,
+			Value: m[k],
 		})
 	}
 	return settings, nil
 }
 
-func evalTarget(p cel.Program, activation map[string]any) (v1beta1.Target, error) {
-	val, _, err := p.Eval(activation)
+// evalLabels evaluates the program to a string map and checks
+// that every key and value is a valid kubernetes label
+func evalLabels(ctx context.Context, p cel.Program, activation map[string]any) (map[string]string, error) {
+	labels, err := evalStringMap(ctx, p, activation)
 	if err != nil {
-		return v1beta1.Target{}, err
+		return nil, err
 	}
 
-	native, err := val.ConvertToNative(reflect.TypeFor[map[string]string]())
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid label key %q: %s", k, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(labels[k]); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid value %q for label %q: %s", labels[k], k, strings.Join(errs, "; "))
+		}
+	}
+	return labels, nil
+}
+
+// evalAnnotations evaluates the program to a string map and
+// checks that every key is a valid kubernetes annotation key
+func evalAnnotations(ctx context.Context, p cel.Program, activation map[string]any) (map[string]string, error) {
+	annotations, err := evalStringMap(ctx, p, activation)
 	if err != nil {
-		return v1beta1.Target{}, fmt.Errorf("failed to marshal target from value %s: %w", val.Value(), err)
+		return nil, err
 	}
 
-	m, ok := native.(map[string]string)
+	for _, k := range slices.Sorted(maps.Keys(annotations)) {
+		if errs := validation.IsQualifiedName(strings.ToLower(k)); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid annotation key %q: %s", k, strings.Join(errs, "; "))
+		}
+	}
+	return annotations, nil
+}
+
+func evalObjectReference(ctx context.Context, p cel.Program, activation map[string]any) (*ObjectReference, error) {
+	m, err := evalStringMap(ctx, p, activation)
+	if err != nil {
+		return nil, err
+	}
+
+	name, nameFound := m["name"]
+	if !nameFound || name == "" {
+		return nil, fmt.Errorf("reference must contain name")
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid reference name %q: %s", name, strings.Join(errs, "; "))
+	}
+
+	return &ObjectReference{
+		Name: name,
+		Kind: m["kind"],
+	}, nil
+}
+
+// evalDedupKey evaluates the program to a non-empty string
+func evalDedupKey(ctx context.Context, p cel.Program, activation map[string]any) (string, error) {
+	val, err := eval(ctx, p, activation)
+	if err != nil {
+		return "", err
+	}
+
+	key, ok := val.Value().(string)
 	if !ok {
-		return v1beta1.Target{}, fmt.Errorf("failed to marshal target, got unexpected type %T", native)
+		return "", fmt.Errorf("invalid type returned for 'dedupKey', expected string but got %s", val.Type().TypeName())
+	}
+	if key == "" {
+		return "", fmt.Errorf("dedup key must not be empty")
+	}
+	return key, nil
+}
+
+// evalTargets evaluates the program to either a single
+// target or a list of targets, returning each target
+func evalTargets(ctx context.Context, p cel.Program, activation map[string]any) ([]v1beta1.Target, error) {
+	val, err := eval(ctx, p, activation)
+	if err != nil {
+		return nil, err
 	}
 
+	var ms []map[string]string
+	if _, isList := val.(traits.Lister); isList {
+		native, err := val.ConvertToNative(reflect.TypeFor[[]map[string]string]())
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal targets from value %s: %w", val.Value(), err)
+		}
+
+		var ok bool
+		ms, ok = native.([]map[string]string)
+		if !ok {
+			return nil, fmt.Errorf("failed to marshal targets, got unexpected type %T", native)
+		}
+	} else {
+		native, err := val.ConvertToNative(reflect.TypeFor[map[string]string]())
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal target from value %s: %w", val.Value(), err)
+		}
+
+		m, ok := native.(map[string]string)
+		if !ok {
+			return nil, fmt.Errorf("failed to marshal target, got unexpected type %T", native)
+		}
+		ms = []map[string]string{m}
+	}
+
+	targets := make([]v1beta1.Target, 0, len(ms))
+	for i, m := range ms {
+		target, err := targetFromMap(m)
+		if err != nil {
+			return nil, fmt.Errorf("target %d: %w", i, err)
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+func targetFromMap(m map[string]string) (v1beta1.Target, error) {
 	id, idFound := m["identifier"]
 	if !idFound {
 		return v1beta1.Target{}, fmt.Errorf("target must container identifier")
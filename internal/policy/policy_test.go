@@ -8,8 +8,11 @@
 package policy
 
 import (
+	"time"
+
 	"github.com/crashappsec/chalkular/api/v1beta1"
 	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	"github.com/google/cel-go/cel"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -18,16 +21,16 @@ var _ = Describe("CompliledPolicy test", Ordered, func() {
 	Context("policy match and target expressions", func() {
 		policy := &v1beta1.ChalkReportPolicy{
 			Spec: v1beta1.ChalkReportPolicySpec{
-				MatchCondition: "report._MATCH",
+				MatchCondition: "report._X_MATCH",
 				Extraction: v1beta1.ChalkReportPolicyExtraction{
-					Target: "{'identifier': report._OCULAR_TEST_IDENTIFIER, 'version': report._OCULAR_TEST_VERSION}",
+					Target: "{'identifier': report._X_OCULAR_TEST_IDENTIFIER, 'version': report._X_OCULAR_TEST_VERSION}",
 				},
 			},
 		}
 		var compiled *CompiledPolicy
 		BeforeAll(func() {
 			By("compiling the policy")
-			compiler, err := NewCompiler(5)
+			compiler, err := NewCompiler(5, CompilerOptions{})
 			Expect(err).To(Not(HaveOccurred()))
 			compiled, err = compiler.compile(policy)
 			Expect(err).To(Not(HaveOccurred()))
@@ -36,10 +39,10 @@ var _ = Describe("CompliledPolicy test", Ordered, func() {
 		It("match should return an error when not a boolean", func() {
 			By("executing the CEL match expression with a invalid payload")
 			matches, err := compiled.Matches(map[string]any{
-				"_MATCH": map[string]any{
+				"_X_MATCH": map[string]any{
 					"complex": []string{"object"},
 				},
-			})
+			}, Source{})
 			Expect(err).To(HaveOccurred())
 			Expect(matches).To(BeFalse())
 		})
@@ -47,33 +50,33 @@ var _ = Describe("CompliledPolicy test", Ordered, func() {
 		It("match should evaluate to a boolean", func() {
 			By("executing the CEL match expression with a false condition")
 			matches, err := compiled.Matches(map[string]any{
-				"_MATCH": false,
-			})
+				"_X_MATCH": false,
+			}, Source{})
 			Expect(err).NotTo(HaveOccurred())
 			Expect(matches).To(BeFalse())
 			By("executing the CEL match expression with a true condition")
 			matches, err = compiled.Matches(map[string]any{
-				"_MATCH": true,
-			})
+				"_X_MATCH": true,
+			}, Source{})
 			Expect(err).NotTo(HaveOccurred())
 			Expect(matches).To(BeTrue())
 		})
 		It("extract should fail if the target expression does not evalute", func() {
 			By("Running the policy extraction on a payload")
 			_, err := compiled.Extract(map[string]any{
-				"_MATCH":                  true,
-				"_OCULAR_TEST_IDENTIFIER": []string{"string", "list"},
-				"_OCULAR_TEST_VERSION":    "testing-version",
-			})
+				"_X_MATCH":                  true,
+				"_X_OCULAR_TEST_IDENTIFIER": []string{"string", "list"},
+				"_X_OCULAR_TEST_VERSION":    "testing-version",
+			}, Source{})
 			Expect(err).To(HaveOccurred())
 		})
 		It("target expression should successfully evalute to a target", func() {
 			By("Running the policy extraction on a payload")
 			vals, err := compiled.Extract(map[string]any{
-				"_MATCH":                  true,
-				"_OCULAR_TEST_IDENTIFIER": "testing-identifier",
-				"_OCULAR_TEST_VERSION":    "testing-version",
-			})
+				"_X_MATCH":                  true,
+				"_X_OCULAR_TEST_IDENTIFIER": "testing-identifier",
+				"_X_OCULAR_TEST_VERSION":    "testing-version",
+			}, Source{})
 			Expect(err).NotTo(HaveOccurred())
 			Expect(vals).To(HaveLen(1))
 			val := vals[0]
@@ -86,21 +89,98 @@ var _ = Describe("CompliledPolicy test", Ordered, func() {
 		})
 	})
 
+	Context("list target expressions", func() {
+		policy := &v1beta1.ChalkReportPolicy{
+			Spec: v1beta1.ChalkReportPolicySpec{
+				MatchCondition: "true",
+				Extraction: v1beta1.ChalkReportPolicyExtraction{
+					Target:        "report._X_TARGETS.map(t, {'identifier': t})",
+					ProfileParams: new("{'PARAM': report._X_PARAM}"),
+				},
+			},
+		}
+		forEachPolicy := &v1beta1.ChalkReportPolicy{
+			Spec: v1beta1.ChalkReportPolicySpec{
+				MatchCondition: "true",
+				Extraction: v1beta1.ChalkReportPolicyExtraction{
+					ForEach:       new("report._X_ITEMS"),
+					Target:        "each._TAGS.map(t, {'identifier': each._REPO, 'version': t})",
+					ProfileParams: new("{'REPO': each._REPO}"),
+				},
+			},
+		}
+		var compiled, compiledForEach *CompiledPolicy
+		BeforeAll(func() {
+			By("compiling the policies")
+			compiler, err := NewCompiler(5, CompilerOptions{})
+			Expect(err).To(Not(HaveOccurred()))
+			compiled, err = compiler.compile(policy)
+			Expect(err).To(Not(HaveOccurred()))
+			compiledForEach, err = compiler.compile(forEachPolicy)
+			Expect(err).To(Not(HaveOccurred()))
+		})
+
+		It("should extract values for each target in the list", func() {
+			vals, err := compiled.Extract(map[string]any{
+				"_X_TARGETS": []string{"test1", "test2"},
+				"_X_PARAM":   "value",
+			}, Source{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vals).To(HaveLen(2))
+			Expect(vals[0].Target).To(Equal(ocularv1beta1.Target{Identifier: "test1"}))
+			Expect(vals[1].Target).To(Equal(ocularv1beta1.Target{Identifier: "test2"}))
+			for _, val := range vals {
+				Expect(val.ProfileParams).To(ConsistOf(ocularv1beta1.ParameterSetting{Name: "PARAM", Value: "value"}))
+			}
+		})
+
+		It("should extract no values for an empty list", func() {
+			vals, err := compiled.Extract(map[string]any{
+				"_X_TARGETS": []string{},
+				"_X_PARAM":   "value",
+			}, Source{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vals).To(BeEmpty())
+		})
+
+		It("should combine the targets with the for each items", func() {
+			vals, err := compiledForEach.Extract(map[string]any{
+				"_X_ITEMS": []any{
+					map[string]any{"_REPO": "repo1", "_TAGS": []string{"v1", "v2"}},
+					map[string]any{"_REPO": "repo2", "_TAGS": []string{"v3"}},
+				},
+			}, Source{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vals).To(HaveLen(3))
+			Expect(vals[0].Target).To(Equal(ocularv1beta1.Target{Identifier: "repo1", Version: "v1"}))
+			Expect(vals[1].Target).To(Equal(ocularv1beta1.Target{Identifier: "repo1", Version: "v2"}))
+			Expect(vals[2].Target).To(Equal(ocularv1beta1.Target{Identifier: "repo2", Version: "v3"}))
+			Expect(vals[2].ProfileParams).To(ConsistOf(ocularv1beta1.ParameterSetting{Name: "REPO", Value: "repo2"}))
+		})
+
+		It("should fail if any target in the list fails to evaluate", func() {
+			_, err := compiledForEach.Extract(map[string]any{
+				"_X_ITEMS": []any{map[string]any{"_TAGS": []string{"v1"}}},
+			}, Source{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Context("policy parameter expressions", func() {
 		policy := &v1beta1.ChalkReportPolicy{
 			Spec: v1beta1.ChalkReportPolicySpec{
 				MatchCondition: "true",
 				Extraction: v1beta1.ChalkReportPolicyExtraction{
-					Target:           "{'identifier': has(report._IDENTIFIER) ? report._IDENTIFIER : 'testing'}",
-					ProfileParams:    new("{'ENABLED': report._ENABLED}"),
-					DownloaderParams: new("report._DL_PARAMS"),
+					Target:           "{'identifier': has(report._X_IDENTIFIER) ? report._X_IDENTIFIER : 'testing'}",
+					ProfileParams:    new("{'ENABLED': report._X_ENABLED}"),
+					DownloaderParams: new("report._X_DL_PARAMS"),
 				},
 			},
 		}
 		var compiled *CompiledPolicy
 		BeforeAll(func() {
 			By("compiling the policy")
-			compiler, err := NewCompiler(5)
+			compiler, err := NewCompiler(5, CompilerOptions{})
 			Expect(err).To(Not(HaveOccurred()))
 			compiled, err = compiler.compile(policy)
 			Expect(err).To(Not(HaveOccurred()))
@@ -109,9 +189,9 @@ var _ = Describe("CompliledPolicy test", Ordered, func() {
 		It("extract should return an error when parameter expression fails", func() {
 			By("executing the extract expression with a valid payload")
 			extract, err := compiled.Extract(map[string]any{
-				"_ENABLED":   "YES",
-				"_DL_PARAMS": map[string]string{},
-			})
+				"_X_ENABLED":   "YES",
+				"_X_DL_PARAMS": map[string]string{},
+			}, Source{})
 			Expect(err).NotTo(HaveOccurred())
 			Expect(extract).To(HaveLen(1))
 			val := extract[0]
@@ -125,52 +205,204 @@ var _ = Describe("CompliledPolicy test", Ordered, func() {
 
 			By("getting an error using the wrong type for profile parameter value")
 			_, err = compiled.Extract(map[string]any{
-				"_ENABLED":   4,
-				"_DL_PARAMS": map[string]string{},
-			})
+				"_X_ENABLED":   4,
+				"_X_DL_PARAMS": map[string]string{},
+			}, Source{})
 			Expect(err).To(HaveOccurred())
 
 			By("getting an error using the wrong type for downloader params")
 			_, err = compiled.Extract(map[string]any{
-				"_ENABLED":   "YES",
-				"_DL_PARAMS": []any{1, "test", true},
-			})
+				"_X_ENABLED":   "YES",
+				"_X_DL_PARAMS": []any{1, "test", true},
+			}, Source{})
 			Expect(err).To(HaveOccurred())
 
 		})
 	})
+	Context("policy metadata expressions", func() {
+		policy := &v1beta1.ChalkReportPolicy{
+			Spec: v1beta1.ChalkReportPolicySpec{
+				MatchCondition: "true",
+				Extraction: v1beta1.ChalkReportPolicyExtraction{
+					Target:      "{'identifier': 'testing'}",
+					Labels:      new("{'chalk.ocular.crashoverride.run/repo': report._X_REPO}"),
+					Annotations: new("{'chalk.ocular.crashoverride.run/commit-message': report._X_MESSAGE}"),
+				},
+			},
+		}
+		var compiled *CompiledPolicy
+		BeforeAll(func() {
+			By("compiling the policy")
+			compiler, err := NewCompiler(5, CompilerOptions{})
+			Expect(err).To(Not(HaveOccurred()))
+			compiled, err = compiler.compile(policy)
+			Expect(err).To(Not(HaveOccurred()))
+		})
+
+		It("extract should return the labels and annotations", func() {
+			extract, err := compiled.Extract(map[string]any{
+				"_X_REPO":    "chalkular",
+				"_X_MESSAGE": "fix: a commit message, which is not a valid label!",
+			}, Source{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(extract).To(HaveLen(1))
+			Expect(extract[0].Labels).To(Equal(map[string]string{
+				"chalk.ocular.crashoverride.run/repo": "chalkular",
+			}))
+			Expect(extract[0].Annotations).To(Equal(map[string]string{
+				"chalk.ocular.crashoverride.run/commit-message": "fix: a commit message, which is not a valid label!",
+			}))
+		})
+
+		It("extract should return an error for an invalid label value", func() {
+			_, err := compiled.Extract(map[string]any{
+				"_X_REPO":    "github.com/crashappsec/chalkular",
+				"_X_MESSAGE": "message",
+			}, Source{})
+			Expect(err).To(MatchError(ContainSubstring("invalid value")))
+		})
+	})
+	Context("policy reference expressions", func() {
+		policy := &v1beta1.ChalkReportPolicy{
+			Spec: v1beta1.ChalkReportPolicySpec{
+				MatchCondition: "true",
+				Extraction: v1beta1.ChalkReportPolicyExtraction{
+					ForEach:       new("report._X_ITEMS"),
+					Target:        "{'identifier': each._IDENTIFIER}",
+					ProfileRef:    new("{'name': each._TYPE == 'build-context' ? 'build-context' : 'analyze'}"),
+					DownloaderRef: new("{'name': each._DOWNLOADER, 'kind': 'Downloader'}"),
+				},
+			},
+		}
+		var compiled *CompiledPolicy
+		BeforeAll(func() {
+			By("compiling the policy")
+			compiler, err := NewCompiler(5, CompilerOptions{})
+			Expect(err).To(Not(HaveOccurred()))
+			compiled, err = compiler.compile(policy)
+			Expect(err).To(Not(HaveOccurred()))
+		})
+
+		It("extract should return the references for each item", func() {
+			extract, err := compiled.Extract(map[string]any{
+				"_X_ITEMS": []any{
+					map[string]any{"_IDENTIFIER": "test1", "_TYPE": "build-context", "_DOWNLOADER": "git"},
+					map[string]any{"_IDENTIFIER": "test2", "_TYPE": "image", "_DOWNLOADER": "docker"},
+				},
+			}, Source{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(extract).To(HaveLen(2))
+			Expect(extract[0].ProfileRef).To(Equal(&ObjectReference{Name: "build-context"}))
+			Expect(extract[0].DownloaderRef).To(Equal(&ObjectReference{Name: "git", Kind: "Downloader"}))
+			Expect(extract[1].ProfileRef).To(Equal(&ObjectReference{Name: "analyze"}))
+			Expect(extract[1].DownloaderRef).To(Equal(&ObjectReference{Name: "docker", Kind: "Downloader"}))
+		})
+
+		It("extract should return an error for an invalid name", func() {
+			_, err := compiled.Extract(map[string]any{
+				"_X_ITEMS": []any{
+					map[string]any{"_IDENTIFIER": "test1", "_TYPE": "image", "_DOWNLOADER": "Not A Name"},
+				},
+			}, Source{})
+			Expect(err).To(MatchError(ContainSubstring("invalid reference name")))
+		})
+	})
+
+	Context("policy dedup key expression", func() {
+		policy := &v1beta1.ChalkReportPolicy{
+			Spec: v1beta1.ChalkReportPolicySpec{
+				MatchCondition: "true",
+				Extraction: v1beta1.ChalkReportPolicyExtraction{
+					ForEach:  new("report._X_ITEMS"),
+					Target:   "{'identifier': each._IDENTIFIER}",
+					DedupKey: new("each._DIGEST"),
+				},
+			},
+		}
+		var compiled *CompiledPolicy
+		BeforeAll(func() {
+			By("compiling the policy")
+			compiler, err := NewCompiler(5, CompilerOptions{})
+			Expect(err).To(Not(HaveOccurred()))
+			compiled, err = compiler.compile(policy)
+			Expect(err).To(Not(HaveOccurred()))
+		})
+
+		It("extract should return the dedup key for each item", func() {
+			extract, err := compiled.Extract(map[string]any{
+				"_X_ITEMS": []any{
+					map[string]any{"_IDENTIFIER": "test1", "_DIGEST": "sha256:aaaa"},
+					map[string]any{"_IDENTIFIER": "test2", "_DIGEST": "sha256:bbbb"},
+				},
+			}, Source{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(extract).To(HaveLen(2))
+			Expect(extract[0].DedupKey).To(Equal("sha256:aaaa"))
+			Expect(extract[1].DedupKey).To(Equal("sha256:bbbb"))
+		})
+
+		It("extract should qualify the dedup key by the target for a list of targets", func() {
+			listPolicy := policy.DeepCopy()
+			listPolicy.Spec.Extraction.Target = "[{'identifier': each._IDENTIFIER, 'version': 'v1'}, {'identifier': each._IDENTIFIER}]"
+			compiler, err := NewCompiler(5, CompilerOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			listCompiled, err := compiler.compile(listPolicy)
+			Expect(err).NotTo(HaveOccurred())
+
+			extract, err := listCompiled.Extract(map[string]any{
+				"_X_ITEMS": []any{map[string]any{"_IDENTIFIER": "test1", "_DIGEST": "sha256:aaaa"}},
+			}, Source{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(extract).To(HaveLen(2))
+			Expect(extract[0].DedupKey).To(Equal("sha256:aaaa/test1@v1"))
+			Expect(extract[1].DedupKey).To(Equal("sha256:aaaa/test1@"))
+		})
+
+		It("extract should return an error for an empty or non-string key", func() {
+			_, err := compiled.Extract(map[string]any{
+				"_X_ITEMS": []any{map[string]any{"_IDENTIFIER": "test1", "_DIGEST": ""}},
+			}, Source{})
+			Expect(err).To(MatchError(ContainSubstring("must not be empty")))
+
+			_, err = compiled.Extract(map[string]any{
+				"_X_ITEMS": []any{map[string]any{"_IDENTIFIER": "test1", "_DIGEST": 1}},
+			}, Source{})
+			Expect(err).To(MatchError(ContainSubstring("expected string")))
+		})
+	})
+
 	Context("for each policy expressions", func() {
 		policy := &v1beta1.ChalkReportPolicy{
 			Spec: v1beta1.ChalkReportPolicySpec{
 				MatchCondition: "true",
 				Extraction: v1beta1.ChalkReportPolicyExtraction{
-					ForEach:          new("report._ITEMS"),
+					ForEach:          new("report._X_ITEMS"),
 					Target:           "{'identifier': each._IDENTIFIER}",
 					ProfileParams:    new("{'PROFILE_PARAM': each._PROFILE}"),
-					DownloaderParams: new("{'DL_PARAM': report._DL}"),
+					DownloaderParams: new("{'DL_PARAM': report._X_DL}"),
 				},
 			},
 		}
 		var compiled *CompiledPolicy
 		BeforeAll(func() {
 			By("compiling the policy")
-			compiler, err := NewCompiler(5)
+			compiler, err := NewCompiler(5, CompilerOptions{})
 			Expect(err).To(Not(HaveOccurred()))
 			compiled, err = compiler.compile(policy)
 			Expect(err).To(Not(HaveOccurred()))
 		})
 		It("should fail if the for each does not return a list", func() {
 			_, err := compiled.Extract(map[string]any{
-				"_ITEMS": "string!",
-				"_DL":    "constant",
-			})
+				"_X_ITEMS": "string!",
+				"_X_DL":    "constant",
+			}, Source{})
 			Expect(err).To(HaveOccurred())
 		})
 
 		It("run the extraction for every item of the for each", func() {
 			By("executing the extract expression with a valid payload")
 			extract, err := compiled.Extract(map[string]any{
-				"_ITEMS": []any{
+				"_X_ITEMS": []any{
 					map[string]string{
 						"_IDENTIFIER": "test1",
 						"_PROFILE":    "string1",
@@ -184,8 +416,8 @@ var _ = Describe("CompliledPolicy test", Ordered, func() {
 						"_PROFILE":    "string3",
 					},
 				},
-				"_DL": "constant",
-			})
+				"_X_DL": "constant",
+			}, Source{})
 			Expect(err).NotTo(HaveOccurred())
 			Expect(extract).To(HaveLen(3))
 			test1 := extract[0]
@@ -202,8 +434,10 @@ var _ = Describe("CompliledPolicy test", Ordered, func() {
 					Value: "string1",
 				},
 			}))
+			Expect(test1.EachIndex).To(HaveValue(Equal(0)))
 			test2 := extract[1]
 			Expect(test2.Target.Identifier).To(Equal("test2"))
+			Expect(test2.EachIndex).To(HaveValue(Equal(1)))
 			Expect(test2.ProfileParams).To(BeEquivalentTo([]ocularv1beta1.ParameterSetting{
 				{
 					Name:  "PROFILE_PARAM",
@@ -232,4 +466,113 @@ var _ = Describe("CompliledPolicy test", Ordered, func() {
 			}))
 		})
 	})
+
+	Context("policy evaluation limits", func() {
+		// each iteration costs multiple units, so this will
+		// exceed a small cost limit for any large enough list
+		policy := &v1beta1.ChalkReportPolicy{
+			Spec: v1beta1.ChalkReportPolicySpec{
+				MatchCondition: "report._X_ITEMS.all(i, i > 0)",
+				Extraction: v1beta1.ChalkReportPolicyExtraction{
+					ForEach: new("report._X_ITEMS.map(i, report._X_ITEMS.map(j, i * j))"),
+					Target:  "{'identifier': string(each.size())}",
+				},
+			},
+		}
+		items := func(n int) map[string]any {
+			var list []any
+			for i := range n {
+				list = append(list, i+1)
+			}
+			return map[string]any{"_X_ITEMS": list}
+		}
+
+		It("should reject expressions that always exceed the cost limit", func() {
+			compiler, err := NewCompiler(5, CompilerOptions{CostLimit: 100})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = compiler.Compile(&v1beta1.ChalkReportPolicy{
+				Spec: v1beta1.ChalkReportPolicySpec{
+					MatchCondition: "[1, 2, 3, 4, 5, 6, 7, 8, 9, 10].map(i, [1, 2, 3, 4, 5, 6, 7, 8, 9, 10].map(j, i * j)).size() > 0",
+					Extraction: v1beta1.ChalkReportPolicyExtraction{
+						Target: "{'identifier': 'test'}",
+					},
+				},
+			})
+			Expect(err).To(MatchError(ContainSubstring("exceeds the cost limit")))
+
+			errs := compiler.Validate(&v1beta1.ChalkReportPolicy{
+				Spec: v1beta1.ChalkReportPolicySpec{
+					MatchCondition: "[1, 2, 3, 4, 5, 6, 7, 8, 9, 10].map(i, [1, 2, 3, 4, 5, 6, 7, 8, 9, 10].map(j, i * j)).size() > 0",
+					Extraction: v1beta1.ChalkReportPolicyExtraction{
+						Target: "{'identifier': 'test'}",
+					},
+				},
+			})
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("spec.matchCondition"))
+		})
+
+		It("should return ErrEvalBudgetExceeded when the cost limit is reached at runtime", func() {
+			compiler, err := NewCompiler(5, CompilerOptions{CostLimit: 1000})
+			Expect(err).NotTo(HaveOccurred())
+			compiled, err := compiler.Compile(policy)
+			Expect(err).NotTo(HaveOccurred())
+
+			matches, err := compiled.Matches(items(5), Source{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matches).To(BeTrue())
+			_, err = compiled.Extract(items(5), Source{})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = compiled.Matches(items(1000), Source{})
+			Expect(err).To(MatchError(ErrEvalBudgetExceeded))
+			_, err = compiled.Extract(items(100), Source{})
+			Expect(err).To(MatchError(ErrEvalBudgetExceeded))
+		})
+
+		It("should evaluate a policy with a cost right at the limit", func() {
+			policy := &v1beta1.ChalkReportPolicy{
+				Spec: v1beta1.ChalkReportPolicySpec{
+					MatchCondition: "report._X_ITEMS.all(i, i > 0)",
+					Extraction: v1beta1.ChalkReportPolicyExtraction{
+						Target: "{'identifier': 'test'}",
+					},
+				},
+			}
+
+			By("measuring the cost of the match condition")
+			unlimited, err := NewCompiler(5, CompilerOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			ast, issues := unlimited.env.Compile(policy.Spec.MatchCondition)
+			Expect(issues.Err()).NotTo(HaveOccurred())
+			prg, err := unlimited.env.Program(ast, cel.EvalOptions(cel.OptTrackCost))
+			Expect(err).NotTo(HaveOccurred())
+			_, details, err := prg.Eval(map[string]any{"report": items(100), "source": Source{}.value()})
+			Expect(err).NotTo(HaveOccurred())
+			cost := *details.ActualCost()
+
+			compiler, err := NewCompiler(5, CompilerOptions{CostLimit: cost})
+			Expect(err).NotTo(HaveOccurred())
+			compiled, err := compiler.Compile(policy)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(compiled.Matches(items(100), Source{})).To(BeTrue())
+
+			compiler, err = NewCompiler(5, CompilerOptions{CostLimit: cost - 1})
+			Expect(err).NotTo(HaveOccurred())
+			compiled, err = compiler.Compile(policy)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = compiled.Matches(items(100), Source{})
+			Expect(err).To(MatchError(ErrEvalBudgetExceeded))
+		})
+
+		It("should return ErrEvalBudgetExceeded when the timeout is reached", func() {
+			compiler, err := NewCompiler(5, CompilerOptions{EvalTimeout: time.Millisecond})
+			Expect(err).NotTo(HaveOccurred())
+			compiled, err := compiler.Compile(policy)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = compiled.Extract(items(2000), Source{})
+			Expect(err).To(MatchError(ErrEvalBudgetExceeded))
+		})
+	})
 })
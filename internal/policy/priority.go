@@ -0,0 +1,90 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package policy
+
+import (
+	"cmp"
+	"slices"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/ocular/api/v1beta1"
+)
+
+// SortByPriority sorts the policies in the order they are evaluated for a report, see [ComparePriority].
+func SortByPriority(policies []chalkularv1beta1.ChalkReportPolicy) {
+	slices.SortStableFunc(policies, func(a, b chalkularv1beta1.ChalkReportPolicy) int {
+		return ComparePriority(&a, &b)
+	})
+}
+
+// ComparePriority orders policies by priority (highest first), exclusive policies before
+// additive policies of the same priority, then by namespace and name so that the order is deterministic.
+func ComparePriority(a, b *chalkularv1beta1.ChalkReportPolicy) int {
+	return cmp.Or(
+		cmp.Compare(b.Spec.Priority, a.Spec.Priority),
+		cmp.Compare(exclusiveOrder(a), exclusiveOrder(b)),
+		cmp.Compare(a.Namespace, b.Namespace),
+		cmp.Compare(a.Name, b.Name),
+	)
+}
+
+func exclusiveOrder(p *chalkularv1beta1.ChalkReportPolicy) int {
+	if p.Spec.MatchPolicy == chalkularv1beta1.MatchPolicyExclusive {
+		return 0
+	}
+	return 1
+}
+
+// ExclusiveClaims are the targets of a single report that exclusive policies
+// generated pipelines for. Policies must be evaluated in the order of [SortByPriority].
+type ExclusiveClaims struct {
+	// claimed is the name of the policy that claimed each target, by namespace
+	claimed map[string]map[v1beta1.Target]string
+}
+
+// Filter removes the values with a target claimed by an exclusive policy in the namespace of
+// the policy, returning the values that are kept and the names of the policies that claimed
+// the removed targets. If the policy is exclusive, the targets of the kept values are claimed,
+// unless the policy is in audit mode, since it does not create pipelines.
+// A nil ExclusiveClaims keeps every value.
+func (c *ExclusiveClaims) Filter(p *chalkularv1beta1.ChalkReportPolicy, values []PipelineValues) ([]PipelineValues, []string) {
+	if c == nil {
+		return values, nil
+	}
+	claimed := c.claimed[p.Namespace]
+
+	var (
+		kept []PipelineValues
+		by   []string
+	)
+	for _, vs := range values {
+		if owner, ok := claimed[vs.Target]; ok && owner != p.Name {
+			if !slices.Contains(by, owner) {
+				by = append(by, owner)
+			}
+			continue
+		}
+		kept = append(kept, vs)
+	}
+
+	if p.Spec.MatchPolicy == chalkularv1beta1.MatchPolicyExclusive &&
+		p.Spec.Mode != chalkularv1beta1.PolicyModeAudit && len(kept) > 0 {
+		if c.claimed == nil {
+			c.claimed = make(map[string]map[v1beta1.Target]string)
+		}
+		if claimed == nil {
+			claimed = make(map[v1beta1.Target]string)
+			c.claimed[p.Namespace] = claimed
+		}
+		for _, vs := range kept {
+			claimed[vs.Target] = p.Name
+		}
+	}
+	return kept, by
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+package policy
+
+import (
+	"github.com/crashappsec/chalkular/api/v1beta1"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("policy priority", func() {
+	newPolicy := func(namespace, name string, priority int32, matchPolicy v1beta1.MatchPolicy) v1beta1.ChalkReportPolicy {
+		return v1beta1.ChalkReportPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: v1beta1.ChalkReportPolicySpec{
+				Priority:    priority,
+				MatchPolicy: matchPolicy,
+			},
+		}
+	}
+	values := func(identifiers ...string) []PipelineValues {
+		var vs []PipelineValues
+		for _, identifier := range identifiers {
+			vs = append(vs, PipelineValues{Target: ocularv1beta1.Target{Identifier: identifier}})
+		}
+		return vs
+	}
+
+	It("should sort by priority, then exclusive policies first, then namespace and name", func() {
+		policies := []v1beta1.ChalkReportPolicy{
+			newPolicy("b", "additive", 0, v1beta1.MatchPolicyAdditive),
+			newPolicy("a", "additive", 0, v1beta1.MatchPolicyAdditive),
+			newPolicy("a", "exclusive", 0, v1beta1.MatchPolicyExclusive),
+			newPolicy("a", "high", 10, v1beta1.MatchPolicyAdditive),
+			newPolicy("a", "default", 0, ""),
+		}
+		SortByPriority(policies)
+
+		var names []string
+		for _, p := range policies {
+			names = append(names, p.Namespace+"/"+p.Name)
+		}
+		Expect(names).To(Equal([]string{"a/high", "a/exclusive", "a/additive", "a/default", "b/additive"}))
+	})
+
+	It("should remove values with a target claimed by an exclusive policy", func() {
+		var claims ExclusiveClaims
+		exclusive := newPolicy("a", "exclusive", 10, v1beta1.MatchPolicyExclusive)
+		additive := newPolicy("a", "additive", 0, v1beta1.MatchPolicyAdditive)
+
+		kept, by := claims.Filter(&exclusive, values("image1"))
+		Expect(kept).To(Equal(values("image1")))
+		Expect(by).To(BeEmpty())
+
+		kept, by = claims.Filter(&additive, values("image1", "image2"))
+		Expect(kept).To(Equal(values("image2")))
+		Expect(by).To(Equal([]string{"exclusive"}))
+
+		By("not claiming targets for additive policies")
+		other := newPolicy("a", "other", 0, v1beta1.MatchPolicyExclusive)
+		kept, by = claims.Filter(&other, values("image2"))
+		Expect(kept).To(Equal(values("image2")))
+		Expect(by).To(BeEmpty())
+	})
+
+	It("should not claim targets for exclusive policies in audit mode", func() {
+		var claims ExclusiveClaims
+		audit := newPolicy("a", "audit", 10, v1beta1.MatchPolicyExclusive)
+		audit.Spec.Mode = v1beta1.PolicyModeAudit
+		additive := newPolicy("a", "additive", 0, v1beta1.MatchPolicyAdditive)
+
+		claims.Filter(&audit, values("image1"))
+		kept, by := claims.Filter(&additive, values("image1"))
+		Expect(kept).To(Equal(values("image1")))
+		Expect(by).To(BeEmpty())
+	})
+
+	It("should only exclude targets claimed in the namespace of the policy", func() {
+		var claims ExclusiveClaims
+		exclusive := newPolicy("a", "exclusive", 10, v1beta1.MatchPolicyExclusive)
+		additive := newPolicy("b", "additive", 0, v1beta1.MatchPolicyAdditive)
+
+		claims.Filter(&exclusive, values("image1"))
+		kept, by := claims.Filter(&additive, values("image1"))
+		Expect(kept).To(Equal(values("image1")))
+		Expect(by).To(BeEmpty())
+	})
+
+	It("should keep every value without claims", func() {
+		var claims *ExclusiveClaims
+		exclusive := newPolicy("a", "exclusive", 10, v1beta1.MatchPolicyExclusive)
+		kept, by := claims.Filter(&exclusive, values("image1"))
+		Expect(kept).To(Equal(values("image1")))
+		Expect(by).To(BeEmpty())
+	})
+})
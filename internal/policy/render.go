@@ -0,0 +1,98 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package policy
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// RenderPipelines templates a [ocularv1beta1.Pipeline] for each of the
+// extracted values using the pipeline template of the policy.
+// The pipelines are not created, and will only have a generated name set.
+// Each pipeline is labeled and annotated with the policy and action ID it was generated
+// for, and is owned by the policy unless its deletion propagation is 'Orphan'.
+func RenderPipelines(policy *chalkularv1beta1.ChalkReportPolicy, actionID string, values []PipelineValues) []*ocularv1beta1.Pipeline {
+	pipelineTemplate := policy.Spec.PipelineTemplate
+
+	pipelines := make([]*ocularv1beta1.Pipeline, 0, len(values))
+	for _, vs := range values {
+		pipeline := &ocularv1beta1.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: fmt.Sprintf("chalkular-%s-", actionID),
+				Namespace:    policy.Namespace,
+				Annotations:  make(map[string]string),
+				Labels:       make(map[string]string),
+			},
+		}
+		maps.Copy(pipeline.Labels, pipelineTemplate.Labels)
+		maps.Copy(pipeline.Annotations, pipelineTemplate.Annotations)
+		maps.Copy(pipeline.Labels, vs.Labels)
+		maps.Copy(pipeline.Annotations, vs.Annotations)
+		setPolicyMetadata(pipeline, policy, actionID, vs)
+		pipelineTemplate.Spec.DeepCopyInto(&pipeline.Spec)
+
+		pipeline.Spec.DownloaderRef.Parameters = append(pipeline.Spec.DownloaderRef.Parameters, vs.DownloaderParams...)
+		pipeline.Spec.ProfileRef.Parameters = append(pipeline.Spec.ProfileRef.Parameters, vs.ProfileParams...)
+		pipeline.Spec.Target = vs.Target
+
+		if vs.ProfileRef != nil {
+			pipeline.Spec.ProfileRef.Name = vs.ProfileRef.Name
+			if vs.ProfileRef.Kind != "" {
+				pipeline.Spec.ProfileRef.Kind = vs.ProfileRef.Kind
+			}
+		}
+		if vs.DownloaderRef != nil {
+			pipeline.Spec.DownloaderRef.Name = vs.DownloaderRef.Name
+			if vs.DownloaderRef.Kind != "" {
+				pipeline.Spec.DownloaderRef.Kind = vs.DownloaderRef.Kind
+			}
+		}
+
+		pipelines = append(pipelines, pipeline)
+	}
+	return pipelines
+}
+
+// setPolicyMetadata sets the labels, annotations and owner reference
+// that trace the pipeline back to the policy and report it was generated for
+func setPolicyMetadata(pipeline *ocularv1beta1.Pipeline, policy *chalkularv1beta1.ChalkReportPolicy, actionID string, vs PipelineValues) {
+	pipeline.Labels[chalkularv1beta1.PipelinePolicyUIDLabel] = string(policy.UID)
+	if len(validation.IsValidLabelValue(policy.Name)) == 0 {
+		pipeline.Labels[chalkularv1beta1.PipelinePolicyLabel] = policy.Name
+	}
+	if len(validation.IsValidLabelValue(actionID)) == 0 {
+		pipeline.Labels[chalkularv1beta1.PipelineActionIDLabel] = actionID
+	}
+
+	pipeline.Annotations[chalkularv1beta1.PipelineActionIDLabel] = actionID
+	pipeline.Annotations[chalkularv1beta1.PipelinePolicyGenerationAnnotation] = strconv.FormatInt(policy.Generation, 10)
+	if vs.EachIndex != nil {
+		pipeline.Annotations[chalkularv1beta1.PipelineEachIndexAnnotation] = strconv.Itoa(*vs.EachIndex)
+	}
+
+	switch policy.Spec.DeletionPropagation {
+	case metav1.DeletePropagationBackground, metav1.DeletePropagationForeground:
+		pipeline.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: chalkularv1beta1.GroupVersion.String(),
+			Kind:       "ChalkReportPolicy",
+			Name:       policy.Name,
+			UID:        policy.UID,
+			// only blocks the deletion of the policy if it is deleted with foreground propagation,
+			// with 'Foreground' the policy controller deletes the pipelines before removing the policy
+			BlockOwnerDeletion: new(policy.Spec.DeletionPropagation == metav1.DeletePropagationForeground),
+		}}
+	}
+}
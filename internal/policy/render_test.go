@@ -0,0 +1,156 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+package policy
+
+import (
+	"strings"
+
+	"github.com/crashappsec/chalkular/api/v1beta1"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("RenderPipelines", func() {
+	policy := &v1beta1.ChalkReportPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-policy",
+			Namespace:  "test-namespace",
+			UID:        "uid-test-policy",
+			Generation: 3,
+		},
+		Spec: v1beta1.ChalkReportPolicySpec{
+			PipelineTemplate: ocularv1beta1.PipelineTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"team": "testing"},
+				},
+				Spec: ocularv1beta1.PipelineSpec{
+					ProfileRef: ocularv1beta1.ParameterizedLocalObjectReference{
+						Name: "test-profile",
+						Parameters: []ocularv1beta1.ParameterSetting{
+							{Name: "STATIC", Value: "static"},
+						},
+					},
+					DownloaderRef: ocularv1beta1.ParameterizedLocalObjectReference{
+						Name: "test-downloader",
+					},
+				},
+			},
+		},
+	}
+
+	It("should template a pipeline for each value", func() {
+		pipelines := RenderPipelines(policy, "action-id", []PipelineValues{
+			{
+				Target:        ocularv1beta1.Target{Identifier: "test1"},
+				ProfileParams: []ocularv1beta1.ParameterSetting{{Name: "DYNAMIC", Value: "1"}},
+			},
+			{
+				Target:           ocularv1beta1.Target{Identifier: "test2", Version: "v2"},
+				DownloaderParams: []ocularv1beta1.ParameterSetting{{Name: "DL", Value: "2"}},
+			},
+		})
+		Expect(pipelines).To(HaveLen(2))
+
+		first := pipelines[0]
+		Expect(first.GenerateName).To(Equal("chalkular-action-id-"))
+		Expect(first.Namespace).To(Equal("test-namespace"))
+		Expect(first.Labels).To(HaveKeyWithValue("team", "testing"))
+		Expect(first.Spec.Target.Identifier).To(Equal("test1"))
+		Expect(first.Spec.ProfileRef.Parameters).To(ConsistOf(
+			ocularv1beta1.ParameterSetting{Name: "STATIC", Value: "static"},
+			ocularv1beta1.ParameterSetting{Name: "DYNAMIC", Value: "1"},
+		))
+
+		second := pipelines[1]
+		Expect(second.Spec.Target).To(Equal(ocularv1beta1.Target{Identifier: "test2", Version: "v2"}))
+		Expect(second.Spec.ProfileRef.Parameters).To(ConsistOf(
+			ocularv1beta1.ParameterSetting{Name: "STATIC", Value: "static"},
+		))
+		Expect(second.Spec.DownloaderRef.Parameters).To(ConsistOf(
+			ocularv1beta1.ParameterSetting{Name: "DL", Value: "2"},
+		))
+	})
+
+	It("should merge the extracted labels and annotations with the template", func() {
+		pipelines := RenderPipelines(policy, "action-id", []PipelineValues{
+			{
+				Target:      ocularv1beta1.Target{Identifier: "test1"},
+				Labels:      map[string]string{"team": "extracted", "repo": "chalkular"},
+				Annotations: map[string]string{"commit": "abc123"},
+			},
+		})
+		Expect(pipelines).To(HaveLen(1))
+		Expect(pipelines[0].Labels).To(Equal(map[string]string{
+			"team":                         "extracted",
+			"repo":                         "chalkular",
+			v1beta1.PipelinePolicyLabel:    "test-policy",
+			v1beta1.PipelinePolicyUIDLabel: "uid-test-policy",
+			v1beta1.PipelineActionIDLabel:  "action-id",
+		}))
+		Expect(pipelines[0].Annotations).To(Equal(map[string]string{
+			"commit":                      "abc123",
+			v1beta1.PipelineActionIDLabel: "action-id",
+			v1beta1.PipelinePolicyGenerationAnnotation: "3",
+		}))
+		Expect(policy.Spec.PipelineTemplate.Labels).To(Equal(map[string]string{"team": "testing"}))
+	})
+
+	It("should replace the references with the extracted references", func() {
+		pipelines := RenderPipelines(policy, "action-id", []PipelineValues{
+			{
+				Target:        ocularv1beta1.Target{Identifier: "test1"},
+				ProfileRef:    &ObjectReference{Name: "build-context"},
+				DownloaderRef: &ObjectReference{Name: "git", Kind: "ClusterDownloader"},
+			},
+			{
+				Target: ocularv1beta1.Target{Identifier: "test2"},
+			},
+		})
+		Expect(pipelines).To(HaveLen(2))
+		Expect(pipelines[0].Spec.ProfileRef.Name).To(Equal("build-context"))
+		Expect(pipelines[0].Spec.ProfileRef.Parameters).To(ConsistOf(
+			ocularv1beta1.ParameterSetting{Name: "STATIC", Value: "static"},
+		))
+		Expect(pipelines[0].Spec.DownloaderRef.Name).To(Equal("git"))
+		Expect(pipelines[0].Spec.DownloaderRef.Kind).To(Equal("ClusterDownloader"))
+		Expect(pipelines[1].Spec.ProfileRef.Name).To(Equal("test-profile"))
+		Expect(pipelines[1].Spec.DownloaderRef.Name).To(Equal("test-downloader"))
+	})
+
+	It("should only label pipelines with values that are valid labels", func() {
+		actionID := "a1b2c3d4/" + strings.Repeat("e", 64)
+		pipelines := RenderPipelines(policy, actionID, []PipelineValues{{EachIndex: new(2)}})
+		Expect(pipelines).To(HaveLen(1))
+		Expect(pipelines[0].Labels).NotTo(HaveKey(v1beta1.PipelineActionIDLabel))
+		Expect(pipelines[0].Annotations).To(HaveKeyWithValue(v1beta1.PipelineActionIDLabel, actionID))
+		Expect(pipelines[0].Annotations).To(HaveKeyWithValue(v1beta1.PipelineEachIndexAnnotation, "2"))
+	})
+
+	It("should set an owner reference to the policy unless orphaned", func() {
+		Expect(RenderPipelines(policy, "action-id", []PipelineValues{{}})[0].OwnerReferences).To(BeEmpty())
+
+		owned := policy.DeepCopy()
+		owned.Spec.DeletionPropagation = metav1.DeletePropagationForeground
+		pipelines := RenderPipelines(owned, "action-id", []PipelineValues{{}})
+		Expect(pipelines[0].OwnerReferences).To(ConsistOf(And(
+			HaveField("Kind", "ChalkReportPolicy"),
+			HaveField("Name", "test-policy"),
+			HaveField("UID", policy.UID),
+			HaveField("BlockOwnerDeletion", HaveValue(BeTrue())),
+		)))
+	})
+
+	It("should not modify the policy template", func() {
+		_ = RenderPipelines(policy, "action-id", []PipelineValues{
+			{ProfileParams: []ocularv1beta1.ParameterSetting{{Name: "DYNAMIC", Value: "1"}}},
+		})
+		Expect(policy.Spec.PipelineTemplate.Spec.ProfileRef.Parameters).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,120 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package policy
+
+import (
+	"time"
+
+	"github.com/google/cel-go/cel"
+)
+
+const (
+	SourceMethodHTTP = "http"
+	SourceMethodSQS  = "sqs"
+)
+
+// Source describes where a chalk report was received from.
+// It is available to policy expressions as the variable 'source'.
+type Source struct {
+	// Method is the intake method the report was received
+	// from, either [SourceMethodHTTP] or [SourceMethodSQS]
+	Method string     `json:"method,omitempty"`
+	HTTP   HTTPSource `json:"http,omitzero"`
+	SQS    SQSSource  `json:"sqs,omitzero"`
+	S3     S3Source   `json:"s3,omitzero"`
+}
+
+// HTTPSource is the authenticated user that uploaded the report
+type HTTPSource struct {
+	User   string   `json:"user,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// SQSSource is the SQS message the report was received in
+type SQSSource struct {
+	QueueURL          string            `json:"queueURL,omitempty"`
+	MessageID         string            `json:"messageID,omitempty"`
+	MessageAttributes map[string]string `json:"messageAttributes,omitempty"`
+}
+
+// S3Source is the S3 object the report was read from,
+// when received from an S3 event notification
+type S3Source struct {
+	Bucket    string    `json:"bucket,omitempty"`
+	Key       string    `json:"key,omitempty"`
+	EventTime time.Time `json:"eventTime,omitzero"`
+}
+
+const (
+	sourceTypeName     = "chalk.Source"
+	httpSourceTypeName = "chalk.HTTPSource"
+	sqsSourceTypeName  = "chalk.SQSSource"
+	s3SourceTypeName   = "chalk.S3Source"
+)
+
+var sourceType = cel.ObjectType(sourceTypeName)
+
+var sourceTypes = map[string]map[string]*cel.Type{
+	sourceTypeName: {
+		"method": cel.StringType,
+		"http":   cel.ObjectType(httpSourceTypeName),
+		"sqs":    cel.ObjectType(sqsSourceTypeName),
+		"s3":     cel.ObjectType(s3SourceTypeName),
+	},
+	httpSourceTypeName: {
+		"user":   cel.StringType,
+		"groups": stringListType,
+	},
+	sqsSourceTypeName: {
+		"queueURL":          cel.StringType,
+		"messageID":         cel.StringType,
+		"messageAttributes": stringMapType,
+	},
+	s3SourceTypeName: {
+		"bucket":    cel.StringType,
+		"key":       cel.StringType,
+		"eventTime": cel.TimestampType,
+	},
+}
+
+// value returns the CEL runtime value of the source. All fields are
+// always set so that policies do not need to check for their presence,
+// except for 's3.eventTime' which is only set if known.
+func (s Source) value() map[string]any {
+	s3 := map[string]any{
+		"bucket": s.S3.Bucket,
+		"key":    s.S3.Key,
+	}
+	if !s.S3.EventTime.IsZero() {
+		s3["eventTime"] = s.S3.EventTime
+	}
+
+	groups := s.HTTP.Groups
+	if groups == nil {
+		groups = []string{}
+	}
+	attributes := s.SQS.MessageAttributes
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
+
+	return map[string]any{
+		"method": s.Method,
+		"http": map[string]any{
+			"user":   s.HTTP.User,
+			"groups": groups,
+		},
+		"sqs": map[string]any{
+			"queueURL":          s.SQS.QueueURL,
+			"messageID":         s.SQS.MessageID,
+			"messageAttributes": attributes,
+		},
+		"s3": s3,
+	}
+}
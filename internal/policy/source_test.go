@@ -0,0 +1,95 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package policy
+
+import (
+	"time"
+
+	"github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("source variable", func() {
+	var compiler *Compiler
+
+	BeforeEach(func() {
+		var err error
+		compiler, err = NewCompiler(5, CompilerOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	report := chalk.Report{"_ACTION_ID": "action-id"}
+
+	httpSource := Source{
+		Method: SourceMethodHTTP,
+		HTTP: HTTPSource{
+			User:   "system:serviceaccount:ci:uploader",
+			Groups: []string{"system:serviceaccounts", "team-a"},
+		},
+	}
+	sqsSource := Source{
+		Method: SourceMethodSQS,
+		SQS: SQSSource{
+			QueueURL:          "https://sqs.us-east-1.amazonaws.com/123456789012/reports",
+			MessageID:         "msg-1",
+			MessageAttributes: map[string]string{"team": "team-b"},
+		},
+		S3: S3Source{
+			Bucket:    "team-b-reports",
+			Key:       "reports/report.json",
+			EventTime: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}
+
+	compile := func(matchCondition string) (*CompiledPolicy, error) {
+		return compiler.Compile(&v1beta1.ChalkReportPolicy{
+			Spec: v1beta1.ChalkReportPolicySpec{
+				MatchCondition: matchCondition,
+				Extraction: v1beta1.ChalkReportPolicyExtraction{
+					Target: "{'identifier': source.method}",
+				},
+			},
+		})
+	}
+
+	DescribeTable("should expose the source to expressions",
+		func(expr string, source Source, expected bool) {
+			compiled, err := compile(expr)
+			Expect(err).NotTo(HaveOccurred())
+			matched, err := compiled.Matches(report, source)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matched).To(Equal(expected))
+		},
+		Entry("http method", "source.method == 'http'", httpSource, true),
+		Entry("http groups", "'team-a' in source.http.groups", httpSource, true),
+		Entry("http user", "source.http.user.startsWith('system:serviceaccount:ci:')", httpSource, true),
+		Entry("empty s3 source", "source.s3.bucket == '' && !has(source.s3.eventTime)", httpSource, true),
+		Entry("sqs message attributes", "source.sqs.messageAttributes['team'] == 'team-b'", sqsSource, true),
+		Entry("missing sqs message attribute", "'team' in source.sqs.messageAttributes", httpSource, false),
+		Entry("s3 bucket and key", "source.s3.bucket == 'team-b-reports' && source.s3.key.startsWith('reports/')",
+			sqsSource, true),
+		Entry("s3 event time", "source.s3.eventTime < timestamp('2026-02-01T00:00:00Z')", sqsSource, true),
+	)
+
+	It("should pass the source to the extraction expressions", func() {
+		compiled, err := compile("true")
+		Expect(err).NotTo(HaveOccurred())
+		values, err := compiled.Extract(report, sqsSource)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(HaveLen(1))
+		Expect(values[0].Target.Identifier).To(Equal(SourceMethodSQS))
+	})
+
+	It("should reject unknown source fields", func() {
+		_, err := compile("source.s3.buckt == 'reports'")
+		Expect(err).To(MatchError(ContainSubstring("undefined field")))
+	})
+})
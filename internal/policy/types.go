@@ -0,0 +1,340 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package policy
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+)
+
+// The chalk report and chalk marks are declared as object types to CEL so
+// that selecting a key that is not a well-known chalk key fails at compile time.
+// At runtime the values are still plain maps, since the field types below
+// do not provide accessors, CEL will fall back to map indexing.
+//
+// Custom keys (prefixed with 'X_' or '_X_') are allowed on both types
+// and will have the type 'dyn'. Any other key can be accessed with
+// index syntax, i.e. report['MY_KEY'], which will also have the type 'dyn'.
+const (
+	reportTypeName = "chalk.Report"
+	markTypeName   = "chalk.Mark"
+)
+
+var (
+	reportType = cel.ObjectType(reportTypeName)
+	markType   = cel.ObjectType(markTypeName)
+
+	stringListType = cel.ListType(cel.StringType)
+	objectType     = cel.MapType(cel.StringType, cel.DynType)
+)
+
+// reportKeys are the well-known keys of a chalk report (host level keys).
+// Numeric keys are left as 'dyn' since JSON numbers are decoded as doubles.
+var reportKeys = map[string]*cel.Type{
+	chalk.KeyActionID: cel.StringType,
+	chalk.KeyChalks:   cel.ListType(markType),
+
+	"_ARGV":                             stringListType,
+	"_DATE":                             cel.StringType,
+	"_DATETIME":                         cel.StringType,
+	"_ENV":                              objectType,
+	"_OPERATION":                        cel.StringType,
+	"_TENANT_ID":                        cel.StringType,
+	"_TIME":                             cel.StringType,
+	"_TIMESTAMP":                        cel.DynType,
+	"_TZ_OFFSET":                        cel.StringType,
+	"_UNMARKED":                         stringListType,
+	"_OP_ARGV":                          stringListType,
+	"_OP_CHALKER_COMMIT_ID":             cel.StringType,
+	"_OP_CHALKER_VERSION":               cel.StringType,
+	"_OP_CHALK_COUNT":                   cel.DynType,
+	"_OP_CLOUD_METADATA":                cel.DynType,
+	"_OP_CLOUD_PROVIDER":                cel.StringType,
+	"_OP_CLOUD_PROVIDER_ACCOUNT_INFO":   cel.DynType,
+	"_OP_CLOUD_PROVIDER_INSTANCE_TYPE":  cel.StringType,
+	"_OP_CLOUD_PROVIDER_IP":             cel.StringType,
+	"_OP_CLOUD_PROVIDER_REGION":         cel.StringType,
+	"_OP_CLOUD_PROVIDER_SERVICE_TYPE":   cel.StringType,
+	"_OP_CLOUD_PROVIDER_TAGS":           cel.DynType,
+	"_OP_CMD_FLAGS":                     stringListType,
+	"_OP_ERRORS":                        stringListType,
+	"_OP_EXE_NAME":                      cel.StringType,
+	"_OP_EXE_PATH":                      cel.StringType,
+	"_OP_FAILED_KEYS":                   cel.DynType,
+	"_OP_HOSTINFO":                      cel.StringType,
+	"_OP_HOSTNAME":                      cel.StringType,
+	"_OP_IPV4_ADDRS":                    stringListType,
+	"_OP_IPV6_ADDRS":                    stringListType,
+	"_OP_NODENAME":                      cel.StringType,
+	"_OP_PLATFORM":                      cel.StringType,
+	"_OP_PUBLIC_IPV4_ADDR":              cel.StringType,
+	"_OP_SEARCH_PATH":                   stringListType,
+	"_OP_TENANT_ID":                     cel.StringType,
+	"_OP_UNMARKED_COUNT":                cel.DynType,
+	"_CHALK_RUN_TIME":                   cel.DynType,
+	"_CHALK_EXTERNAL_ACTION_AUDIT":      cel.DynType,
+	"_OP_HOST_REPORT_KEYS":              cel.DynType,
+	"_OP_ARTIFACT_REPORT_KEYS":          cel.DynType,
+	"_OP_CONTAINER_NAME":                cel.StringType,
+	"_OP_CLOUD_PROVIDER_ACCOUNT_ID":     cel.StringType,
+	"_OP_CLOUD_PROVIDER_INSTANCE_ID":    cel.StringType,
+	"_OP_CLOUD_PROVIDER_INSTANCE_NAME":  cel.StringType,
+	"_OP_CLOUD_PROVIDER_CLUSTER_NAME":   cel.StringType,
+	"_OP_CLOUD_PROVIDER_NAMESPACE_NAME": cel.StringType,
+}
+
+// markKeys are the well-known keys of a chalk mark, both the chalk-time
+// keys and the run-time keys that are reported per artifact.
+// Numeric keys are left as 'dyn' since JSON numbers are decoded as doubles.
+var markKeys = map[string]*cel.Type{
+	// chalk-time keys
+	"MAGIC":                            cel.StringType,
+	"CHALK_ID":                         cel.StringType,
+	"CHALK_VERSION":                    cel.StringType,
+	"CHALK_RAND":                       cel.StringType,
+	"CHALK_PTR":                        cel.StringType,
+	"METADATA_HASH":                    cel.StringType,
+	"METADATA_ID":                      cel.StringType,
+	"EARLIEST_VERSION":                 cel.StringType,
+	"TIMESTAMP_WHEN_CHALKED":           cel.DynType,
+	"DATETIME_WHEN_CHALKED":            cel.StringType,
+	"HOST_SYSNAME_WHEN_CHALKED":        cel.StringType,
+	"HOST_NODENAME_WHEN_CHALKED":       cel.StringType,
+	"HOST_RELEASE_WHEN_CHALKED":        cel.StringType,
+	"HOST_VERSION_WHEN_CHALKED":        cel.StringType,
+	"HOST_MACHINE_WHEN_CHALKED":        cel.StringType,
+	"PUBLIC_IPV4_ADDR_WHEN_CHALKED":    cel.StringType,
+	"TENANT_ID_WHEN_CHALKED":           cel.StringType,
+	"INJECTOR_CHALK_ID":                cel.StringType,
+	"INJECTOR_VERSION":                 cel.StringType,
+	"INJECTOR_PLATFORM":                cel.StringType,
+	"INJECTOR_COMMIT_ID":               cel.StringType,
+	"INJECTOR_ARGV":                    stringListType,
+	"INJECTOR_ENV":                     objectType,
+	"PATH_WHEN_CHALKED":                cel.StringType,
+	"PATH_WITHIN_ZIP":                  cel.StringType,
+	"ARTIFACT_TYPE":                    cel.StringType,
+	"HASH":                             cel.StringType,
+	"PRE_CHALK_HASH":                   cel.StringType,
+	"ORIGIN_URI":                       cel.StringType,
+	"BRANCH":                           cel.StringType,
+	"TAG":                              cel.StringType,
+	"TAG_SIGNED":                       cel.DynType,
+	"COMMIT_ID":                        cel.StringType,
+	"COMMIT_SIGNED":                    cel.DynType,
+	"AUTHOR":                           cel.StringType,
+	"DATE_AUTHORED":                    cel.StringType,
+	"TIMESTAMP_AUTHORED":               cel.DynType,
+	"COMMITTER":                        cel.StringType,
+	"DATE_COMMITTED":                   cel.StringType,
+	"TIMESTAMP_COMMITTED":              cel.DynType,
+	"COMMIT_MESSAGE":                   cel.StringType,
+	"TAGGER":                           cel.StringType,
+	"DATE_TAGGED":                      cel.StringType,
+	"TIMESTAMP_TAGGED":                 cel.DynType,
+	"TAG_MESSAGE":                      cel.StringType,
+	"VCS_DIR_WHEN_CHALKED":             cel.StringType,
+	"VCS_MISSING_FILES":                stringListType,
+	"CODE_OWNERS":                      cel.StringType,
+	"BUILD_ID":                         cel.StringType,
+	"BUILD_COMMIT_ID":                  cel.StringType,
+	"BUILD_URI":                        cel.StringType,
+	"BUILD_API_URI":                    cel.StringType,
+	"BUILD_TRIGGER":                    cel.StringType,
+	"BUILD_CONTACT":                    stringListType,
+	"BUILD_ORIGIN_ID":                  cel.StringType,
+	"BUILD_ORIGIN_KEY":                 cel.StringType,
+	"BUILD_ORIGIN_OWNER_ID":            cel.StringType,
+	"BUILD_ORIGIN_OWNER_KEY":           cel.StringType,
+	"BUILD_ORIGIN_URI":                 cel.StringType,
+	"DOCKER_FILE":                      cel.StringType,
+	"DOCKERFILE_PATH":                  cel.StringType,
+	"DOCKERFILE_PATH_WITHIN_VCTL":      cel.StringType,
+	"DOCKER_PLATFORM":                  cel.StringType,
+	"DOCKER_PLATFORMS":                 stringListType,
+	"DOCKER_LABELS":                    objectType,
+	"DOCKER_ANNOTATIONS":               objectType,
+	"DOCKER_TAGS":                      stringListType,
+	"DOCKER_CONTEXT":                   cel.StringType,
+	"DOCKER_ADDITIONAL_CONTEXTS":       objectType,
+	"DOCKER_CHALK_ADDED_LABELS":        objectType,
+	"DOCKER_CHALK_ADDED_TO_DOCKERFILE": cel.DynType,
+	"DOCKER_BASE_IMAGE":                cel.StringType,
+	"DOCKER_BASE_IMAGE_REPO":           cel.StringType,
+	"DOCKER_BASE_IMAGE_REGISTRY":       cel.StringType,
+	"DOCKER_BASE_IMAGE_NAME":           cel.StringType,
+	"DOCKER_BASE_IMAGE_TAG":            cel.StringType,
+	"DOCKER_BASE_IMAGE_DIGEST":         cel.StringType,
+	"DOCKER_BASE_IMAGE_ID":             cel.StringType,
+	"DOCKER_BASE_IMAGE_METADATA_ID":    cel.StringType,
+	"DOCKER_BASE_IMAGE_CHALK":          cel.DynType,
+	"DOCKER_BASE_IMAGES":               cel.DynType,
+	"DOCKER_COPY_IMAGES":               cel.DynType,
+	"DOCKER_TARGET":                    cel.StringType,
+	"SIGNING":                          cel.DynType,
+	"SIGNATURE":                        cel.DynType,
+	"SIGN_PARAMS":                      cel.DynType,
+	"ERR_INFO":                         stringListType,
+	"FAILED_KEYS":                      cel.DynType,
+	"SBOM":                             cel.DynType,
+	"SAST":                             cel.DynType,
+	"SECRET_SCANNER":                   cel.DynType,
+	"CLAMAV":                           cel.DynType,
+	"EMBEDDED_CHALK":                   cel.DynType,
+	"EMBEDDED_TMPDIR":                  cel.StringType,
+	"CLOUD_METADATA_WHEN_CHALKED":      cel.DynType,
+
+	// run-time artifact keys
+	"_OP_ARTIFACT_TYPE":          cel.StringType,
+	"_OP_ARTIFACT_PATH":          cel.StringType,
+	"_OP_ARTIFACT_ACCESSED":      cel.DynType,
+	"_OP_ARTIFACT_ENV_VAR_NAMES": stringListType,
+	"_OP_ALL_IMAGE_METADATA":     cel.DynType,
+	"_CURRENT_HASH":              cel.StringType,
+	"_COMMIT_ID":                 cel.StringType,
+	"_IMAGE_ID":                  cel.StringType,
+	"_IMAGE_DIGEST":              cel.StringType,
+	"_IMAGE_LIST_DIGEST":         cel.StringType,
+	"_IMAGE_SIZE":                cel.DynType,
+	"_IMAGE_VSIZE":               cel.DynType,
+	"_IMAGE_CREATION_DATETIME":   cel.StringType,
+	"_IMAGE_ENTRYPOINT":          stringListType,
+	"_IMAGE_CMD":                 stringListType,
+	"_IMAGE_HOSTNAME":            cel.StringType,
+	"_IMAGE_DOMAINNAME":          cel.StringType,
+	"_IMAGE_USER":                cel.StringType,
+	"_IMAGE_EXPOSED_PORTS":       cel.DynType,
+	"_IMAGE_ENV":                 cel.DynType,
+	"_IMAGE_WORKINGDIR":          cel.StringType,
+	"_IMAGE_LABELS":              objectType,
+	"_IMAGE_ANNOTATIONS":         objectType,
+	"_IMAGE_DOCKER_VERSION":      cel.StringType,
+	"_IMAGE_ARCHITECTURE":        cel.StringType,
+	"_IMAGE_VARIANT":             cel.StringType,
+	"_IMAGE_OS":                  cel.StringType,
+	"_IMAGE_OS_VERSION":          cel.StringType,
+	"_IMAGE_STOP_SIGNAL":         cel.StringType,
+	"_IMAGE_SHELL":               stringListType,
+	"_IMAGE_VOLUMES":             cel.DynType,
+	"_IMAGE_ONBUILD":             cel.DynType,
+	"_IMAGE_PROVENANCE":          cel.DynType,
+	"_IMAGE_SBOM":                cel.DynType,
+	"_REPO_TAGS":                 stringListType,
+	"_REPO_DIGESTS":              cel.DynType,
+	"_REPO_LIST_DIGESTS":         cel.DynType,
+	"_INSTANCE_CONTAINER_ID":     cel.StringType,
+	"_INSTANCE_NAME":             cel.StringType,
+	"_INSTANCE_STATUS":           cel.StringType,
+	"_PROCESS_PID":               cel.DynType,
+	"_PROCESS_PARENT_PID":        cel.DynType,
+	"_PROCESS_CMDLINE":           stringListType,
+	"_PROCESS_EXE_PATH":          cel.StringType,
+	"_VIRTUAL":                   cel.DynType,
+}
+
+// isCustomKey returns true if the key is a user defined
+// chalk key, which must start with either 'X_' or '_X_'
+func isCustomKey(key string) bool {
+	return strings.HasPrefix(key, "X_") || strings.HasPrefix(key, "_X_")
+}
+
+// chalkTypeProvider is a [types.Provider] that declares the chalk
+// report and chalk mark object types on top of the standard registry
+type chalkTypeProvider struct {
+	*types.Registry
+	objects map[string]objectDecl
+}
+
+// objectDecl declares the fields of an object type, if custom is true
+// custom chalk keys are also allowed to be selected
+type objectDecl struct {
+	fields map[string]*cel.Type
+	custom bool
+}
+
+func newChalkTypeProvider() (*chalkTypeProvider, error) {
+	registry, err := types.NewRegistry()
+	if err != nil {
+		return nil, err
+	}
+	objects := map[string]objectDecl{
+		reportTypeName:         {fields: reportKeys, custom: true},
+		markTypeName:           {fields: markKeys, custom: true},
+		imageReferenceTypeName: {fields: imageReferenceFields},
+	}
+	for name, fields := range sourceTypes {
+		objects[name] = objectDecl{fields: fields}
+	}
+	return &chalkTypeProvider{
+		Registry: registry,
+		objects:  objects,
+	}, nil
+}
+
+func (p *chalkTypeProvider) FindStructType(structType string) (*types.Type, bool) {
+	if _, found := p.objects[structType]; found {
+		return types.NewTypeTypeWithParam(types.NewObjectType(structType)), true
+	}
+	return p.Registry.FindStructType(structType)
+}
+
+func (p *chalkTypeProvider) FindStructFieldNames(structType string) ([]string, bool) {
+	if object, found := p.objects[structType]; found {
+		return slices.Sorted(maps.Keys(object.fields)), true
+	}
+	return p.Registry.FindStructFieldNames(structType)
+}
+
+func (p *chalkTypeProvider) FindStructFieldType(structType, fieldName string) (*types.FieldType, bool) {
+	object, found := p.objects[structType]
+	if !found {
+		return p.Registry.FindStructFieldType(structType, fieldName)
+	}
+	if t, known := object.fields[fieldName]; known {
+		return &types.FieldType{Type: t}, true
+	}
+	if object.custom && isCustomKey(fieldName) {
+		return &types.FieldType{Type: cel.DynType}, true
+	}
+	return nil, false
+}
+
+// chalkObjectLib is a [cel.Library] that declares the chalk object
+// types and allows the index and 'in' operators to be used with any key.
+// The overloads are only declared, at runtime the values are maps and
+// are handled by the standard implementations of the operators.
+type chalkObjectLib struct {
+	provider *chalkTypeProvider
+}
+
+func (l *chalkObjectLib) CompileOptions() []cel.EnvOption {
+	var indexOverloads, inOverloads []cel.FunctionOpt
+	for _, t := range []*cel.Type{reportType, markType} {
+		name := strings.ToLower(strings.ReplaceAll(t.String(), ".", "_"))
+		indexOverloads = append(indexOverloads,
+			cel.Overload("index_"+name+"_string", []*cel.Type{t, cel.StringType}, cel.DynType))
+		inOverloads = append(inOverloads,
+			cel.Overload("in_string_"+name, []*cel.Type{cel.StringType, t}, cel.BoolType))
+	}
+	return []cel.EnvOption{
+		cel.CustomTypeProvider(l.provider),
+		cel.Function(operators.Index, indexOverloads...),
+		cel.Function(operators.In, inOverloads...),
+	}
+}
+
+func (l *chalkObjectLib) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
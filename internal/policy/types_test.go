@@ -0,0 +1,120 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package policy
+
+import (
+	"github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("chalk report types", func() {
+	var compiler *Compiler
+
+	BeforeEach(func() {
+		var err error
+		compiler, err = NewCompiler(5, CompilerOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	matchPolicy := func(matchCondition string) *v1beta1.ChalkReportPolicy {
+		return &v1beta1.ChalkReportPolicy{
+			Spec: v1beta1.ChalkReportPolicySpec{
+				MatchCondition: matchCondition,
+				Extraction: v1beta1.ChalkReportPolicyExtraction{
+					Target: "{'identifier': 'test'}",
+				},
+			},
+		}
+	}
+
+	report := chalk.Report{
+		"_ACTION_ID": "action-id",
+		"X_TEAM":     "platform",
+		"OTHER_KEY":  "other",
+		"_CHALKS": []any{
+			map[string]any{
+				"_OP_ARTIFACT_TYPE":  "Docker Image",
+				"_X_OCULAR_STRATEGY": "image",
+			},
+		},
+	}
+
+	DescribeTable("should reject misspelled well-known keys",
+		func(expr string) {
+			_, err := compiler.Compile(matchPolicy(expr))
+			Expect(err).To(MatchError(ContainSubstring("undefined field")))
+		},
+		Entry("report key", "report._ACTON_ID == 'test'"),
+		Entry("chalk mark key", "report._CHALKS.exists(c, c._OP_ARTIFACT_TYP == 'Docker Image')"),
+	)
+
+	Context("with a policy written before reports were typed", func() {
+		previous := matchPolicy("report._CHALKS.exists(c, c._OCULAR_IMAGE_REPO == 'example.com/app')")
+		previousReport := chalk.Report{
+			"_ACTION_ID": "action-id",
+			"_CHALKS":    []any{map[string]any{"_OCULAR_IMAGE_REPO": "example.com/app"}},
+		}
+
+		It("should explain how to select keys that are not well-known", func() {
+			_, err := compiler.Compile(previous)
+			Expect(err).To(MatchError(And(
+				ContainSubstring("undefined field '_OCULAR_IMAGE_REPO'"),
+				ContainSubstring("must be prefixed with 'X_' or '_X_', or selected with index syntax"),
+			)))
+			Expect(compiler.Validate(previous)).To(ConsistOf(
+				HaveField("Detail", ContainSubstring("must be prefixed with 'X_' or '_X_'"))))
+		})
+
+		It("should compile and match when unknown keys are allowed", func() {
+			var err error
+			compiler, err = NewCompiler(5, CompilerOptions{AllowUnknownKeys: true})
+			Expect(err).NotTo(HaveOccurred())
+
+			compiled, err := compiler.Compile(previous)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(compiled.Matches(previousReport, Source{})).To(BeTrue())
+
+			By("declaring the report as a map, as before reports were typed")
+			for _, expr := range []string{
+				"size(report) == 2",
+				"report.exists(k, k == '_CHALKS')",
+				"report._MY_KEY == 'value' || !has(report._MY_KEY)",
+				"chalks(report).size() == 1 && size(chalks(report)[0]) == 1",
+			} {
+				compiled, err := compiler.Compile(matchPolicy(expr))
+				Expect(err).NotTo(HaveOccurred(), expr)
+				Expect(compiled.Matches(previousReport, Source{})).To(BeTrue(), expr)
+			}
+		})
+	})
+
+	It("should reject well-known keys used as the wrong type", func() {
+		_, err := compiler.Compile(matchPolicy("report._ACTION_ID > 1"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("should allow custom keys and index syntax",
+		func(expr string, expected bool) {
+			compiled, err := compiler.Compile(matchPolicy(expr))
+			Expect(err).NotTo(HaveOccurred())
+			matched, err := compiled.Matches(report, Source{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matched).To(Equal(expected))
+		},
+		Entry("well-known report key", "report._ACTION_ID == 'action-id'", true),
+		Entry("well-known chalk mark key", "report._CHALKS.exists(c, c._OP_ARTIFACT_TYPE == 'Docker Image')", true),
+		Entry("custom report key", "report.X_TEAM == 'platform'", true),
+		Entry("custom chalk mark key", "report._CHALKS.exists(c, c._X_OCULAR_STRATEGY == 'image')", true),
+		Entry("index syntax", "report['OTHER_KEY'] == 'other'", true),
+		Entry("in operator", "'OTHER_KEY' in report && !('MISSING' in report)", true),
+		Entry("has macro", "has(report._ENV)", false),
+	)
+})
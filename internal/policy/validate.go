@@ -0,0 +1,154 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var (
+	stringMapType = cel.MapType(cel.StringType, cel.StringType)
+
+	matchConditionTypes = []*cel.Type{cel.BoolType}
+	forEachTypes        = []*cel.Type{cel.ListType(cel.DynType)}
+	targetTypes         = []*cel.Type{stringMapType, cel.ListType(stringMapType)}
+	parametersTypes     = []*cel.Type{stringMapType}
+	metadataTypes       = []*cel.Type{stringMapType}
+	referenceTypes      = []*cel.Type{stringMapType}
+	dedupKeyTypes       = []*cel.Type{cel.StringType}
+)
+
+// Validate compiles every CEL expression in the policy and checks that the
+// output type of each expression could be the type expected for that field.
+// Errors are returned with the path of the field that failed.
+func (c *Compiler) Validate(policy *chalkularv1beta1.ChalkReportPolicy) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	extractionPath := specPath.Child("extraction")
+	s := policy.Spec
+
+	if err := c.check(specPath.Child("matchCondition"), s.MatchCondition, matchConditionTypes); err != nil {
+		allErrs = append(allErrs, err)
+	}
+
+	if s.Extraction.ForEach != nil {
+		if err := c.check(extractionPath.Child("forEach"), *s.Extraction.ForEach, forEachTypes); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	if err := c.check(extractionPath.Child("target"), s.Extraction.Target, targetTypes); err != nil {
+		allErrs = append(allErrs, err)
+	}
+
+	if s.Extraction.DownloaderParams != nil {
+		if err := c.check(extractionPath.Child("downloaderParams"), *s.Extraction.DownloaderParams, parametersTypes); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	if s.Extraction.ProfileParams != nil {
+		if err := c.check(extractionPath.Child("profileParams"), *s.Extraction.ProfileParams, parametersTypes); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	if s.Extraction.Labels != nil {
+		if err := c.check(extractionPath.Child("labels"), *s.Extraction.Labels, metadataTypes); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	if s.Extraction.Annotations != nil {
+		if err := c.check(extractionPath.Child("annotations"), *s.Extraction.Annotations, metadataTypes); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	if s.Extraction.ProfileRef != nil {
+		if err := c.check(extractionPath.Child("profileRef"), *s.Extraction.ProfileRef, referenceTypes); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	if s.Extraction.DownloaderRef != nil {
+		if err := c.check(extractionPath.Child("downloaderRef"), *s.Extraction.DownloaderRef, referenceTypes); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	if s.Extraction.DedupKey != nil {
+		if err := c.check(extractionPath.Child("dedupKey"), *s.Extraction.DedupKey, dedupKeyTypes); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	return allErrs
+}
+
+// check compiles the expression and returns a field error if it fails to compile,
+// is estimated to exceed the cost limit or its output type could never be one of expected.
+func (c *Compiler) check(path *field.Path, expr string, expected []*cel.Type) *field.Error {
+	ast, issues := c.env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return field.Invalid(path, expr, fmt.Sprintf("failed to compile CEL expression: %s", compileErr(issues)))
+	}
+
+	if err := c.checkCost(ast); err != nil {
+		return field.Invalid(path, expr, err.Error())
+	}
+
+	output := ast.OutputType()
+	for _, e := range expected {
+		if couldBeType(e, output) {
+			return nil
+		}
+	}
+
+	expectedNames := make([]string, 0, len(expected))
+	for _, e := range expected {
+		expectedNames = append(expectedNames, e.String())
+	}
+	return field.Invalid(path, expr, fmt.Sprintf("expression returns type '%s', expected %s",
+		output, strings.Join(expectedNames, " or ")))
+}
+
+// couldBeType returns true if a value of type actual could at runtime
+// be the expected type. Unlike [cel.Type.IsAssignableType], a 'dyn' type
+// (including as a map value or list element) is considered a possible match.
+func couldBeType(expected, actual *cel.Type) bool {
+	if expected.Kind() == types.DynKind || actual.Kind() == types.DynKind {
+		return true
+	}
+	if expected.Kind() != actual.Kind() {
+		return false
+	}
+
+	switch expected.Kind() {
+	case types.ListKind, types.MapKind:
+		expectedParams, actualParams := expected.Parameters(), actual.Parameters()
+		if len(expectedParams) != len(actualParams) {
+			return false
+		}
+		for i := range expectedParams {
+			if !couldBeType(expectedParams[i], actualParams[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return expected.IsAssignableType(actual)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+package policy
+
+import (
+	"github.com/crashappsec/chalkular/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var _ = Describe("Compiler.Validate", func() {
+	var compiler *Compiler
+
+	BeforeEach(func() {
+		var err error
+		compiler, err = NewCompiler(5, CompilerOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	fieldPaths := func(errs field.ErrorList) []string {
+		var paths []string
+		for _, e := range errs {
+			paths = append(paths, e.Field)
+		}
+		return paths
+	}
+
+	It("should accept expressions that could return the expected types", func() {
+		errs := compiler.Validate(&v1beta1.ChalkReportPolicy{
+			Spec: v1beta1.ChalkReportPolicySpec{
+				MatchCondition: "report._CHALKS.exists(c, c._OP_ARTIFACT_TYPE == 'Docker Image')",
+				Extraction: v1beta1.ChalkReportPolicyExtraction{
+					ForEach:          new("report._CHALKS"),
+					Target:           "[{'identifier': each._REPO}]",
+					DownloaderParams: new("report._X_DL_PARAMS"),
+					ProfileParams:    new("{'ENABLED': report._X_ENABLED}"),
+					Labels:           new("{'repo': each._REPO}"),
+					Annotations:      new("report._X_ANNOTATIONS"),
+					ProfileRef:       new("{'name': report._X_PROFILE}"),
+					DownloaderRef:    new("{'name': 'downloader', 'kind': 'ClusterDownloader'}"),
+					DedupKey:         new("each.HASH"),
+				},
+			},
+		})
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("should reject expressions that fail to compile", func() {
+		errs := compiler.Validate(&v1beta1.ChalkReportPolicy{
+			Spec: v1beta1.ChalkReportPolicySpec{
+				MatchCondition: "invalid CEL expression",
+				Extraction: v1beta1.ChalkReportPolicyExtraction{
+					Target:           "{'identifier': unknown_variable}",
+					DownloaderParams: new("{'KEY': "),
+				},
+			},
+		})
+		Expect(fieldPaths(errs)).To(ConsistOf(
+			"spec.matchCondition",
+			"spec.extraction.target",
+			"spec.extraction.downloaderParams",
+		))
+	})
+
+	It("should reject expressions whose output type can never match", func() {
+		errs := compiler.Validate(&v1beta1.ChalkReportPolicy{
+			Spec: v1beta1.ChalkReportPolicySpec{
+				MatchCondition: "'true'",
+				Extraction: v1beta1.ChalkReportPolicyExtraction{
+					ForEach:       new("1"),
+					Target:        "{'identifier': 1}",
+					ProfileParams: new("['list']"),
+					Labels:        new("{'repo': 1}"),
+					Annotations:   new("'annotation'"),
+					ProfileRef:    new("'analyze'"),
+					DedupKey:      new("{'hash': 'value'}"),
+				},
+			},
+		})
+		Expect(fieldPaths(errs)).To(ConsistOf(
+			"spec.matchCondition",
+			"spec.extraction.forEach",
+			"spec.extraction.target",
+			"spec.extraction.profileParams",
+			"spec.extraction.labels",
+			"spec.extraction.annotations",
+			"spec.extraction.profileRef",
+			"spec.extraction.dedupKey",
+		))
+	})
+})
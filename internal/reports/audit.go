@@ -0,0 +1,95 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"context"
+	"strings"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// maxAuditedTargets is the maximum amount of targets recorded
+// for a report matched by a policy in audit mode
+const maxAuditedTargets = 20
+
+// auditPipelines records the pipelines that a policy in audit mode would have created for the
+// report as an event and in the audit summary of the policy status, returning the result of the
+// policy. The pipelines are not deduplicated or checked against the active pipeline limits,
+// since they are never created.
+func (s *Scheduler) auditPipelines(ctx context.Context, g policyGeneratedPipelines) chalkularv1beta1.ChalkReportPolicyResult {
+	l := logf.FromContext(ctx).WithValues("policy", g.policy.Name, "namespace", g.policy.Namespace)
+
+	targets := auditedTargets(g.pipelines)
+	l.Info("policy in audit mode matched report, not creating pipelines",
+		"pipelines", len(g.pipelines), "excluded", g.excluded)
+	schedulerPipelinesAudited.With(prometheus.Labels{"policy": g.policy.Name, "namespace": g.policy.Namespace}).Add(float64(len(g.pipelines)))
+	s.recorder.Eventf(&g.policy, nil,
+		corev1.EventTypeNormal,
+		"PipelinesAudited",
+		"AuditPipelinesFromReport",
+		"report '%s' would create %d pipelines in audit mode: %s", g.actionID, len(g.pipelines), strings.Join(targets, ", "))
+
+	if err := s.recordAudit(ctx, &g.policy, g.actionID, len(g.pipelines), targets); err != nil {
+		l.Error(err, "unable to record audited pipelines in policy status")
+	}
+
+	return chalkularv1beta1.ChalkReportPolicyResult{
+		Name:              g.policy.Name,
+		Namespace:         g.policy.Namespace,
+		ExcludedPipelines: g.excluded,
+		AuditedPipelines:  len(g.pipelines),
+	}
+}
+
+// recordAudit adds the report matched by the policy in audit mode to the audit summary of its status.
+// The policy is read from the API server, since its status is also updated by the controllers.
+func (s *Scheduler) recordAudit(ctx context.Context, reportPolicy *chalkularv1beta1.ChalkReportPolicy, actionID string, pipelines int, targets []string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current := &chalkularv1beta1.ChalkReportPolicy{}
+		if err := s.apiReader.Get(ctx, client.ObjectKeyFromObject(reportPolicy), current); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if current.UID != reportPolicy.UID {
+			return nil
+		}
+
+		summary := &current.Status.Audit
+		summary.MatchedReports++
+		summary.Pipelines += int64(pipelines)
+		summary.LastMatch = &chalkularv1beta1.ChalkReportPolicyAuditMatch{
+			ActionID:  actionID,
+			Time:      metav1.Now(),
+			Pipelines: pipelines,
+			Targets:   targets,
+		}
+		return s.mgrClient.Status().Update(ctx, current)
+	})
+}
+
+// auditedTargets returns the targets of the pipelines as 'identifier@version',
+// or only the identifier if there is no version, up to [maxAuditedTargets]
+func auditedTargets(pipelines []*ocularv1beta1.Pipeline) []string {
+	var targets []string
+	for _, pipeline := range pipelines[:min(len(pipelines), maxAuditedTargets)] {
+		target := pipeline.Spec.Target.Identifier
+		if version := pipeline.Spec.Target.Version; version != "" {
+			target += "@" + version
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
@@ -0,0 +1,390 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// backlogDrainInterval is how often the backlog is drained,
+// to create pending pipelines once active pipelines complete
+const backlogDrainInterval = 10 * time.Second
+
+// ErrBacklogFull is returned (wrapped) when pipelines cannot be
+// deferred, since the backlog has reached its maximum size
+var ErrBacklogFull = fmt.Errorf("%w, pending pipeline backlog is full", ErrPipelineThreshold)
+
+// PendingPipeline is a pipeline generated by a policy that was deferred, since
+// creating it would exceed the active pipeline threshold or limits.
+type PendingPipeline struct {
+	ID       string `json:"id"`
+	Priority int32  `json:"priority,omitempty"`
+	ActionID string `json:"actionID"`
+	// PolicyName and PolicyUID identify the policy that generated the pipeline,
+	// which is in the namespace of the pipeline. The pipeline is dropped if the
+	// policy is deleted before it is created.
+	PolicyName string                  `json:"policyName"`
+	PolicyUID  types.UID               `json:"policyUID"`
+	Pipeline   *ocularv1beta1.Pipeline `json:"pipeline"`
+}
+
+// A BacklogStore persists the pipelines deferred by the [Scheduler], so that
+// they are created once the controller restarts or another replica becomes leader.
+type BacklogStore interface {
+	// Save stores the pending pipeline
+	Save(ctx context.Context, p PendingPipeline) error
+	// Delete removes the pending pipeline with the ID
+	Delete(ctx context.Context, id string) error
+	// Load returns all the stored pending pipelines
+	Load(ctx context.Context) ([]PendingPipeline, error)
+}
+
+// pipelineBacklog holds the pending pipelines in the order
+// they should be created, persisting them in the store
+type pipelineBacklog struct {
+	store  BacklogStore
+	max    int
+	notify chan struct{}
+	// durable is false if the pending pipelines are lost when the controller restarts
+	durable bool
+
+	mu    sync.Mutex
+	items []PendingPipeline
+}
+
+func newPipelineBacklog(store BacklogStore, maxPending int) *pipelineBacklog {
+	return &pipelineBacklog{
+		store:   store,
+		max:     maxPending,
+		notify:  make(chan struct{}, 1),
+		durable: IsDurableBacklogStore(store),
+	}
+}
+
+// comparePending orders pending pipelines by priority (highest first),
+// then by the order they were deferred in
+func comparePending(a, b PendingPipeline) int {
+	return cmp.Or(cmp.Compare(b.Priority, a.Priority), strings.Compare(a.ID, b.ID))
+}
+
+// load replaces the pending pipelines with those in the store
+func (b *pipelineBacklog) load(ctx context.Context) error {
+	items, err := b.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(items, comparePending)
+
+	b.mu.Lock()
+	b.items = items
+	schedulerPendingPipelines.Set(float64(len(b.items)))
+	b.mu.Unlock()
+	return nil
+}
+
+// add stores the pending pipelines, returning [ErrBacklogFull]
+// if the backlog does not have room for all of them
+func (b *pipelineBacklog) add(ctx context.Context, pending []PendingPipeline) error {
+	if len(pending) == 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.items)+len(pending) > b.max {
+		return fmt.Errorf("%w: %d pending pipelines, unable to defer %d more with a maximum of %d",
+			ErrBacklogFull, len(b.items), len(pending), b.max)
+	}
+
+	for i := range pending {
+		pending[i].ID = newItemID()
+		if err := b.store.Save(ctx, pending[i]); err != nil {
+			// remove the pipelines already saved, so that
+			// the report can be processed again without duplicates
+			for _, saved := range pending[:i] {
+				_ = b.store.Delete(ctx, saved.ID)
+			}
+			return fmt.Errorf("unable to store pending pipeline: %w", err)
+		}
+	}
+	b.items = append(b.items, pending...)
+	slices.SortStableFunc(b.items, comparePending)
+	schedulerPendingPipelines.Set(float64(len(b.items)))
+
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// remove removes the pending pipeline from the backlog and the store
+func (b *pipelineBacklog) remove(ctx context.Context, id string) error {
+	b.mu.Lock()
+	b.items = slices.DeleteFunc(b.items, func(p PendingPipeline) bool { return p.ID == id })
+	schedulerPendingPipelines.Set(float64(len(b.items)))
+	b.mu.Unlock()
+	return b.store.Delete(ctx, id)
+}
+
+// list returns the pending pipelines in the order they should be created
+func (b *pipelineBacklog) list() []PendingPipeline {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.items)
+}
+
+func (b *pipelineBacklog) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.items)
+}
+
+// hasKey returns true if a pending pipeline has the dedup key
+func (b *pipelineBacklog) hasKey(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.ContainsFunc(b.items, func(p PendingPipeline) bool {
+		return p.Pipeline.Labels[dedupKeyLabel] == key
+	})
+}
+
+// deferPipelines adds the deferred pipelines of the reports to the backlog
+func (s *Scheduler) deferPipelines(ctx context.Context, processed []processedReport) error {
+	var pending []PendingPipeline
+	for _, p := range processed {
+		for _, g := range p.generated {
+			for _, pipeline := range g.deferred {
+				pending = append(pending, PendingPipeline{
+					Priority:   g.policy.Spec.Priority,
+					ActionID:   g.actionID,
+					PolicyName: g.policy.Name,
+					PolicyUID:  g.policy.UID,
+					Pipeline:   pipeline,
+				})
+			}
+		}
+	}
+	return s.backlog.add(ctx, pending)
+}
+
+// drainBacklog creates pending pipelines until the context is done, whenever
+// pipelines are deferred and periodically, to wait for active pipelines to complete
+func (s *Scheduler) drainBacklog(ctx context.Context) {
+	ticker := time.NewTicker(backlogDrainInterval)
+	defer ticker.Stop()
+	for {
+		s.drainOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.backlog.notify:
+		}
+	}
+}
+
+// drainOnce creates the pending pipelines in order, while below the active pipeline
+// threshold. Pending pipelines of policies that are at their active pipeline limit
+// are kept in the backlog, and the pipelines after them are created instead.
+func (s *Scheduler) drainOnce(ctx context.Context) {
+	l := logf.FromContext(ctx)
+	pending := s.backlog.list()
+	if len(pending) == 0 {
+		return
+	}
+
+	allowed, reservation, err := s.reserveThreshold(ctx, len(pending))
+	if err != nil {
+		l.Error(err, "unable to check capacity to create pending pipelines")
+		return
+	}
+	defer reservation.release()
+
+	var created int
+	for _, p := range pending {
+		if created == allowed || ctx.Err() != nil {
+			break
+		}
+		if s.createPending(ctx, reservation, p) {
+			created++
+		}
+	}
+	if created > 0 {
+		l.Info(fmt.Sprintf("created %d pending pipelines", created), "pipelines", created, "pending", s.backlog.len())
+	}
+}
+
+// createPending creates the pending pipeline if its policy still exists and is
+// below its active pipeline limits, returning true if the pipeline was created
+func (s *Scheduler) createPending(ctx context.Context, reservation *thresholdReservation, p PendingPipeline) bool {
+	l := logf.FromContext(ctx).WithValues("policy", p.PolicyName, "namespace", p.Pipeline.Namespace,
+		"action-id", p.ActionID, "pending-pipeline", p.ID)
+
+	reportPolicy := &chalkularv1beta1.ChalkReportPolicy{}
+	err := s.mgrClient.Get(ctx, client.ObjectKey{Namespace: p.Pipeline.Namespace, Name: p.PolicyName}, reportPolicy)
+	if apierrors.IsNotFound(err) || (err == nil && reportPolicy.UID != p.PolicyUID) {
+		l.Info("policy of pending pipeline was deleted, dropping pipeline")
+		s.removePending(ctx, p)
+		return false
+	} else if err != nil {
+		l.Error(err, "unable to get policy of pending pipeline")
+		return false
+	}
+
+	allowed, quota, err := s.reserveQuota(ctx, reportPolicy, 1)
+	defer quota.release()
+	if allowed == 0 {
+		if !errors.Is(err, ErrActivePipelineLimit) {
+			l.Error(err, "unable to check active pipeline limits for pending pipeline")
+		}
+		return false
+	}
+
+	pipeline := p.Pipeline.DeepCopy()
+	if err := s.mgrClient.Create(ctx, pipeline); err != nil {
+		l.Error(err, "unable to create pending pipeline")
+		s.retryCreate(ctx, reportPolicy, retryPipeline{
+			actionID:   p.ActionID,
+			policyName: p.PolicyName,
+			policyUID:  p.PolicyUID,
+			pipeline:   p.Pipeline.DeepCopy(),
+			attempts:   1,
+		}, err)
+		s.removePending(ctx, p)
+		return false
+	}
+
+	schedulerPipelinesCreated.With(prometheus.Labels{"profile": pipeline.Spec.ProfileRef.Name, "policy": p.PolicyName, "namespace": pipeline.Namespace}).Inc()
+	s.recordCreated(ctx, pipeline, s.dedupWindowFor(reportPolicy))
+	reservation.created(pipeline)
+	quota.created(pipeline)
+	s.recorder.Eventf(reportPolicy, nil,
+		corev1.EventTypeNormal,
+		"PendingPipelineCreated",
+		"CreatePendingPipeline",
+		"report '%s' created pending pipeline %s", p.ActionID, pipeline.Name)
+	s.removePending(ctx, p)
+	return true
+}
+
+func (s *Scheduler) removePending(ctx context.Context, p PendingPipeline) {
+	if err := s.backlog.remove(ctx, p.ID); err != nil {
+		logf.FromContext(ctx).Error(err, "unable to remove pending pipeline from backlog", "pending-pipeline", p.ID)
+	}
+}
+
+// memoryBacklogStore is a [BacklogStore] that does not persist pending
+// pipelines, which are lost if the controller restarts.
+type memoryBacklogStore struct{}
+
+var _ BacklogStore = memoryBacklogStore{}
+
+// NewMemoryBacklogStore returns a [BacklogStore] that keeps pending pipelines in memory.
+func NewMemoryBacklogStore() BacklogStore {
+	return memoryBacklogStore{}
+}
+
+// IsDurableBacklogStore returns false if the store does not persist pending
+// pipelines, which are lost if the controller restarts
+func IsDurableBacklogStore(store BacklogStore) bool {
+	_, inMemory := store.(memoryBacklogStore)
+	return !inMemory
+}
+
+func (memoryBacklogStore) Save(context.Context, PendingPipeline) error { return nil }
+
+func (memoryBacklogStore) Delete(context.Context, string) error { return nil }
+
+func (memoryBacklogStore) Load(context.Context) ([]PendingPipeline, error) { return nil, nil }
+
+// fileBacklogStore is a [BacklogStore] that stores each
+// pending pipeline as a JSON file in a directory
+type fileBacklogStore struct {
+	dir string
+}
+
+var _ BacklogStore = &fileBacklogStore{}
+
+// NewFileBacklogStore returns a [BacklogStore] that stores pending pipelines
+// as files in the directory, creating the directory if it does not exist.
+func NewFileBacklogStore(dir string) (BacklogStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create backlog directory: %w", err)
+	}
+	return &fileBacklogStore{dir: dir}, nil
+}
+
+func (f *fileBacklogStore) path(id string) string {
+	return filepath.Join(f.dir, id+fileQueueExt)
+}
+
+func (f *fileBacklogStore) Save(_ context.Context, p PendingPipeline) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending pipeline: %w", err)
+	}
+	if err := writeFileAtomic(f.path(p.ID), data); err != nil {
+		return fmt.Errorf("failed to store pending pipeline: %w", err)
+	}
+	return nil
+}
+
+func (f *fileBacklogStore) Delete(_ context.Context, id string) error {
+	if err := os.Remove(f.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove pending pipeline %s: %w", id, err)
+	}
+	return nil
+}
+
+func (f *fileBacklogStore) Load(context.Context) ([]PendingPipeline, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backlog directory: %w", err)
+	}
+
+	var pending []PendingPipeline
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), fileQueueExt)
+		if !ok || e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(f.path(id))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pending pipeline %s: %w", id, err)
+		}
+		var p PendingPipeline
+		if err := json.Unmarshal(data, &p); err != nil || p.Pipeline == nil {
+			// keep the file for inspection, but never try to load it again
+			_ = os.Rename(f.path(id), f.path(id)+fileQueueInvalidExt)
+			continue
+		}
+		pending = append(pending, p)
+	}
+	return pending, nil
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	backlogQueueValue    = "pipelines"
+	backlogConfigMapName = "chalkular-pending-"
+	backlogConfigMapKey  = "pipeline.json.gz"
+)
+
+// configMapBacklogStore is a [BacklogStore] that stores each
+// pending pipeline as a ConfigMap in a single namespace.
+type configMapBacklogStore struct {
+	client    client.Client
+	reader    client.Reader
+	namespace string
+}
+
+var _ BacklogStore = &configMapBacklogStore{}
+
+// NewConfigMapBacklogStore returns a [BacklogStore] that stores pending pipelines as ConfigMaps
+// in the namespace. The reader should read directly from the API server, since ConfigMaps are not cached.
+func NewConfigMapBacklogStore(c client.Client, reader client.Reader, namespace string) BacklogStore {
+	return &configMapBacklogStore{
+		client:    c,
+		reader:    reader,
+		namespace: namespace,
+	}
+}
+
+func (b *configMapBacklogStore) Save(ctx context.Context, p PendingPipeline) error {
+	data, err := encodeGzipJSON(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending pipeline: %w", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backlogConfigMapName + p.ID,
+			Namespace: b.namespace,
+			Labels:    map[string]string{queueLabel: backlogQueueValue},
+		},
+		BinaryData: map[string][]byte{backlogConfigMapKey: data},
+	}
+	if err := b.client.Create(ctx, cm); err != nil {
+		return fmt.Errorf("failed to create pending pipeline: %w", err)
+	}
+	return nil
+}
+
+func (b *configMapBacklogStore) Delete(ctx context.Context, id string) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backlogConfigMapName + id,
+			Namespace: b.namespace,
+		},
+	}
+	if err := b.client.Delete(ctx, cm); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to remove pending pipeline %s: %w", id, err)
+	}
+	return nil
+}
+
+func (b *configMapBacklogStore) Load(ctx context.Context) ([]PendingPipeline, error) {
+	list := &corev1.ConfigMapList{}
+	if err := b.reader.List(ctx, list,
+		client.InNamespace(b.namespace),
+		client.MatchingLabels{queueLabel: backlogQueueValue},
+	); err != nil {
+		return nil, fmt.Errorf("failed to list pending pipelines: %w", err)
+	}
+
+	pending := make([]PendingPipeline, 0, len(list.Items))
+	for _, cm := range list.Items {
+		var p PendingPipeline
+		if err := decodeGzipJSON(cm.BinaryData[backlogConfigMapKey], &p); err != nil || p.Pipeline == nil {
+			logf.FromContext(ctx).Info("skipping invalid pending pipeline", "configmap", cm.Name)
+			continue
+		}
+		pending = append(pending, p)
+	}
+	return pending, nil
+}
@@ -0,0 +1,256 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func pendingPipeline(id string, priority int32) PendingPipeline {
+	return PendingPipeline{
+		ID:         id,
+		Priority:   priority,
+		ActionID:   "a1b2c3d4",
+		PolicyName: "images",
+		PolicyUID:  "uid-images",
+		Pipeline: &ocularv1beta1.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "chalkular-a1b2c3d4-",
+				Namespace:    "scans",
+				Labels:       map[string]string{schedulerLabel: schedulerValue},
+			},
+		},
+	}
+}
+
+// backlogStoreBehaviour are the specs shared by every [BacklogStore] implementation
+func backlogStoreBehaviour(newStore func() BacklogStore) {
+	It("should load the saved pending pipelines until deleted", func(ctx SpecContext) {
+		store := newStore()
+		Expect(store.Save(ctx, pendingPipeline("first", 0))).To(Succeed())
+		Expect(store.Save(ctx, pendingPipeline("second", 1))).To(Succeed())
+
+		pending, err := store.Load(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(ConsistOf(
+			HaveField("ID", "first"),
+			And(HaveField("ID", "second"), HaveField("Priority", int32(1))),
+		))
+
+		Expect(store.Delete(ctx, "first")).To(Succeed())
+		Expect(store.Delete(ctx, "unknown")).To(Succeed())
+		pending, err = store.Load(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(ConsistOf(HaveField("ID", "second")))
+	})
+}
+
+var _ = Describe("Backlog", func() {
+	Context("file store", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+		})
+
+		backlogStoreBehaviour(func() BacklogStore {
+			store, err := NewFileBacklogStore(dir)
+			Expect(err).NotTo(HaveOccurred())
+			return store
+		})
+
+		It("should set aside pending pipelines that cannot be decoded", func(ctx SpecContext) {
+			Expect(os.WriteFile(filepath.Join(dir, "invalid.json"), []byte("{"), 0o600)).To(Succeed())
+			store, err := NewFileBacklogStore(dir)
+			Expect(err).NotTo(HaveOccurred())
+
+			pending, err := store.Load(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(BeEmpty())
+			Expect(filepath.Join(dir, "invalid.json.invalid")).To(BeAnExistingFile())
+		})
+	})
+
+	Context("configmap store", func() {
+		var c client.Client
+
+		BeforeEach(func() {
+			c = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+		})
+
+		backlogStoreBehaviour(func() BacklogStore {
+			return NewConfigMapBacklogStore(c, c, "chalkular-system")
+		})
+
+		It("should not load the items of the report queue", func(ctx SpecContext) {
+			_, err := NewConfigMapQueue(c, c, "chalkular-system").Push(ctx, queuedReports("a1b2c3d4"))
+			Expect(err).NotTo(HaveOccurred())
+
+			pending, err := NewConfigMapBacklogStore(c, c, "chalkular-system").Load(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(BeEmpty())
+		})
+	})
+
+	Context("scheduler", func() {
+		report := ReceivedReport{Report: chalk.Report{
+			"_ACTION_ID": "a1b2c3d4",
+			"_CHALKS":    []any{map[string]any{"HASH": "aaaa"}, map[string]any{"HASH": "bbbb"}},
+		}}
+
+		var (
+			s       *Scheduler
+			c       client.Client
+			running *ocularv1beta1.Pipeline
+		)
+
+		BeforeEach(func() {
+			reportPolicy := newTestPolicy("scans", "images")
+			reportPolicy.Spec.Extraction.ForEach = new("report._CHALKS")
+			reportPolicy.Spec.Extraction.Target = "{'identifier': each.HASH}"
+			started := metav1.Now()
+			running = &ocularv1beta1.Pipeline{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "chalkular-running",
+					Namespace: "scans",
+					Labels:    map[string]string{schedulerLabel: schedulerValue},
+				},
+				Status: ocularv1beta1.PipelineStatus{StartTime: &started},
+			}
+			s, c = newTestScheduler(reportPolicy, running)
+			s.rejectPipelineThreshold = 1
+			s.backlog = newPipelineBacklog(NewMemoryBacklogStore(), 10)
+		})
+
+		countPipelines := func(ctx context.Context) int {
+			pipelines := &ocularv1beta1.PipelineList{}
+			Expect(c.List(ctx, pipelines, client.InNamespace("scans"))).To(Succeed())
+			return len(pipelines.Items)
+		}
+
+		completeRunning := func(ctx context.Context) {
+			completed := metav1.Now()
+			running.Status.CompletionTime = &completed
+			Expect(c.Update(ctx, running)).To(Succeed())
+		}
+
+		It("should defer pipelines at the threshold and create them once capacity frees up", func(ctx SpecContext) {
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(And(
+				HaveField("Pipelines", BeEmpty()),
+				HaveField("DeferredPipelines", 2),
+				HaveField("Error", BeEmpty()),
+			)))
+			Expect(s.backlog.len()).To(Equal(2))
+			Expect(results[0].DeferredInMemory).To(BeTrue())
+
+			By("keeping the pipelines pending while at the threshold")
+			s.drainOnce(ctx)
+			Expect(s.backlog.len()).To(Equal(2))
+			Expect(countPipelines(ctx)).To(Equal(1))
+
+			By("creating pending pipelines up to the threshold once the active pipeline completes")
+			completeRunning(ctx)
+			s.drainOnce(ctx)
+			Expect(s.backlog.len()).To(Equal(1))
+			Expect(countPipelines(ctx)).To(Equal(2))
+		})
+
+		It("should only mark reports deferred to a backlog that is not persisted", func(ctx SpecContext) {
+			store, err := NewFileBacklogStore(GinkgoT().TempDir())
+			Expect(err).NotTo(HaveOccurred())
+			s.backlog = newPipelineBacklog(store, 10)
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(HaveField("DeferredPipelines", 2)))
+			Expect(results[0].DeferredInMemory).To(BeFalse())
+		})
+
+		It("should defer new pipelines while pipelines are pending", func(ctx SpecContext) {
+			Expect(s.backlog.add(ctx, []PendingPipeline{pendingPipeline("", 0)})).To(Succeed())
+			completeRunning(ctx)
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(HaveField("DeferredPipelines", 2)))
+			Expect(s.backlog.list()).To(HaveExactElements(
+				HaveField("ActionID", "a1b2c3d4"),
+				HaveField("Pipeline.Labels", HaveKey(dedupKeyLabel)),
+				HaveField("Pipeline.Labels", HaveKey(dedupKeyLabel)),
+			))
+		})
+
+		It("should create pending pipelines in priority order", func(ctx SpecContext) {
+			s.rejectPipelineThreshold = 2
+			low, high := pendingPipeline("", 0), pendingPipeline("", 10)
+			high.ActionID = "e5f6a7b8"
+			Expect(s.backlog.add(ctx, []PendingPipeline{low, high})).To(Succeed())
+
+			s.drainOnce(ctx)
+			Expect(s.backlog.list()).To(ConsistOf(HaveField("ActionID", "a1b2c3d4")))
+			Expect(countPipelines(ctx)).To(Equal(2))
+		})
+
+		It("should reject reports once the backlog is full", func(ctx SpecContext) {
+			s.backlog.max = 1
+
+			_, err := processTestReports(ctx, s, report)
+			Expect(err).To(MatchError(ErrPipelineThreshold))
+			Expect(err).To(MatchError(ErrBacklogFull))
+			Expect(s.backlog.len()).To(BeZero())
+		})
+
+		It("should drop pending pipelines of deleted policies", func(ctx SpecContext) {
+			deleted := pendingPipeline("", 0)
+			deleted.PolicyUID = "uid-deleted"
+			Expect(s.backlog.add(ctx, []PendingPipeline{deleted})).To(Succeed())
+			completeRunning(ctx)
+
+			s.drainOnce(ctx)
+			Expect(s.backlog.len()).To(BeZero())
+			Expect(countPipelines(ctx)).To(Equal(1))
+		})
+
+		It("should defer the pipelines over the active pipeline limit of the policy", func(ctx SpecContext) {
+			completeRunning(ctx)
+			s.rejectPipelineThreshold = 0
+			reportPolicy := newTestPolicy("scans", "images")
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(reportPolicy), reportPolicy)).To(Succeed())
+			reportPolicy.Spec.Limits = &chalkularv1beta1.ChalkReportPolicyLimits{MaxActivePipelines: new(int32(1))}
+			Expect(c.Update(ctx, reportPolicy)).To(Succeed())
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(And(
+				HaveField("Pipelines", HaveLen(1)),
+				HaveField("DeferredPipelines", 1),
+				HaveField("Error", BeEmpty()),
+			)))
+
+			By("keeping the pipeline pending while the policy is at its limit")
+			s.drainOnce(ctx)
+			Expect(s.backlog.len()).To(Equal(1))
+		})
+
+	})
+})
@@ -11,32 +11,106 @@ package reports
 import (
 	"context"
 
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
 	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	"github.com/crashappsec/chalkular/internal/policy"
 )
 
-type SchedulerResult chan error
+// SchedulerResult is the result of processing enqueued reports
+type SchedulerResult struct {
+	// Reports are the results of each report, in the order they were
+	// enqueued. It is empty if the reports failed to be processed.
+	Reports []ReportResult
+	// Err is set if the reports failed to be processed, i.e. the active
+	// pipeline threshold was reached. The reports are removed from the
+	// queue, so they should be enqueued again after a delay.
+	Err error
+}
+
+// ReportResult is the result of processing a single chalk report
+type ReportResult struct {
+	// ActionID is the '_ACTION_ID' of the report, if it has one
+	ActionID string
+	// Policies are the results of the policies that matched
+	// the report or failed to be evaluated
+	Policies []chalkularv1beta1.ChalkReportPolicyResult
+	// Err is set if the report is invalid and was not processed,
+	// in which case retrying the report will fail again
+	Err error
+	// DeferredInMemory is true if pipelines for the report were deferred to a
+	// backlog that is not persisted, so are lost if the controller restarts.
+	// Sources that redeliver reports should not acknowledge the report.
+	DeferredInMemory bool
+}
+
+// Pipelines returns the amount of pipelines created for the report
+func (r ReportResult) Pipelines() int {
+	var count int
+	for _, p := range r.Policies {
+		count += len(p.Pipelines)
+	}
+	return count
+}
+
+// ReceivedReport is a chalk report along with
+// the source it was received from
+type ReceivedReport struct {
+	Report chalk.Report  `json:"report"`
+	Source policy.Source `json:"source,omitzero"`
+}
 
 type SchedulerClient interface {
-	Enqueue(context.Context, []chalk.Report) SchedulerResult
+	// Enqueue stores the reports in the queue of the scheduler. An error
+	// is returned if the reports could not be stored, otherwise the
+	// result of processing the reports is sent to the channel. If the
+	// reports could not be processed, the error of the result is set
+	// and the caller is responsible for enqueuing them again.
+	Enqueue(context.Context, []ReceivedReport) (<-chan SchedulerResult, error)
+
+	// Submit stores the reports in the queue of the scheduler, without
+	// waiting for the result. An error is returned if the reports could
+	// not be stored. Reports that could not be processed remain queued,
+	// and are processed again after a backoff.
+	Submit(context.Context, []ReceivedReport) error
+
+	// Evaluate runs the policies in the namespace (or all namespaces if empty)
+	// against the reports without creating any pipelines.
+	Evaluate(ctx context.Context, namespace string, reports []ReceivedReport) ([]PolicyEvaluation, error)
+
+	// Lookup returns the recorded reports with the action ID, oldest first.
+	// Reports that are queued but not yet processed are not returned.
+	Lookup(ctx context.Context, actionID string) ([]chalkularv1beta1.ChalkReport, error)
 }
 
 type schedulerClient struct {
-	eventBus eventBus
+	scheduler *Scheduler
 }
 
-type event struct {
-	Reports []chalk.Report
-	Result  SchedulerResult
-}
+func (c *schedulerClient) Enqueue(ctx context.Context, reports []ReceivedReport) (<-chan SchedulerResult, error) {
+	done := make(chan SchedulerResult, 1)
 
-type eventBus = chan event
+	// hold the lock until the waiter is registered, so the
+	// result cannot be sent before the scheduler knows of it
+	c.scheduler.waitersMu.Lock()
+	defer c.scheduler.waitersMu.Unlock()
 
-func (c *schedulerClient) Enqueue(_ context.Context, reports []chalk.Report) SchedulerResult {
-	done := make(SchedulerResult, 1)
-	c.eventBus <- event{
-		Reports: reports,
-		Result:  done,
+	id, err := c.scheduler.queue.Push(ctx, reports)
+	if err != nil {
+		return nil, err
 	}
-	return done
+	c.scheduler.waiters[id] = done
+	return done, nil
+}
+
+func (c *schedulerClient) Submit(ctx context.Context, reports []ReceivedReport) error {
+	_, err := c.scheduler.queue.Push(ctx, reports)
+	return err
+}
+
+func (c *schedulerClient) Evaluate(ctx context.Context, namespace string, reports []ReceivedReport) ([]PolicyEvaluation, error) {
+	return c.scheduler.evaluateReports(ctx, namespace, reports)
+}
 
+func (c *schedulerClient) Lookup(ctx context.Context, actionID string) ([]chalkularv1beta1.ChalkReport, error) {
+	return c.scheduler.findReports(ctx, actionID)
 }
@@ -0,0 +1,251 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/internal/policy"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;create;update;delete
+
+const (
+	// dedupKeyLabel is the label set on created pipelines and their dedup
+	// leases with the key used to detect duplicate reports, see [dedupKey]
+	dedupKeyLabel = "chalk.ocular.crashoverride.run/dedup-key"
+	// dedupLeasePruneInterval is how often dedup leases with a window that has passed are deleted
+	dedupLeasePruneInterval = 10 * time.Minute
+	// dedupLeasePrunePageSize is the amount of dedup leases listed at a time when pruning
+	dedupLeasePrunePageSize = 500
+)
+
+// dedupKey returns the key identifying a pipeline created by a policy for a report,
+// or for the value extracted by the dedup key expression of the policy. Without an
+// extracted key, the key is qualified by the 'forEach' item and target of the pipeline,
+// so that a report redelivered after only some of its pipelines were created still
+// creates the rest. The key is hashed so that it is always a valid label value.
+func dedupKey(policyUID types.UID, actionID string, vs policy.PipelineValues) string {
+	value := actionID
+	if vs.EachIndex != nil {
+		value += "/" + strconv.Itoa(*vs.EachIndex)
+	}
+	value += "/" + vs.Target.Identifier + "@" + vs.Target.Version
+	if vs.DedupKey != "" {
+		// prefixed so that an extracted key can never collide with an action ID
+		value = "key/" + vs.DedupKey
+	}
+	sum := sha256.Sum256([]byte(string(policyUID) + "/" + value))
+	return hex.EncodeToString(sum[:20])
+}
+
+// dedupWindowFor returns the dedup window of the policy,
+// defaulting to the window configured for the scheduler
+func (s *Scheduler) dedupWindowFor(p *chalkularv1beta1.ChalkReportPolicy) time.Duration {
+	if p.Spec.DedupWindow != nil {
+		return p.Spec.DedupWindow.Duration
+	}
+	return s.dedupWindow
+}
+
+// recentKeys are the dedup keys of the pipelines recently created by the scheduler,
+// to detect duplicates before the created pipelines are in the informer cache.
+type recentKeys struct {
+	mu   sync.Mutex
+	keys map[string]time.Time
+}
+
+// seen returns true if the key was added and has not expired
+func (r *recentKeys) seen(key string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	expires, ok := r.keys[key]
+	return ok && expires.After(now)
+}
+
+// add adds the key until it expires, removing the keys that have expired
+func (r *recentKeys) add(key string, now, expires time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.keys == nil {
+		r.keys = make(map[string]time.Time)
+	}
+	for k, e := range r.keys {
+		if !e.After(now) {
+			delete(r.keys, k)
+		}
+	}
+	r.keys[key] = expires
+}
+
+// isDuplicate returns true if a pipeline with the dedup key in the namespace is pending, waiting
+// to be retried, has not completed, or was created or completed within the dedup window. Created
+// pipelines are recorded with a dedup lease (see [Scheduler.recordCreated]), so duplicates are detected
+// across restarts and replicas even once the pipelines are deleted. Failing to list pipelines or
+// read the lease is logged, and the pipeline is not considered a duplicate.
+func (s *Scheduler) isDuplicate(ctx context.Context, namespace, key string, window time.Duration) bool {
+	if window <= 0 {
+		return false
+	}
+	l := logf.FromContext(ctx)
+	now := time.Now()
+	if s.recent.seen(key, now) || s.retries.hasKey(key) || (s.backlog != nil && s.backlog.hasKey(key)) {
+		return true
+	}
+
+	list := &ocularv1beta1.PipelineList{}
+	if err := s.mgrClient.List(ctx, list,
+		client.InNamespace(namespace),
+		client.MatchingLabels{dedupKeyLabel: key},
+	); err != nil {
+		l.Error(err, "unable to list pipelines to detect duplicate pipelines")
+		return false
+	}
+	since := now.Add(-window)
+	for _, p := range list.Items {
+		completed := p.Status.CompletionTime
+		if completed == nil || completed.After(since) || p.CreationTimestamp.After(since) {
+			return true
+		}
+	}
+
+	// leases are read from the API server, so that they are not cached
+	lease := &coordinationv1.Lease{}
+	err := s.apiReader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: dedupLeaseName(key)}, lease)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			l.Error(err, "unable to get dedup lease to detect duplicate pipelines")
+		}
+		return false
+	}
+	// the lease is valid for the window it was recorded with, but is also
+	// compared against the current window in case the window was shortened
+	return lease.Spec.RenewTime != nil && lease.Spec.RenewTime.After(since) && !dedupLeaseExpired(lease, now)
+}
+
+// recordCreated records that the pipeline was created with its dedup key, in memory until the
+// pipeline is in the informer cache, and with a dedup lease that outlives the pipeline. Failing
+// to record the lease is logged, in which case only the pipeline itself is used to detect duplicates.
+func (s *Scheduler) recordCreated(ctx context.Context, pipeline *ocularv1beta1.Pipeline, window time.Duration) {
+	key := pipeline.Labels[dedupKeyLabel]
+	if window <= 0 || key == "" {
+		return
+	}
+	now := time.Now()
+	s.recent.add(key, now, now.Add(window))
+
+	renewed := metav1.NewMicroTime(now)
+	spec := coordinationv1.LeaseSpec{
+		HolderIdentity:       new(schedulerValue),
+		LeaseDurationSeconds: new(int32(math.Ceil(min(window.Seconds(), math.MaxInt32)))),
+		RenewTime:            &renewed,
+	}
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dedupLeaseName(key),
+			Namespace: pipeline.Namespace,
+			Labels:    map[string]string{schedulerLabel: schedulerValue, dedupKeyLabel: key},
+		},
+		Spec: spec,
+	}
+	err := s.mgrClient.Create(ctx, lease)
+	if apierrors.IsAlreadyExists(err) {
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			if err := s.apiReader.Get(ctx, client.ObjectKeyFromObject(lease), lease); err != nil {
+				return err
+			}
+			lease.Spec = spec
+			return s.mgrClient.Update(ctx, lease)
+		})
+	}
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "unable to record dedup lease for created pipeline",
+			"pipeline", pipeline.Name, "namespace", pipeline.Namespace)
+	}
+}
+
+// dedupLeaseName returns the name of the lease recording the dedup key
+func dedupLeaseName(key string) string {
+	return "chalkular-dedup-" + key
+}
+
+// dedupLeaseExpired returns true if the dedup window the lease was recorded with has passed
+func dedupLeaseExpired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	expires := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+	return !expires.After(now)
+}
+
+// pruneDedupLeases deletes the expired dedup leases every
+// [dedupLeasePruneInterval] until the context is done
+func (s *Scheduler) pruneDedupLeases(ctx context.Context) {
+	ticker := time.NewTicker(dedupLeasePruneInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.pruneExpiredLeases(ctx, time.Now()); err != nil {
+			logf.FromContext(ctx).Error(err, "unable to prune dedup leases")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pruneExpiredLeases deletes the dedup leases with a dedup window
+// that has passed in all namespaces, returning the amount deleted
+func (s *Scheduler) pruneExpiredLeases(ctx context.Context, now time.Time) (int, error) {
+	var (
+		pruned int
+		next   string
+	)
+	for {
+		list := &coordinationv1.LeaseList{}
+		if err := s.apiReader.List(ctx, list,
+			client.MatchingLabels{schedulerLabel: schedulerValue},
+			client.HasLabels{dedupKeyLabel},
+			client.Limit(dedupLeasePrunePageSize),
+			client.Continue(next),
+		); err != nil {
+			return pruned, fmt.Errorf("failed to list dedup leases: %w", err)
+		}
+		for i := range list.Items {
+			lease := &list.Items[i]
+			if !dedupLeaseExpired(lease, now) {
+				continue
+			}
+			if err := s.mgrClient.Delete(ctx, lease); client.IgnoreNotFound(err) != nil {
+				return pruned, fmt.Errorf("failed to delete dedup lease %s/%s: %w", lease.Namespace, lease.Name, err)
+			}
+			pruned++
+		}
+		if next = list.Continue; next == "" {
+			return pruned, nil
+		}
+	}
+}
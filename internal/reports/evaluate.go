@@ -0,0 +1,185 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	"github.com/crashappsec/chalkular/api/v1beta1/httpserver"
+	"github.com/crashappsec/chalkular/internal/policy"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// PolicyEvaluation is the result of a dry-run evaluation
+// of a single policy against a single chalk report.
+type PolicyEvaluation struct {
+	ActionID string
+	Policy   types.NamespacedName
+	Matched  bool
+	// Audit is true if the policy is in audit mode, in
+	// which case the pipelines would not be created
+	Audit     bool
+	Values    []policy.PipelineValues
+	Pipelines []*ocularv1beta1.Pipeline
+	Err       error
+	// ExcludedBy are the exclusive policies that claimed
+	// the targets of the values removed from Values
+	ExcludedBy []string
+}
+
+// ToAPI converts the evaluation to the type returned by the HTTP API and the CLI
+func (e PolicyEvaluation) ToAPI() httpserver.PolicyEvaluation {
+	result := httpserver.PolicyEvaluation{
+		ActionID:   e.ActionID,
+		Policy:     e.Policy.Name,
+		Namespace:  e.Policy.Namespace,
+		Matched:    e.Matched,
+		Audit:      e.Audit,
+		ExcludedBy: e.ExcludedBy,
+	}
+	for _, vs := range e.Values {
+		result.Values = append(result.Values, httpserver.PipelineValues{
+			Target:           vs.Target,
+			DownloaderParams: vs.DownloaderParams,
+			ProfileParams:    vs.ProfileParams,
+			Labels:           vs.Labels,
+			Annotations:      vs.Annotations,
+			ProfileRef:       toAPIReference(vs.ProfileRef),
+			DownloaderRef:    toAPIReference(vs.DownloaderRef),
+			DedupKey:         vs.DedupKey,
+			EachIndex:        vs.EachIndex,
+		})
+	}
+	for _, p := range e.Pipelines {
+		result.Pipelines = append(result.Pipelines, *p)
+	}
+	if e.Err != nil {
+		result.Error = e.Err.Error()
+	}
+	return result
+}
+
+func toAPIReference(ref *policy.ObjectReference) *httpserver.ObjectReference {
+	if ref == nil {
+		return nil
+	}
+	return &httpserver.ObjectReference{Name: ref.Name, Kind: ref.Kind}
+}
+
+// EvaluatorOptions configures how an [Evaluator] evaluates policies
+type EvaluatorOptions struct {
+	// MaxPipelinesPerPolicy is the maximum amount of pipelines a policy
+	// can generate for a single report. 0 or less indicates no maximum.
+	MaxPipelinesPerPolicy int
+	// Reader is used to validate the profile and downloader of rendered pipelines.
+	// If nil, the profiles and downloaders are not validated.
+	Reader client.Reader
+	// IgnoreReady evaluates policies that are not in the 'Ready' condition,
+	// i.e. policies read from files that have not been reconciled
+	IgnoreReady bool
+}
+
+// Evaluator evaluates policies against chalk reports and renders the pipelines
+// they would create, without creating them. It is used by the scheduler, the
+// policy evaluation endpoint and the 'chalkular policy test' command.
+type Evaluator struct {
+	compiler *policy.Compiler
+	opts     EvaluatorOptions
+}
+
+// NewEvaluator creates an [Evaluator] that compiles policies with the compiler
+func NewEvaluator(compiler *policy.Compiler, opts EvaluatorOptions) *Evaluator {
+	return &Evaluator{compiler: compiler, opts: opts}
+}
+
+// compile returns the compiled policy. Policies without a UID, i.e. read from
+// files, can not be cached by the compiler so are compiled each time.
+func (e *Evaluator) compile(reportPolicy *chalkularv1beta1.ChalkReportPolicy) (*policy.CompiledPolicy, error) {
+	if reportPolicy.UID == "" {
+		return e.compiler.Compile(reportPolicy)
+	}
+	return e.compiler.Get(reportPolicy)
+}
+
+// Evaluate evaluates the policies against each of the reports, returning an evaluation
+// for each report and policy. Policies are evaluated in priority order, so exclusive
+// policies claim targets first, but the evaluations of a report are in the order of the policies.
+func (e *Evaluator) Evaluate(ctx context.Context, policies []chalkularv1beta1.ChalkReportPolicy, reports []ReceivedReport) ([]PolicyEvaluation, error) {
+	l := logf.FromContext(ctx)
+
+	order := make([]int, len(policies))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return policy.ComparePriority(&policies[a], &policies[b])
+	})
+
+	var evaluations []PolicyEvaluation
+	for i, report := range reports {
+		actionID, valid := report.Report[chalk.KeyActionID].(string)
+		if !valid {
+			return nil, fmt.Errorf("%w, report %d is missing or has an invalid key %s", ErrInvalidReport, i, chalk.KeyActionID)
+		}
+		reportCtx := logf.IntoContext(ctx, l.WithValues("action-id", actionID))
+
+		var claims policy.ExclusiveClaims
+		reportEvaluations := make([]PolicyEvaluation, len(policies))
+		for _, j := range order {
+			reportPolicy := &policies[j]
+			policyCtx := logf.IntoContext(reportCtx, logf.FromContext(reportCtx).
+				WithValues("policy", reportPolicy.Name, "namespace", reportPolicy.Namespace))
+
+			evaluation, err := e.evaluatePolicy(policyCtx, reportPolicy, actionID, report, &claims)
+			reportEvaluations[j] = PolicyEvaluation{
+				ActionID:   actionID,
+				Policy:     client.ObjectKeyFromObject(reportPolicy),
+				Matched:    evaluation.matched,
+				Audit:      reportPolicy.Spec.Mode == chalkularv1beta1.PolicyModeAudit,
+				Values:     evaluation.values,
+				Pipelines:  evaluation.pipelines,
+				Err:        err,
+				ExcludedBy: evaluation.excludedBy,
+			}
+		}
+		evaluations = append(evaluations, reportEvaluations...)
+	}
+	return evaluations, nil
+}
+
+// evaluator returns an [Evaluator] configured like the scheduler
+func (s *Scheduler) evaluator() *Evaluator {
+	return NewEvaluator(s.policyCompiler, EvaluatorOptions{
+		MaxPipelinesPerPolicy: s.maxPipelinesPerPolicy,
+		Reader:                s.mgrClient,
+	})
+}
+
+// evaluateReports evaluates every policy in the namespace (or all namespaces
+// if empty) against the reports, returning the pipelines that would be created.
+// No pipelines are created and no events are recorded.
+func (s *Scheduler) evaluateReports(ctx context.Context, namespace string, reports []ReceivedReport) ([]PolicyEvaluation, error) {
+	l := logf.FromContext(ctx)
+	l.Info("evaluating chalk reports", "reports", len(reports), "namespace", namespace)
+
+	policies := &chalkularv1beta1.ChalkReportPolicyList{}
+	if err := s.mgrClient.List(ctx, policies, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("unable to list chalk report policies: %w", err)
+	}
+	policy.SortByPriority(policies.Items)
+
+	return s.evaluator().Evaluate(ctx, policies.Items, reports)
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("evaluateReports", func() {
+	report := ReceivedReport{Report: chalk.Report{"_ACTION_ID": "a1b2c3d4"}}
+
+	It("should only evaluate the policies in the namespace", func(ctx SpecContext) {
+		s, c := newTestScheduler(newTestPolicy("scans", "images"), newTestPolicy("other", "images"))
+
+		evaluations, err := s.evaluateReports(ctx, "scans", []ReceivedReport{report})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(evaluations).To(ConsistOf(HaveField("Policy", types.NamespacedName{Namespace: "scans", Name: "images"})))
+
+		By("evaluating the policies in every namespace without a namespace")
+		evaluations, err = s.evaluateReports(ctx, "", []ReceivedReport{report})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(evaluations).To(HaveLen(2))
+
+		By("not creating any pipelines")
+		Expect(countPipelines(ctx, c)).To(BeZero())
+	})
+
+	It("should render the pipelines as the scheduler would create them", func(ctx SpecContext) {
+		audited := newTestPolicy("scans", "audited")
+		audited.Spec.Mode = chalkularv1beta1.PolicyModeAudit
+		s, _ := newTestScheduler(newTestPolicy("scans", "images"), audited)
+
+		evaluations, err := s.evaluateReports(ctx, "scans", []ReceivedReport{report})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(evaluations).To(ConsistOf(
+			And(HaveField("Policy.Name", "audited"), HaveField("Audit", true), HaveField("Matched", true)),
+			And(HaveField("Policy.Name", "images"), HaveField("Audit", false), HaveField("Matched", true)),
+		))
+		for _, evaluation := range evaluations {
+			Expect(evaluation.Err).NotTo(HaveOccurred())
+			Expect(evaluation.Pipelines).To(HaveLen(1))
+			Expect(evaluation.Pipelines[0].Labels).To(And(
+				HaveKeyWithValue(schedulerLabel, schedulerValue),
+				HaveKey(dedupKeyLabel),
+				HaveKeyWithValue(chalkularv1beta1.PipelineActionIDLabel, "a1b2c3d4"),
+			))
+		}
+	})
+
+	It("should reject reports without an action ID", func(ctx SpecContext) {
+		s, _ := newTestScheduler(newTestPolicy("scans", "images"))
+
+		_, err := s.evaluateReports(ctx, "scans", []ReceivedReport{{Report: chalk.Report{}}})
+		Expect(err).To(MatchError(ErrInvalidReport))
+	})
+})
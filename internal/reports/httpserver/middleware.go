@@ -114,5 +114,11 @@ func authorizationMiddleware(authN authenticator.Request, authZ authorizer.Autho
 			errorResponse(c, http.StatusForbidden, msg)
 			return
 		}
+
+		c.Set(userKey, res.User)
 	}
 }
+
+// userKey is the [gin.Context] key for the
+// user info of the authenticated request
+const userKey = "chalkular-user"
@@ -0,0 +1,105 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package httpserver
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	v1beta1 "github.com/crashappsec/chalkular/api/v1beta1/httpserver"
+	"github.com/crashappsec/chalkular/internal/reports"
+	"github.com/gin-gonic/gin"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var policieslog = logf.Log.WithName("policies-http")
+
+// evaluatePolicies runs a dry-run of all policies against the chalk report(s)
+// in the request body. The body can either be a single chalk report or a list of reports.
+// The query parameter 'namespace' can be used to limit which policies are evaluated. The
+// user must be allowed to get and list the policies in the namespace, or in all namespaces
+// if no namespace is given, since the evaluations include the expressions' results.
+func evaluatePolicies(scheduler reports.SchedulerClient, authZ authorizer.Authorizer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		namespace := c.Query("namespace")
+		if !canReadPolicies(c, authZ, namespace) {
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			errorResponse(c, http.StatusBadRequest, "unable to read request")
+			return
+		}
+
+		rs, err := chalk.ParseReports(body)
+		if err != nil {
+			errorResponse(c, http.StatusBadRequest, "unable to parse request")
+			return
+		}
+
+		policieslog.Info("received policy evaluation request", "count", len(rs), "namespace", namespace)
+		evaluations, err := scheduler.Evaluate(c, namespace, receivedReports(c, rs))
+		if err != nil {
+			policieslog.Error(err, "failed to evaluate policies")
+			errorResponse(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+
+		results := make([]v1beta1.PolicyEvaluation, 0, len(evaluations))
+		for _, e := range evaluations {
+			results = append(results, e.ToAPI())
+		}
+
+		c.JSON(http.StatusOK, v1beta1.APIResponse[[]v1beta1.PolicyEvaluation]{
+			Code:     http.StatusOK,
+			Response: results,
+			Message:  fmt.Sprintf("evaluated %d reports", len(rs)),
+		})
+	}
+}
+
+// canReadPolicies returns true if the user of the request can get and list the policies in the
+// namespace (or all namespaces if empty), otherwise a forbidden response is sent and false is returned
+func canReadPolicies(c *gin.Context, authZ authorizer.Authorizer, namespace string) bool {
+	u, ok := c.Value(userKey).(user.Info)
+	if !ok {
+		errorResponse(c, http.StatusUnauthorized, "Unauthenticated")
+		return false
+	}
+
+	scope := "all namespaces"
+	if namespace != "" {
+		scope = fmt.Sprintf("namespace %s", namespace)
+	}
+	for _, verb := range []string{"get", "list"} {
+		decision, reason, err := authZ.Authorize(c, authorizer.AttributesRecord{
+			User:            u,
+			Verb:            verb,
+			Namespace:       namespace,
+			APIGroup:        chalkularv1beta1.GroupVersion.Group,
+			APIVersion:      chalkularv1beta1.GroupVersion.Version,
+			Resource:        "chalkreportpolicies",
+			ResourceRequest: true,
+		})
+		if err != nil || decision != authorizer.DecisionAllow {
+			policieslog.Info("user is not allowed to evaluate policies", "user", u.GetName(),
+				"verb", verb, "namespace", namespace, "reason", reason, "err", err)
+			errorResponse(c, http.StatusForbidden, fmt.Sprintf("user %s cannot %s chalkreportpolicies in %s",
+				u.GetName(), verb, scope))
+			return false
+		}
+	}
+	return true
+}
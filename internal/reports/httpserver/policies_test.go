@@ -0,0 +1,115 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	v1beta1 "github.com/crashappsec/chalkular/api/v1beta1/httpserver"
+	"github.com/crashappsec/chalkular/internal/reports"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+// fakeScheduler is a [reports.SchedulerClient] that records
+// the namespace of the evaluations it is asked for
+type fakeScheduler struct {
+	reports.SchedulerClient
+	namespaces []string
+}
+
+func (f *fakeScheduler) Evaluate(_ context.Context, namespace string, rs []reports.ReceivedReport) ([]reports.PolicyEvaluation, error) {
+	f.namespaces = append(f.namespaces, namespace)
+	return []reports.PolicyEvaluation{{
+		ActionID: rs[0].Report["_ACTION_ID"].(string),
+		Policy:   types.NamespacedName{Namespace: namespace, Name: "images"},
+		Matched:  true,
+	}}, nil
+}
+
+// namespaceAuthorizer allows getting and listing
+// policies only in the namespaces in allowed
+func namespaceAuthorizer(allowed ...string) authorizer.Authorizer {
+	return authorizer.AuthorizerFunc(func(_ context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+		if a.IsResourceRequest() &&
+			a.GetAPIGroup() == chalkularv1beta1.GroupVersion.Group &&
+			a.GetResource() == "chalkreportpolicies" &&
+			(a.GetVerb() == "get" || a.GetVerb() == "list") {
+			for _, ns := range allowed {
+				if a.GetNamespace() == ns {
+					return authorizer.DecisionAllow, "", nil
+				}
+			}
+		}
+		return authorizer.DecisionNoOpinion, "not allowed", nil
+	})
+}
+
+var _ = Describe("evaluatePolicies", func() {
+	var scheduler *fakeScheduler
+
+	BeforeEach(func() {
+		gin.SetMode(gin.TestMode)
+		scheduler = &fakeScheduler{}
+	})
+
+	evaluate := func(authZ authorizer.Authorizer, query string) *httptest.ResponseRecorder {
+		engine := gin.New()
+		engine.POST("/policies/evaluate", func(c *gin.Context) {
+			c.Set(userKey, &user.DefaultInfo{Name: "alice"})
+		}, evaluatePolicies(scheduler, authZ))
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/policies/evaluate"+query,
+			strings.NewReader(`{"_ACTION_ID": "a1b2c3d4"}`))
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	It("should evaluate the policies in a namespace the user can read", func() {
+		w := evaluate(namespaceAuthorizer("scans"), "?namespace=scans")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(scheduler.namespaces).To(Equal([]string{"scans"}))
+
+		var response v1beta1.APIResponse[[]v1beta1.PolicyEvaluation]
+		Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+		Expect(response.Response).To(ConsistOf(And(
+			HaveField("Policy", "images"),
+			HaveField("Namespace", "scans"),
+			HaveField("ActionID", "a1b2c3d4"),
+		)))
+	})
+
+	It("should reject a namespace the user cannot read", func() {
+		w := evaluate(namespaceAuthorizer("scans"), "?namespace=other")
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+		Expect(w.Body.String()).To(ContainSubstring("user alice cannot get chalkreportpolicies in namespace other"))
+		Expect(scheduler.namespaces).To(BeEmpty())
+	})
+
+	It("should require access to all namespaces without a namespace", func() {
+		w := evaluate(namespaceAuthorizer("scans"), "")
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+		Expect(w.Body.String()).To(ContainSubstring("in all namespaces"))
+		Expect(scheduler.namespaces).To(BeEmpty())
+
+		w = evaluate(namespaceAuthorizer(""), "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(scheduler.namespaces).To(Equal([]string{""}))
+	})
+})
@@ -9,32 +9,205 @@
 package httpserver
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
 	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
 	v1beta1 "github.com/crashappsec/chalkular/api/v1beta1/httpserver"
+	"github.com/crashappsec/chalkular/internal/policy"
 	"github.com/crashappsec/chalkular/internal/reports"
 	"github.com/gin-gonic/gin"
+	"k8s.io/apiserver/pkg/authentication/user"
 )
 
 var reportslog = logf.Log.WithName("reports-http")
 
+// retryAfter is the value of the 'Retry-After' header, in seconds,
+// sent when reports are rejected or could not be queued
+const retryAfter = "30"
+
+// scheduleReport queues the chalk reports in the request body. If the query parameter
+// 'wait' is true, the response is sent once the reports are processed and contains the
+// result of each report, otherwise the response is sent once the reports are queued.
 func scheduleReport(scheduler reports.SchedulerClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var reports []chalk.Report
-		if err := c.BindJSON(&reports); err != nil {
+		var rs []chalk.Report
+		if err := c.BindJSON(&rs); err != nil {
 			errorResponse(c, http.StatusBadRequest, "unable to parse request")
 			return
 		}
 
-		reportslog.Info("received report upload", "count", len(reports))
-		_ = scheduler.Enqueue(c, reports)
-		c.JSON(http.StatusOK, v1beta1.APIResponse[struct{}]{
-			Code:    http.StatusOK,
-			Message: fmt.Sprintf("processed %d reports", len(reports)),
+		wait, err := strconv.ParseBool(c.DefaultQuery("wait", "false"))
+		if err != nil {
+			errorResponse(c, http.StatusBadRequest, "invalid value for query parameter 'wait'")
+			return
+		}
+
+		reportslog.Info("received report upload", "count", len(rs), "wait", wait)
+		if !wait {
+			// reports that cannot be processed yet remain queued, since
+			// the response is sent before they are processed
+			if err := scheduler.Submit(c, receivedReports(c, rs)); err != nil {
+				queueErrorResponse(c, err)
+				return
+			}
+			c.JSON(http.StatusAccepted, v1beta1.APIResponse[struct{}]{
+				Code:    http.StatusAccepted,
+				Message: fmt.Sprintf("queued %d reports", len(rs)),
+			})
+			return
+		}
+
+		result, err := scheduler.Enqueue(c, receivedReports(c, rs))
+		if err != nil {
+			queueErrorResponse(c, err)
+			return
+		}
+
+		var processed reports.SchedulerResult
+		select {
+		case <-c.Request.Context().Done():
+			// the reports remain queued and will still be processed,
+			// unless they cannot be processed yet
+			errorResponse(c, http.StatusServiceUnavailable, "request cancelled before reports were processed")
+			return
+		case processed = <-result:
+		}
+		if processed.Err != nil {
+			reportslog.Error(processed.Err, "failed to process reports")
+			code := http.StatusServiceUnavailable
+			if errors.Is(processed.Err, reports.ErrPipelineThreshold) {
+				code = http.StatusTooManyRequests
+			}
+			c.Header("Retry-After", retryAfter)
+			errorResponse(c, code, processed.Err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, v1beta1.APIResponse[v1beta1.ReportResults]{
+			Code:     http.StatusOK,
+			Response: toAPIResults(processed),
+			Message:  fmt.Sprintf("processed %d reports", len(rs)),
 		})
 	}
 }
+
+// queueErrorResponse responds with a '503', since the reports could not be queued
+func queueErrorResponse(c *gin.Context, err error) {
+	reportslog.Error(err, "unable to queue reports")
+	c.Header("Retry-After", retryAfter)
+	errorResponse(c, http.StatusServiceUnavailable, "unable to queue reports")
+}
+
+func toAPIResults(result reports.SchedulerResult) v1beta1.ReportResults {
+	results := v1beta1.ReportResults{
+		Success: []string{},
+		Failed:  []string{},
+		Reports: make([]v1beta1.ReportResult, 0, len(result.Reports)),
+	}
+	for _, r := range result.Reports {
+		report := v1beta1.ReportResult{ActionID: r.ActionID}
+		if r.Err != nil {
+			report.Error = r.Err.Error()
+		} else if slices.ContainsFunc(r.Policies, func(p chalkularv1beta1.ChalkReportPolicyResult) bool { return p.Error != "" }) {
+			results.Failed = append(results.Failed, r.ActionID)
+		} else {
+			results.Success = append(results.Success, r.ActionID)
+		}
+		report.Policies = toAPIPolicyResults(r.Policies)
+		results.Reports = append(results.Reports, report)
+	}
+	return results
+}
+
+// reportStatus responds with the scheduling status of the
+// reports with the action ID given in the path, oldest first
+func reportStatus(scheduler reports.SchedulerClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actionID := c.Param("actionID")
+		records, err := scheduler.Lookup(c, actionID)
+		if errors.Is(err, reports.ErrInvalidActionID) {
+			errorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		} else if err != nil {
+			reportslog.Error(err, "unable to look up reports", "action-id", actionID)
+			errorResponse(c, http.StatusInternalServerError, "unable to look up reports")
+			return
+		}
+		if len(records) == 0 {
+			errorResponse(c, http.StatusNotFound,
+				fmt.Sprintf("no reports found for action ID '%s', reports that are queued are not found until processed", actionID))
+			return
+		}
+
+		statuses := make([]v1beta1.ReportStatus, 0, len(records))
+		for _, r := range records {
+			statuses = append(statuses, toAPIStatus(r))
+		}
+		c.JSON(http.StatusOK, v1beta1.APIResponse[[]v1beta1.ReportStatus]{
+			Code:     http.StatusOK,
+			Response: statuses,
+		})
+	}
+}
+
+func toAPIStatus(record chalkularv1beta1.ChalkReport) v1beta1.ReportStatus {
+	status := v1beta1.ReportStatus{
+		Name:         record.Name,
+		ActionID:     record.Spec.ActionID,
+		Source:       record.Spec.Source.Method,
+		ReceivedTime: record.Spec.ReceivedTime.Time,
+		Policies:     toAPIPolicyResults(record.Status.Policies),
+	}
+	if record.Status.ProcessedTime != nil {
+		status.ProcessedTime = &record.Status.ProcessedTime.Time
+	}
+	return status
+}
+
+func toAPIPolicyResults(results []chalkularv1beta1.ChalkReportPolicyResult) []v1beta1.PolicyResult {
+	var policies []v1beta1.PolicyResult
+	for _, p := range results {
+		policies = append(policies, v1beta1.PolicyResult{
+			Policy:            p.Name,
+			Namespace:         p.Namespace,
+			Pipelines:         p.Pipelines,
+			Error:             p.Error,
+			Duplicate:         p.Duplicate,
+			SkippedPipelines:  p.SkippedPipelines,
+			DeferredPipelines: p.DeferredPipelines,
+			ExcludedPipelines: p.ExcludedPipelines,
+			RetryingPipelines: p.RetryingPipelines,
+			AuditedPipelines:  p.AuditedPipelines,
+		})
+	}
+	return policies
+}
+
+// httpSource returns the source for reports received in the request
+func httpSource(c *gin.Context) policy.Source {
+	source := policy.Source{Method: policy.SourceMethodHTTP}
+	if u, ok := c.Value(userKey).(user.Info); ok {
+		source.HTTP = policy.HTTPSource{
+			User:   u.GetName(),
+			Groups: u.GetGroups(),
+		}
+	}
+	return source
+}
+
+// receivedReports wraps the reports with the source of the request
+func receivedReports(c *gin.Context, rs []chalk.Report) []reports.ReceivedReport {
+	source := httpSource(c)
+	received := make([]reports.ReceivedReport, 0, len(rs))
+	for _, r := range rs {
+		received = append(received, reports.ReceivedReport{Report: r, Source: source})
+	}
+	return received
+}
@@ -0,0 +1,158 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/internal/policy"
+	"github.com/crashappsec/chalkular/internal/reports"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// fakeManager is a [manager.Manager] with only the methods used
+// by [reports.NewScheduler], backed by a fake client that is built
+// once the fields indexed by the scheduler are added
+type fakeManager struct {
+	manager.Manager
+	builder *fake.ClientBuilder
+	client  client.Client
+}
+
+func (m *fakeManager) GetFieldIndexer() client.FieldIndexer {
+	return m
+}
+
+func (m *fakeManager) IndexField(_ context.Context, obj client.Object, field string, extract client.IndexerFunc) error {
+	m.builder.WithIndex(obj, field, extract)
+	return nil
+}
+
+func (m *fakeManager) GetClient() client.Client {
+	if m.client == nil {
+		m.client = m.builder.Build()
+	}
+	return m.client
+}
+
+func (m *fakeManager) GetAPIReader() client.Reader {
+	return m.GetClient()
+}
+
+func (m *fakeManager) GetEventRecorder(string) events.EventRecorder {
+	return events.NewFakeRecorder(100)
+}
+
+var _ = Describe("scheduleReport", func() {
+	var (
+		running *ocularv1beta1.Pipeline
+		c       client.Client
+		engine  *gin.Engine
+	)
+
+	BeforeEach(func() {
+		gin.SetMode(gin.TestMode)
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(chalkularv1beta1.AddToScheme(scheme)).To(Succeed())
+		Expect(ocularv1beta1.AddToScheme(scheme)).To(Succeed())
+
+		started := metav1.Now()
+		running = &ocularv1beta1.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "chalkular-running",
+				Namespace: "scans",
+				Labels:    map[string]string{chalkularv1beta1.PipelineScheduledByLabel: chalkularv1beta1.PipelineScheduledByValue},
+			},
+			Status: ocularv1beta1.PipelineStatus{StartTime: &started},
+		}
+		reportPolicy := &chalkularv1beta1.ChalkReportPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "images", Namespace: "scans", UID: "uid-images"},
+			Spec: chalkularv1beta1.ChalkReportPolicySpec{
+				MatchCondition: "true",
+				Extraction: chalkularv1beta1.ChalkReportPolicyExtraction{
+					Target: "{'identifier': report._ACTION_ID, 'version': 'latest'}",
+				},
+			},
+			Status: chalkularv1beta1.ChalkReportPolicyStatus{
+				Conditions: []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Ready"}},
+			},
+		}
+		mgr := &fakeManager{
+			builder: fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(reportPolicy, running).
+				WithStatusSubresource(&chalkularv1beta1.ChalkReport{}, &chalkularv1beta1.ChalkReportPolicy{}),
+		}
+
+		compiler, err := policy.NewCompiler(16, policy.CompilerOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		scheduler, err := reports.NewScheduler(mgr, compiler, reports.NewMemoryQueue(), reports.SchedulerOptions{
+			RejectPipelineThreshold: 1,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		c = mgr.GetClient()
+
+		schedulerCtx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			_ = scheduler.Start(schedulerCtx)
+		}()
+		DeferCleanup(func() {
+			cancel()
+			<-stopped
+		})
+
+		engine = gin.New()
+		engine.POST("/report", scheduleReport(scheduler.GetClient()))
+	})
+
+	upload := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/report"+query,
+			strings.NewReader(`[{"_ACTION_ID": "a1b2c3d4"}]`))
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	It("should respond with a 429 once the active pipeline threshold is reached", func(ctx SpecContext) {
+		w := upload("?wait=true")
+		Expect(w.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(w.Header().Get("Retry-After")).To(Equal(retryAfter))
+		Expect(w.Body.String()).To(ContainSubstring(reports.ErrPipelineThreshold.Error()))
+
+		By("processing the uploaded reports again once the active pipeline completes")
+		completed := metav1.Now()
+		running.Status.CompletionTime = &completed
+		Expect(c.Update(ctx, running)).To(Succeed())
+
+		w = upload("?wait=true")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(ContainSubstring(`"success":["a1b2c3d4"]`))
+		pipelines := &ocularv1beta1.PipelineList{}
+		Expect(c.List(ctx, pipelines, client.InNamespace("scans"))).To(Succeed())
+		Expect(pipelines.Items).To(HaveLen(2))
+	})
+})
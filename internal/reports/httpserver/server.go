@@ -64,6 +64,8 @@ func NewServer(config *rest.Config, httpClient *http.Client, client reports.Sche
 	apiV1beta1 := engine.Group("/api/v1beta1", authorizationMiddleware(authN, authZ))
 	{
 		apiV1beta1.POST("/report", scheduleReport(client))
+		apiV1beta1.GET("/report/:actionID", reportStatus(client))
+		apiV1beta1.POST("/policies/evaluate", evaluatePolicies(client, authZ))
 	}
 
 	s.engine = engine
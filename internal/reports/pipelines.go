@@ -10,156 +10,282 @@ package reports
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"maps"
+	"time"
 
 	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
 	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	"github.com/crashappsec/chalkular/internal/policy"
 	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// maxUncachedPipelineAge is how long a created pipeline that is not in the
+// cache of the client is counted as active, in case it was deleted
+const maxUncachedPipelineAge = time.Minute
+
 type policyGeneratedPipelines struct {
 	report    chalk.Report
 	actionID  string
 	policy    chalkularv1beta1.ChalkReportPolicy
 	pipelines []*ocularv1beta1.Pipeline
+	// skipped is the number of pipelines removed from pipelines since a
+	// pipeline with the same dedup key already exists within the dedup window
+	skipped int
+	// overLimit is the number of pipelines removed from pipelines since the active
+	// pipeline limit was reached, or the limits could not be checked (see limitErr)
+	overLimit int
+	limitErr  error
+	// deferred are the pipelines removed from pipelines to be added to the backlog,
+	// since the active pipeline threshold or limit of the policy was reached
+	deferred []*ocularv1beta1.Pipeline
+	// quota are the pipelines reserved below the active pipeline limits
+	quota *quotaReservation
+	// excluded is the number of pipelines not rendered since their
+	// target was claimed by the exclusive policies in excludedBy
+	excluded   int
+	excludedBy []string
 }
 
-func (s *Scheduler) createPipelinesForReport(ctx context.Context, policies []chalkularv1beta1.ChalkReportPolicy, actionID string, report chalk.Report) []policyGeneratedPipelines {
-	l := logf.FromContext(ctx)
+// policyEvaluation is the result of evaluating a
+// single policy against a chalk report
+type policyEvaluation struct {
+	matched   bool
+	values    []policy.PipelineValues
+	pipelines []*ocularv1beta1.Pipeline
+	// excluded is the amount of values removed since their target was
+	// claimed by the exclusive policies in excludedBy
+	excluded   int
+	excludedBy []string
+}
 
-	var generatedPipelines []policyGeneratedPipelines
-	for _, policy := range policies {
-		policyLogger := l.WithValues("policy", policy.Name, "namespace", policy.Namespace)
+// failedPolicy is a policy that failed to be evaluated against a report. The failure
+// is only recorded once the report is processed, so that reports that are processed
+// again do not record it more than once.
+type failedPolicy struct {
+	policy chalkularv1beta1.ChalkReportPolicy
+	err    *policyEvalError
+}
 
-		if err := s.isDownloaderValid(ctx, &policy); err != nil {
-			policyLogger.Info("skipping policy, unable to validate downloader: %w", err)
-		}
+// policyEvalError is returned from [Evaluator.evaluatePolicy]
+// when the failure should be recorded as an event on the policy
+type policyEvalError struct {
+	reason string
+	action string
+	err    error
+}
 
-		if err := s.isProfileValid(ctx, &policy); err != nil {
-			policyLogger.Info("skipping policy, unable to validate profile: %w", err)
-		}
+func (e *policyEvalError) Error() string {
+	return e.err.Error()
+}
 
-		if !meta.IsStatusConditionTrue(policy.Status.Conditions, "Ready") {
-			policyLogger.Info("skipping policy, not in 'Ready' condition")
-			continue
-		}
+func (e *policyEvalError) Unwrap() error {
+	return e.err
+}
 
-		p, err := s.policyCompiler.Get(&policy)
-		if err != nil {
-			policyLogger.Error(err, "unable to get compiled expressions for policy, skipping")
-			continue
-		}
-		matches, err := p.Matches(report)
+// createPipelinesForReport evaluates the policies against the report, returning the rendered
+// pipelines for each policy that matched and the policies that failed to be evaluated.
+// The policies should be sorted with [policy.SortByPriority], so exclusive policies claim targets first.
+func (s *Scheduler) createPipelinesForReport(ctx context.Context, policies []chalkularv1beta1.ChalkReportPolicy, actionID string, report ReceivedReport) ([]policyGeneratedPipelines, []failedPolicy) {
+	l := logf.FromContext(ctx)
+
+	var (
+		generatedPipelines []policyGeneratedPipelines
+		failed             []failedPolicy
+		claims             policy.ExclusiveClaims
+	)
+	for _, reportPolicy := range policies {
+		policyLogger := l.WithValues("policy", reportPolicy.Name, "namespace", reportPolicy.Namespace)
+		policyCtx := logf.IntoContext(ctx, policyLogger)
+
+		evaluation, err := s.evaluator().evaluatePolicy(policyCtx, &reportPolicy, actionID, report, &claims)
 		if err != nil {
-			policyLogger.Error(err, "failed to run match expresssion, skipping")
-			s.recorder.Eventf(&policy, nil,
-				corev1.EventTypeWarning,
-				"PolicyEvalFailed",
-				"MatchConditionEval",
-				"failed to evaluate match condition for action %s: %s", actionID, err)
+			if evalErr, ok := errors.AsType[*policyEvalError](err); ok {
+				failed = append(failed, failedPolicy{policy: reportPolicy, err: evalErr})
+			}
 			continue
 		}
-		if !matches {
-			policyLogger.Info("policy match returned false")
+
+		if !evaluation.matched {
 			continue
 		}
 
-		pipelineTemplate := policy.Spec.PipelineTemplate
+		generatedPipelines = append(generatedPipelines, policyGeneratedPipelines{
+			report:     report.Report,
+			actionID:   actionID,
+			policy:     reportPolicy,
+			pipelines:  evaluation.pipelines,
+			excluded:   evaluation.excluded,
+			excludedBy: evaluation.excludedBy,
+		})
 
-		values, err := p.Extract(report)
-		if err != nil {
-			policyLogger.Error(err, "failed to extract pipeline values")
-			s.recorder.Eventf(&policy, nil,
-				corev1.EventTypeWarning,
-				"PolicyExtractFailed",
-				"ExtractPipelineValues",
-				"failed to extract pipeline values for action %s: %s", actionID, err)
-			continue
+	}
+
+	l.Info(fmt.Sprintf("generated %d pipelines for chalk report", len(generatedPipelines)), "pipelines", len(generatedPipelines))
+	return generatedPipelines, failed
+}
+
+// recordFailedPolicy records the failed evaluation of the policy as
+// an event on the policy, returning the result of the policy
+func (s *Scheduler) recordFailedPolicy(f failedPolicy) chalkularv1beta1.ChalkReportPolicyResult {
+	if errors.Is(f.err, policy.ErrEvalBudgetExceeded) {
+		schedulerPolicyEvalBudgetExceeded.WithLabelValues(f.policy.Name, f.policy.Namespace).Inc()
+	}
+	s.recorder.Eventf(&f.policy, nil,
+		corev1.EventTypeWarning,
+		f.err.reason,
+		f.err.action,
+		"%s", f.err)
+	return chalkularv1beta1.ChalkReportPolicyResult{
+		Name:      f.policy.Name,
+		Namespace: f.policy.Namespace,
+		Error:     f.err.Error(),
+	}
+}
+
+// evaluatePolicy runs the match condition and extraction expressions of the
+// policy against the report and renders the resulting pipelines. The pipelines are not created.
+// Values with a target already claimed by an exclusive policy are removed, and if the policy is
+// exclusive the targets of the remaining values are claimed.
+func (e *Evaluator) evaluatePolicy(ctx context.Context, reportPolicy *chalkularv1beta1.ChalkReportPolicy, actionID string, report ReceivedReport, claims *policy.ExclusiveClaims) (policyEvaluation, error) {
+	policyLogger := logf.FromContext(ctx)
+
+	if !e.opts.IgnoreReady && !meta.IsStatusConditionTrue(reportPolicy.Status.Conditions, "Ready") {
+		policyLogger.Info("skipping policy, not in 'Ready' condition")
+		return policyEvaluation{}, fmt.Errorf("policy is not in 'Ready' condition")
+	}
+
+	p, err := e.compile(reportPolicy)
+	if err != nil {
+		policyLogger.Error(err, "unable to get compiled expressions for policy, skipping")
+		return policyEvaluation{}, fmt.Errorf("unable to compile policy: %w", err)
+	}
+	matches, err := p.Matches(report.Report, report.Source)
+	if err != nil {
+		policyLogger.Error(err, "failed to run match expresssion, skipping")
+		return policyEvaluation{}, &policyEvalError{
+			reason: evalFailedReason(err, "PolicyEvalFailed"),
+			action: "MatchConditionEval",
+			err:    fmt.Errorf("failed to evaluate match condition for action %s: %w", actionID, err),
 		}
+	}
+	if !matches {
+		policyLogger.Info("policy match returned false")
+		return policyEvaluation{}, nil
+	}
 
-		if s.maxPipelinesPerPolicy > 0 && len(values) > s.maxPipelinesPerPolicy {
-			policyLogger.Error(err, "policy generated too many pipelines")
-			s.recorder.Eventf(&policy, nil,
-				corev1.EventTypeWarning,
-				"TooManyPipelinesGenerated",
-				"ExtractPipelineValues",
-				"more than %d pipelines were generated for action %s: exceededs limit of %d", len(values), actionID, s.maxPipelinesPerPolicy)
-			continue
+	values, err := p.Extract(report.Report, report.Source)
+	if err != nil {
+		policyLogger.Error(err, "failed to extract pipeline values")
+		return policyEvaluation{matched: true}, &policyEvalError{
+			reason: evalFailedReason(err, "PolicyExtractFailed"),
+			action: "ExtractPipelineValues",
+			err:    fmt.Errorf("failed to extract pipeline values for action %s: %w", actionID, err),
 		}
+	}
 
-		var pipelines []*ocularv1beta1.Pipeline
-		policyLogger.Info(fmt.Sprintf("policy generated %d values", len(values)), "values", len(values))
-		for _, vs := range values {
-			pipeline := &ocularv1beta1.Pipeline{
-				ObjectMeta: metav1.ObjectMeta{
-					GenerateName: fmt.Sprintf("chalkular-%s-", actionID),
-					Namespace:    policy.Namespace,
-					Annotations:  make(map[string]string),
-					Labels:       make(map[string]string),
-				},
-			}
-			maps.Copy(pipeline.Labels, pipelineTemplate.Labels)
-			maps.Copy(pipeline.Annotations, pipelineTemplate.Annotations)
-			pipelineTemplate.Spec.DeepCopyInto(&pipeline.Spec)
+	if maxPipelines := e.opts.MaxPipelinesPerPolicy; maxPipelines > 0 && len(values) > maxPipelines {
+		policyLogger.Info("policy generated too many pipelines", "values", len(values))
+		return policyEvaluation{matched: true, values: values}, &policyEvalError{
+			reason: "TooManyPipelinesGenerated",
+			action: "ExtractPipelineValues",
+			err: fmt.Errorf("%d pipelines were generated for action %s, which exceeds the limit of %d",
+				len(values), actionID, maxPipelines),
+		}
+	}
 
-			pipeline.Spec.DownloaderRef.Parameters = append(pipeline.Spec.DownloaderRef.Parameters, vs.DownloaderParams...)
-			pipeline.Spec.ProfileRef.Parameters = append(pipeline.Spec.ProfileRef.Parameters, vs.ProfileParams...)
-			pipeline.Spec.Target = vs.Target
+	kept, excludedBy := claims.Filter(reportPolicy, values)
+	if excluded := len(values) - len(kept); excluded > 0 {
+		policyLogger.Info("excluded values with targets claimed by exclusive policies",
+			"excluded", excluded, "claimedBy", excludedBy)
+	}
+
+	policyLogger.Info(fmt.Sprintf("policy generated %d values", len(kept)), "values", len(kept))
+	pipelines := policy.RenderPipelines(reportPolicy, actionID, kept)
+	// pipelines are rendered in the same order as the values they were extracted from
+	for i, pipeline := range pipelines {
+		pipeline.Labels[schedulerLabel] = schedulerValue
+		pipeline.Labels[dedupKeyLabel] = dedupKey(reportPolicy.UID, actionID, kept[i])
+	}
 
-			pipeline.Labels[schedulerLabel] = schedulerValue
-			pipelines = append(pipelines, pipeline)
+	// the profile and downloader can be extracted from the
+	// report, so are validated for each rendered pipeline
+	for _, pipeline := range pipelines {
+		if e.opts.Reader == nil {
+			break
+		}
+		if err := e.isDownloaderValid(ctx, pipeline); err != nil {
+			policyLogger.Info("unable to validate downloader", "downloader", pipeline.Spec.DownloaderRef.Name,
+				"kind", pipeline.Spec.DownloaderRef.Kind, "error", err.Error())
 		}
-		generatedPipelines = append(generatedPipelines, policyGeneratedPipelines{
-			report:    report,
-			actionID:  actionID,
-			policy:    policy,
-			pipelines: pipelines,
-		})
 
+		if err := e.isProfileValid(ctx, pipeline); err != nil {
+			policyLogger.Info("unable to validate profile", "profile", pipeline.Spec.ProfileRef.Name, "error", err.Error())
+		}
 	}
 
-	l.Info(fmt.Sprintf("generated %d pipelines for chalk report", len(generatedPipelines)), "pipelines", len(generatedPipelines))
-	return generatedPipelines
+	return policyEvaluation{
+		matched:    true,
+		values:     kept,
+		pipelines:  pipelines,
+		excluded:   len(values) - len(kept),
+		excludedBy: excludedBy,
+	}, nil
 }
 
-func (s *Scheduler) isProfileValid(ctx context.Context, reportPolicy *chalkularv1beta1.ChalkReportPolicy) error {
-	found := &ocularv1beta1.Profile{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: reportPolicy.Namespace,
-			Name:      reportPolicy.Spec.PipelineTemplate.Spec.ProfileRef.Name,
-		},
+// evalFailedReason returns the event reason for a failed evaluation of the policy,
+// which is 'PolicyEvalBudgetExceeded' if the CEL cost limit or timeout was reached
+func evalFailedReason(err error, reason string) string {
+	if errors.Is(err, policy.ErrEvalBudgetExceeded) {
+		return "PolicyEvalBudgetExceeded"
 	}
-	return s.mgrClient.Get(ctx, client.ObjectKey{Namespace: reportPolicy.Namespace, Name: reportPolicy.Spec.PipelineTemplate.Spec.ProfileRef.Name}, found)
+	return reason
 }
 
-func (s *Scheduler) isDownloaderValid(ctx context.Context, reportPolicy *chalkularv1beta1.ChalkReportPolicy) error {
-	downloaderRef := reportPolicy.Spec.PipelineTemplate.Spec.DownloaderRef
+func (e *Evaluator) isProfileValid(ctx context.Context, pipeline *ocularv1beta1.Pipeline) error {
+	profileRef := pipeline.Spec.ProfileRef
+	switch profileRef.Kind {
+	case "", "Profile":
+		found := &ocularv1beta1.Profile{}
+		return e.opts.Reader.Get(ctx, client.ObjectKey{Namespace: pipeline.Namespace, Name: profileRef.Name}, found)
+	default:
+		return fmt.Errorf("unknown profile kind: %s", profileRef.Kind)
+	}
+}
+
+func (e *Evaluator) isDownloaderValid(ctx context.Context, pipeline *ocularv1beta1.Pipeline) error {
+	downloaderRef := pipeline.Spec.DownloaderRef
 	switch downloaderRef.Kind {
 	case "", "Downloader":
 		found := &ocularv1beta1.Downloader{}
-		return s.mgrClient.Get(ctx, client.ObjectKey{Namespace: reportPolicy.Namespace, Name: reportPolicy.Spec.PipelineTemplate.Spec.DownloaderRef.Name}, found)
+		return e.opts.Reader.Get(ctx, client.ObjectKey{Namespace: pipeline.Namespace, Name: downloaderRef.Name}, found)
 	case "ClusterDownloader":
 		found := &ocularv1beta1.ClusterDownloader{}
-		return s.mgrClient.Get(ctx, client.ObjectKey{Name: downloaderRef.Name}, found)
+		return e.opts.Reader.Get(ctx, client.ObjectKey{Name: downloaderRef.Name}, found)
 	default:
 		return fmt.Errorf("unknown downloader kind: %s", downloaderRef.Kind)
 	}
 }
 
+// isPipelineActiveIndexer indexes the pipelines that have not completed as active. Pipelines
+// that were created but have not started yet are active, since they will run once scheduled.
 func isPipelineActiveIndexer(o client.Object) []string {
 	p := o.(*ocularv1beta1.Pipeline)
-	if p.Status.StartTime != nil && p.Status.CompletionTime == nil {
+	if p.Status.CompletionTime == nil {
 		return []string{"true"}
 	}
 	return []string{"false"}
 }
 
+// countActivePipelines returns the amount of active pipelines created by the scheduler, including
+// the pipelines that were created but are not in the cache of the client yet. Must be called
+// with reservedMu held.
 func (s *Scheduler) countActivePipelines(ctx context.Context) (int, error) {
 	list := &ocularv1beta1.PipelineList{}
 	err := s.mgrClient.List(ctx, list,
@@ -169,5 +295,39 @@ func (s *Scheduler) countActivePipelines(ctx context.Context) (int, error) {
 	if err != nil {
 		return 0, fmt.Errorf("failed to list pipelines: %w", err)
 	}
-	return len(list.Items), nil
+
+	active := len(list.Items)
+	if len(s.uncached) > 0 {
+		listed := make(map[types.NamespacedName]struct{}, len(list.Items))
+		for _, pipeline := range list.Items {
+			listed[client.ObjectKeyFromObject(&pipeline)] = struct{}{}
+		}
+		now := time.Now()
+		for key, created := range s.uncached {
+			if _, ok := listed[key]; ok || now.Sub(created) > maxUncachedPipelineAge {
+				delete(s.uncached, key)
+				continue
+			}
+			// the pipeline may have already completed, or been deleted
+			err := s.mgrClient.Get(ctx, key, &ocularv1beta1.Pipeline{})
+			if err == nil {
+				delete(s.uncached, key)
+				continue
+			} else if !apierrors.IsNotFound(err) {
+				return 0, fmt.Errorf("failed to get created pipeline: %w", err)
+			}
+			active++
+		}
+	}
+	return active, nil
+}
+
+// trackUncached counts the created pipeline as active until it is in the cache of the client,
+// so that pipelines created before the cache is updated cannot exceed the threshold. Must be
+// called with reservedMu held.
+func (s *Scheduler) trackUncached(pipeline *ocularv1beta1.Pipeline) {
+	if s.uncached == nil {
+		s.uncached = make(map[types.NamespacedName]time.Time)
+	}
+	s.uncached[client.ObjectKeyFromObject(pipeline)] = time.Now()
 }
@@ -0,0 +1,279 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	QueueBackendMemory    = "memory"
+	QueueBackendFile      = "file"
+	QueueBackendConfigMap = "configmap"
+)
+
+// QueueItem is a batch of received reports stored in a [Queue]
+type QueueItem struct {
+	ID      string
+	Reports []ReceivedReport
+}
+
+// A Queue stores batches of received reports between the intake methods
+// and the [Scheduler]. Items remain in the queue until acknowledged, so that
+// a durable queue can recover the items that were not processed when the
+// controller restarted or lost leadership.
+type Queue interface {
+	// Push stores the reports, returning the ID of
+	// the queued item once it has been stored
+	Push(ctx context.Context, reports []ReceivedReport) (string, error)
+	// Pop blocks until an item is available or the context is done.
+	// The item is not removed until [Queue.Ack] is called with its ID.
+	Pop(ctx context.Context) (QueueItem, error)
+	// Ack removes the item from the queue
+	Ack(ctx context.Context, id string) error
+	// Requeue returns an item that was popped but could not be processed
+	// to the queue, so it is popped again after the items already pending
+	Requeue(ctx context.Context, id string) error
+}
+
+// pendingItems is the ordered list of IDs of the items in
+// a queue that have not yet been popped. It is shared by the
+// queue implementations to wait for new items to be pushed.
+type pendingItems struct {
+	mu     sync.Mutex
+	ids    []string
+	notify chan struct{}
+}
+
+func newPendingItems(ids ...string) *pendingItems {
+	return &pendingItems{
+		ids:    ids,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (p *pendingItems) push(id string) {
+	p.mu.Lock()
+	p.ids = append(p.ids, id)
+	p.mu.Unlock()
+
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// next blocks until an item is pending, returning its ID
+func (p *pendingItems) next(ctx context.Context) (string, error) {
+	for {
+		p.mu.Lock()
+		if len(p.ids) > 0 {
+			id := p.ids[0]
+			p.ids = p.ids[1:]
+			p.mu.Unlock()
+			return id, nil
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-p.notify:
+		}
+	}
+}
+
+// newItemID returns a new ID for a queue item. IDs sort in the order they were created.
+func newItemID() string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%020d-%s", time.Now().UnixNano(), hex.EncodeToString(suffix))
+}
+
+// memoryQueue is a [Queue] that stores items in memory.
+// Items are lost if the controller restarts.
+type memoryQueue struct {
+	pending *pendingItems
+
+	mu    sync.Mutex
+	items map[string][]ReceivedReport
+}
+
+var _ Queue = &memoryQueue{}
+
+// NewMemoryQueue returns a [Queue] that stores items in memory.
+func NewMemoryQueue() Queue {
+	return &memoryQueue{
+		pending: newPendingItems(),
+		items:   make(map[string][]ReceivedReport),
+	}
+}
+
+func (q *memoryQueue) Push(_ context.Context, reports []ReceivedReport) (string, error) {
+	id := newItemID()
+	q.mu.Lock()
+	q.items[id] = reports
+	q.mu.Unlock()
+	q.pending.push(id)
+	return id, nil
+}
+
+func (q *memoryQueue) Pop(ctx context.Context) (QueueItem, error) {
+	id, err := q.pending.next(ctx)
+	if err != nil {
+		return QueueItem{}, err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QueueItem{ID: id, Reports: q.items[id]}, nil
+}
+
+func (q *memoryQueue) Ack(_ context.Context, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.items, id)
+	return nil
+}
+
+func (q *memoryQueue) Requeue(_ context.Context, id string) error {
+	q.mu.Lock()
+	_, ok := q.items[id]
+	q.mu.Unlock()
+	if ok {
+		q.pending.push(id)
+	}
+	return nil
+}
+
+// fileQueue is a [Queue] that stores each item as a JSON file in a directory,
+// i.e. a mounted persistent volume. Items that were not acknowledged are
+// recovered from the directory when the queue is constructed.
+type fileQueue struct {
+	dir     string
+	pending *pendingItems
+}
+
+var _ Queue = &fileQueue{}
+
+const (
+	fileQueueExt        = ".json"
+	fileQueueInvalidExt = ".invalid"
+)
+
+// NewFileQueue returns a [Queue] that stores items as files in the directory,
+// creating the directory if it does not exist.
+func NewFileQueue(dir string) (Queue, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue directory: %w", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), fileQueueExt); ok && !e.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	// entries are sorted by name, which is the order the items were pushed
+	return &fileQueue{
+		dir:     dir,
+		pending: newPendingItems(ids...),
+	}, nil
+}
+
+func (q *fileQueue) path(id string) string {
+	return filepath.Join(q.dir, id+fileQueueExt)
+}
+
+func (q *fileQueue) Push(_ context.Context, reports []ReceivedReport) (string, error) {
+	data, err := json.Marshal(reports)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode reports: %w", err)
+	}
+
+	id := newItemID()
+	if err := writeFileAtomic(q.path(id), data); err != nil {
+		return "", fmt.Errorf("failed to store queue item: %w", err)
+	}
+
+	q.pending.push(id)
+	return id, nil
+}
+
+// writeFileAtomic writes the data to a temporary file in the same directory first,
+// so that partially written files are never read from the path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-"+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (q *fileQueue) Pop(ctx context.Context) (QueueItem, error) {
+	for {
+		id, err := q.pending.next(ctx)
+		if err != nil {
+			return QueueItem{}, err
+		}
+
+		data, err := os.ReadFile(q.path(id))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return QueueItem{}, fmt.Errorf("failed to read queue item %s: %w", id, err)
+		}
+
+		var reports []ReceivedReport
+		if err := json.Unmarshal(data, &reports); err != nil {
+			// keep the item for inspection, but never try to process it again
+			_ = os.Rename(q.path(id), q.path(id)+fileQueueInvalidExt)
+			return QueueItem{}, fmt.Errorf("failed to decode queue item %s: %w", id, err)
+		}
+		return QueueItem{ID: id, Reports: reports}, nil
+	}
+}
+
+func (q *fileQueue) Ack(_ context.Context, id string) error {
+	if err := os.Remove(q.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove queue item %s: %w", id, err)
+	}
+	return nil
+}
+
+// Requeue adds the item to the pending items again, items
+// that were removed are skipped once they are popped
+func (q *fileQueue) Requeue(_ context.Context, id string) error {
+	q.pending.push(id)
+	return nil
+}
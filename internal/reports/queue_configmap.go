@@ -0,0 +1,204 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;create;patch;delete
+
+const (
+	queueLabel         = "chalk.ocular.crashoverride.run/queue"
+	queueValue         = "reports"
+	queueInvalidValue  = "invalid"
+	queueConfigMapName = "chalkular-queue-"
+	queueConfigMapKey  = "reports.json.gz"
+)
+
+// configMapQueue is a [Queue] that stores each item as a ConfigMap
+// in a single namespace. Items that were not acknowledged are
+// recovered from the namespace on the first call to [Queue.Pop].
+type configMapQueue struct {
+	client    client.Client
+	reader    client.Reader
+	namespace string
+
+	pending *pendingItems
+
+	mu     sync.Mutex
+	loaded bool
+}
+
+var _ Queue = &configMapQueue{}
+
+// NewConfigMapQueue returns a [Queue] that stores items as ConfigMaps in the namespace.
+// The reader should read directly from the API server, since ConfigMaps are not cached.
+func NewConfigMapQueue(c client.Client, reader client.Reader, namespace string) Queue {
+	return &configMapQueue{
+		client:    c,
+		reader:    reader,
+		namespace: namespace,
+		pending:   newPendingItems(),
+	}
+}
+
+func (q *configMapQueue) Push(ctx context.Context, reports []ReceivedReport) (string, error) {
+	data, err := encodeGzipJSON(reports)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode reports: %w", err)
+	}
+
+	id := newItemID()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      queueConfigMapName + id,
+			Namespace: q.namespace,
+			Labels:    map[string]string{queueLabel: queueValue},
+		},
+		BinaryData: map[string][]byte{queueConfigMapKey: data},
+	}
+	if err := q.client.Create(ctx, cm); err != nil {
+		return "", fmt.Errorf("failed to create queue item: %w", err)
+	}
+
+	q.pending.push(id)
+	return id, nil
+}
+
+// load adds the items already stored in the namespace to the pending items
+func (q *configMapQueue) load(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.loaded {
+		return nil
+	}
+
+	list := &corev1.ConfigMapList{}
+	if err := q.reader.List(ctx, list,
+		client.InNamespace(q.namespace),
+		client.MatchingLabels{queueLabel: queueValue},
+	); err != nil {
+		return fmt.Errorf("failed to list queue items: %w", err)
+	}
+
+	ids := make([]string, 0, len(list.Items))
+	for _, cm := range list.Items {
+		if id, ok := strings.CutPrefix(cm.Name, queueConfigMapName); ok {
+			ids = append(ids, id)
+		}
+	}
+	// names are ordered by the time the items were pushed
+	slices.Sort(ids)
+
+	q.pending.mu.Lock()
+	// items pushed before loading are also in the list
+	for _, id := range q.pending.ids {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	q.pending.ids = ids
+	q.pending.mu.Unlock()
+
+	q.loaded = true
+	return nil
+}
+
+func (q *configMapQueue) Pop(ctx context.Context) (QueueItem, error) {
+	if err := q.load(ctx); err != nil {
+		return QueueItem{}, err
+	}
+
+	for {
+		id, err := q.pending.next(ctx)
+		if err != nil {
+			return QueueItem{}, err
+		}
+
+		cm := &corev1.ConfigMap{}
+		err = q.reader.Get(ctx, client.ObjectKey{Namespace: q.namespace, Name: queueConfigMapName + id}, cm)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return QueueItem{}, fmt.Errorf("failed to get queue item %s: %w", id, err)
+		}
+
+		var reports []ReceivedReport
+		if err := decodeGzipJSON(cm.BinaryData[queueConfigMapKey], &reports); err != nil {
+			// keep the item for inspection, but never try to process it again
+			q.setAside(ctx, cm)
+			return QueueItem{}, fmt.Errorf("failed to decode queue item %s: %w", id, err)
+		}
+		return QueueItem{ID: id, Reports: reports}, nil
+	}
+}
+
+// setAside relabels the ConfigMap of an item that cannot be decoded,
+// so that it is no longer recovered as an item of the queue
+func (q *configMapQueue) setAside(ctx context.Context, cm *corev1.ConfigMap) {
+	patch := client.MergeFrom(cm.DeepCopy())
+	cm.Labels[queueLabel] = queueInvalidValue
+	_ = q.client.Patch(ctx, cm, patch)
+}
+
+// encodeGzipJSON encodes the value as gzip compressed JSON,
+// since ConfigMaps are limited to 1MiB
+func encodeGzipJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeGzipJSON(data []byte, v any) error {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+	return json.NewDecoder(r).Decode(v)
+}
+
+func (q *configMapQueue) Ack(ctx context.Context, id string) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      queueConfigMapName + id,
+			Namespace: q.namespace,
+		},
+	}
+	if err := q.client.Delete(ctx, cm); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to remove queue item %s: %w", id, err)
+	}
+	return nil
+}
+
+// Requeue adds the item to the pending items again, items
+// that were removed are skipped once they are popped
+func (q *configMapQueue) Requeue(_ context.Context, id string) error {
+	q.pending.push(id)
+	return nil
+}
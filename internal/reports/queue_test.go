@@ -0,0 +1,228 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	"github.com/crashappsec/chalkular/internal/policy"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func queuedReports(actionID string) []ReceivedReport {
+	return []ReceivedReport{{
+		Report: chalk.Report{"_ACTION_ID": actionID},
+		Source: policy.Source{
+			Method: policy.SourceMethodHTTP,
+			HTTP:   policy.HTTPSource{User: "uploader"},
+		},
+	}}
+}
+
+// queueBehaviour are the specs shared by every [Queue] implementation
+func queueBehaviour(newQueue func() Queue) {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		DeferCleanup(cancel)
+	})
+
+	It("should pop items in the order they were pushed", func() {
+		q := newQueue()
+		first, err := q.Push(ctx, queuedReports("first"))
+		Expect(err).NotTo(HaveOccurred())
+		second, err := q.Push(ctx, queuedReports("second"))
+		Expect(err).NotTo(HaveOccurred())
+
+		item, err := q.Pop(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(item.ID).To(Equal(first))
+		Expect(item.Reports).To(Equal(queuedReports("first")))
+
+		item, err = q.Pop(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(item.ID).To(Equal(second))
+		Expect(item.Reports).To(Equal(queuedReports("second")))
+	})
+
+	It("should block until an item is pushed", func() {
+		q := newQueue()
+		popped := make(chan QueueItem, 1)
+		go func() {
+			defer GinkgoRecover()
+			item, err := q.Pop(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			popped <- item
+		}()
+
+		Consistently(popped, "100ms").ShouldNot(Receive())
+		id, err := q.Push(ctx, queuedReports("a1b2c3d4"))
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(popped).Should(Receive(HaveField("ID", id)))
+	})
+
+	It("should pop requeued items after the items already pending", func() {
+		q := newQueue()
+		first, err := q.Push(ctx, queuedReports("first"))
+		Expect(err).NotTo(HaveOccurred())
+		item, err := q.Pop(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := q.Push(ctx, queuedReports("second"))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(q.Requeue(ctx, item.ID)).To(Succeed())
+		item, err = q.Pop(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(item.ID).To(Equal(second))
+		item, err = q.Pop(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(item.ID).To(Equal(first))
+		Expect(item.Reports).To(Equal(queuedReports("first")))
+	})
+
+	It("should stop waiting when the context is done", func() {
+		q := newQueue()
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := q.Pop(cancelled)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+}
+
+var _ = Describe("Queue", func() {
+	Context("memory", func() {
+		queueBehaviour(NewMemoryQueue)
+	})
+
+	Context("file", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+		})
+
+		queueBehaviour(func() Queue {
+			q, err := NewFileQueue(dir)
+			Expect(err).NotTo(HaveOccurred())
+			return q
+		})
+
+		It("should recover items that were not acknowledged", func(ctx SpecContext) {
+			q, err := NewFileQueue(dir)
+			Expect(err).NotTo(HaveOccurred())
+			acked, err := q.Push(ctx, queuedReports("acked"))
+			Expect(err).NotTo(HaveOccurred())
+			pending, err := q.Push(ctx, queuedReports("pending"))
+			Expect(err).NotTo(HaveOccurred())
+
+			item, err := q.Pop(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(item.ID).To(Equal(acked))
+			Expect(q.Ack(ctx, acked)).To(Succeed())
+
+			By("constructing a new queue from the same directory")
+			recovered, err := NewFileQueue(dir)
+			Expect(err).NotTo(HaveOccurred())
+			item, err = recovered.Pop(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(item.ID).To(Equal(pending))
+			Expect(item.Reports).To(Equal(queuedReports("pending")))
+		})
+
+		It("should set aside items that cannot be decoded", func(ctx SpecContext) {
+			Expect(os.WriteFile(filepath.Join(dir, "invalid.json"), []byte("{"), 0o600)).To(Succeed())
+			q, err := NewFileQueue(dir)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = q.Pop(ctx)
+			Expect(err).To(MatchError(ContainSubstring("failed to decode queue item invalid")))
+			Expect(filepath.Join(dir, "invalid.json.invalid")).To(BeAnExistingFile())
+			Expect(filepath.Join(dir, "invalid.json")).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Context("configmap", func() {
+		const namespace = "chalkular-system"
+		var c client.Client
+
+		BeforeEach(func() {
+			c = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+		})
+
+		queueBehaviour(func() Queue {
+			return NewConfigMapQueue(c, c, namespace)
+		})
+
+		It("should recover items that were not acknowledged", func(ctx SpecContext) {
+			q := NewConfigMapQueue(c, c, namespace)
+			acked, err := q.Push(ctx, queuedReports("acked"))
+			Expect(err).NotTo(HaveOccurred())
+			pending, err := q.Push(ctx, queuedReports("pending"))
+			Expect(err).NotTo(HaveOccurred())
+
+			cms := &corev1.ConfigMapList{}
+			Expect(c.List(ctx, cms, client.InNamespace(namespace))).To(Succeed())
+			Expect(cms.Items).To(HaveLen(2))
+			Expect(cms.Items[0].Labels).To(HaveKeyWithValue(queueLabel, queueValue))
+
+			item, err := q.Pop(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(item.ID).To(Equal(acked))
+			Expect(q.Ack(ctx, acked)).To(Succeed())
+
+			By("constructing a new queue in the same namespace")
+			recovered := NewConfigMapQueue(c, c, namespace)
+			item, err = recovered.Pop(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(item.ID).To(Equal(pending))
+			Expect(item.Reports).To(Equal(queuedReports("pending")))
+
+			Expect(recovered.Ack(ctx, pending)).To(Succeed())
+			Expect(c.List(ctx, cms, client.InNamespace(namespace))).To(Succeed())
+			Expect(cms.Items).To(BeEmpty())
+		})
+
+		It("should set aside items that cannot be decoded", func(ctx SpecContext) {
+			Expect(c.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      queueConfigMapName + "invalid",
+					Namespace: namespace,
+					Labels:    map[string]string{queueLabel: queueValue},
+				},
+				BinaryData: map[string][]byte{queueConfigMapKey: []byte("{")},
+			})).To(Succeed())
+			q := NewConfigMapQueue(c, c, namespace)
+
+			_, err := q.Pop(ctx)
+			Expect(err).To(MatchError(ContainSubstring("failed to decode queue item invalid")))
+			cm := &corev1.ConfigMap{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: queueConfigMapName + "invalid"}, cm)).To(Succeed())
+			Expect(cm.Labels).To(HaveKeyWithValue(queueLabel, queueInvalidValue))
+
+			By("not recovering the item with a new queue")
+			recovered := NewConfigMapQueue(c, c, namespace)
+			popCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			_, err = recovered.Pop(popCtx)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+		})
+	})
+})
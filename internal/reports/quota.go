@@ -0,0 +1,221 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+const (
+	// policyUIDLabel is the label set on created pipelines with the
+	// UID of the policy, to count the active pipelines of the policy
+	policyUIDLabel = chalkularv1beta1.PipelinePolicyUIDLabel
+	// namespaceQuotaAnnotation is the annotation on a namespace that sets the maximum
+	// amount of active pipelines created by all the policies in the namespace
+	namespaceQuotaAnnotation = "chalk.ocular.crashoverride.run/max-active-pipelines"
+)
+
+// quotaReservations are the amount of pipelines being created by workers for each policy
+// and namespace, that count towards the active pipeline limits until they are created.
+type quotaReservations struct {
+	mu       sync.Mutex
+	reserved map[string]int
+	// uncached are the pipelines created by the scheduler that are not
+	// in the cache of the client yet, that count towards the limits
+	uncached map[types.NamespacedName]uncachedPipeline
+}
+
+// uncachedPipeline is a created pipeline that is not in the cache of the client yet
+type uncachedPipeline struct {
+	policyUID string
+	created   time.Time
+}
+
+// trackUncached counts the created pipeline as active until it is in the cache of the
+// client, so that pipelines created before the cache is updated cannot exceed the limits.
+// Pipelines tracked for longer than [maxUncachedPipelineAge] are no longer counted, in case
+// they were deleted. Must be called with mu held.
+func (q *quotaReservations) trackUncached(pipeline *ocularv1beta1.Pipeline, now time.Time) {
+	if q.uncached == nil {
+		q.uncached = make(map[types.NamespacedName]uncachedPipeline)
+	}
+	for key, u := range q.uncached {
+		if now.Sub(u.created) > maxUncachedPipelineAge {
+			delete(q.uncached, key)
+		}
+	}
+	q.uncached[client.ObjectKeyFromObject(pipeline)] = uncachedPipeline{
+		policyUID: pipeline.Labels[policyUIDLabel],
+		created:   now,
+	}
+}
+
+func namespaceKey(namespace string) string {
+	return "namespace/" + namespace
+}
+
+// quotaReservation are the pipelines of a policy reserved below the active pipeline limits of
+// the policy and its namespace, that count towards the limits until they are created or the
+// reservation is released
+type quotaReservation struct {
+	s *Scheduler
+	// keys are the keys of the limits the pipelines are reserved for
+	keys  []string
+	count int
+}
+
+// created moves a reserved pipeline that was created to the pipelines
+// that count towards the limits until they are in the cache of the client
+func (r *quotaReservation) created(pipeline *ocularv1beta1.Pipeline) {
+	q := &r.s.quotas
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if r.count > 0 {
+		r.count--
+		for _, key := range r.keys {
+			q.reserved[key]--
+		}
+	}
+	q.trackUncached(pipeline, time.Now())
+}
+
+// release releases the reserved pipelines that were not created
+func (r *quotaReservation) release() {
+	q := &r.s.quotas
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, key := range r.keys {
+		q.reserved[key] -= r.count
+	}
+	r.count = 0
+}
+
+// reserveQuota returns the amount of the pipelines of the policy that can be created without
+// exceeding the active pipeline limit of the policy or its namespace, and reserves them until they
+// are created or the reservation is released. An error wrapping [ErrActivePipelineLimit] is returned
+// if not every pipeline can be created. Active pipelines are the pipelines created by the scheduler
+// that have not completed, including the pipelines that were created but are not in the cache yet.
+func (s *Scheduler) reserveQuota(ctx context.Context, p *chalkularv1beta1.ChalkReportPolicy, count int) (int, *quotaReservation, error) {
+	none := &quotaReservation{s: s}
+	if count == 0 {
+		return 0, none, nil
+	}
+
+	policyLimit := -1
+	if p.Spec.Limits != nil && p.Spec.Limits.MaxActivePipelines != nil {
+		policyLimit = int(*p.Spec.Limits.MaxActivePipelines)
+	}
+	namespaceLimit, err := s.namespaceQuota(ctx, p.Namespace)
+	if err != nil {
+		return 0, none, err
+	}
+	if policyLimit < 0 && namespaceLimit < 0 {
+		return count, none, nil
+	}
+
+	s.quotas.mu.Lock()
+	defer s.quotas.mu.Unlock()
+
+	list := &ocularv1beta1.PipelineList{}
+	if err := s.mgrClient.List(ctx, list,
+		client.InNamespace(p.Namespace),
+		client.MatchingLabels{schedulerLabel: schedulerValue},
+	); err != nil {
+		return 0, none, fmt.Errorf("unable to list pipelines to check active pipeline limits: %w", err)
+	}
+	var namespaceActive, policyActive int
+	listed := make(map[types.NamespacedName]struct{}, len(list.Items))
+	for _, pipeline := range list.Items {
+		listed[client.ObjectKeyFromObject(&pipeline)] = struct{}{}
+		if pipeline.Status.CompletionTime != nil {
+			continue
+		}
+		namespaceActive++
+		if pipeline.Labels[policyUIDLabel] == string(p.UID) {
+			policyActive++
+		}
+	}
+	now := time.Now()
+	for key, u := range s.quotas.uncached {
+		if _, ok := listed[key]; ok || now.Sub(u.created) > maxUncachedPipelineAge {
+			delete(s.quotas.uncached, key)
+			continue
+		}
+		if key.Namespace != p.Namespace {
+			continue
+		}
+		namespaceActive++
+		if u.policyUID == string(p.UID) {
+			policyActive++
+		}
+	}
+
+	pKey, nKey := policyKey(p.Namespace, p.Name), namespaceKey(p.Namespace)
+	allowed := count
+	var limitErr error
+	if policyLimit >= 0 {
+		if available := policyLimit - policyActive - s.quotas.reserved[pKey]; available < allowed {
+			allowed = max(available, 0)
+			limitErr = fmt.Errorf("%w: policy has %d active pipelines and a limit of %d",
+				ErrActivePipelineLimit, policyActive, policyLimit)
+		}
+	}
+	if namespaceLimit >= 0 {
+		if available := namespaceLimit - namespaceActive - s.quotas.reserved[nKey]; available < allowed {
+			allowed = max(available, 0)
+			limitErr = fmt.Errorf("%w: namespace %s has %d active pipelines and a limit of %d",
+				ErrActivePipelineLimit, p.Namespace, namespaceActive, namespaceLimit)
+		}
+	}
+
+	if s.quotas.reserved == nil {
+		s.quotas.reserved = make(map[string]int)
+	}
+	s.quotas.reserved[pKey] += allowed
+	s.quotas.reserved[nKey] += allowed
+	return allowed, &quotaReservation{s: s, keys: []string{pKey, nKey}, count: allowed}, limitErr
+}
+
+// namespaceQuota returns the maximum amount of active pipelines set by the annotation
+// of the namespace, or -1 if the namespace has no quota. An invalid annotation is logged and ignored.
+func (s *Scheduler) namespaceQuota(ctx context.Context, namespace string) (int, error) {
+	ns := &corev1.Namespace{}
+	if err := s.mgrClient.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return -1, nil
+		}
+		return -1, fmt.Errorf("unable to get namespace to check active pipeline quota: %w", err)
+	}
+
+	value, ok := ns.Annotations[namespaceQuotaAnnotation]
+	if !ok {
+		return -1, nil
+	}
+	quota, err := strconv.Atoi(value)
+	if err != nil || quota < 0 {
+		logf.FromContext(ctx).Info("ignoring invalid namespace quota annotation",
+			"namespace", namespace, "annotation", namespaceQuotaAnnotation, "value", value)
+		return -1, nil
+	}
+	return quota, nil
+}
@@ -0,0 +1,214 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// +kubebuilder:rbac:groups=chalk.ocular.crashoverride.run,resources=chalkreports,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=chalk.ocular.crashoverride.run,resources=chalkreports/status,verbs=get;update;patch
+
+const (
+	// maxRecordedReportSize is the maximum size of a compressed chalk report
+	// that will be stored in a [chalkularv1beta1.ChalkReport], to stay well
+	// below the size limit of a single resource in etcd
+	maxRecordedReportSize = 512 * 1024
+	// reportPruneInterval is how often recorded reports older than the history TTL are deleted
+	reportPruneInterval = 10 * time.Minute
+	// reportPrunePageSize is the amount of recorded reports listed at a time when pruning
+	reportPrunePageSize = 500
+)
+
+// newChalkReport returns the [chalkularv1beta1.ChalkReport] to record the received report with.
+// The report is stored compressed, unless it was read from S3 in which case a reference is stored instead.
+func newChalkReport(actionID string, report ReceivedReport) (*chalkularv1beta1.ChalkReport, error) {
+	record := &chalkularv1beta1.ChalkReport{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "chalkreport-",
+			Labels:       map[string]string{schedulerLabel: schedulerValue},
+		},
+		Spec: chalkularv1beta1.ChalkReportSpec{
+			ActionID:     actionID,
+			ReceivedTime: metav1.Now(),
+			Source: chalkularv1beta1.ChalkReportSource{
+				Method:    report.Source.Method,
+				User:      report.Source.HTTP.User,
+				QueueURL:  report.Source.SQS.QueueURL,
+				MessageID: report.Source.SQS.MessageID,
+			},
+		},
+	}
+
+	if len(validation.IsValidLabelValue(actionID)) == 0 {
+		record.Labels[chalkularv1beta1.ChalkReportActionIDLabel] = actionID
+	}
+	if name := strings.ToLower(actionID); len(validation.IsDNS1123Label(name)) == 0 {
+		record.GenerateName = name + "-"
+	}
+
+	if report.Source.S3.Bucket != "" {
+		record.Spec.ReportRef = &chalkularv1beta1.ChalkReportObjectReference{
+			Bucket: report.Source.S3.Bucket,
+			Key:    report.Source.S3.Key,
+		}
+		return record, nil
+	}
+
+	compressed, err := compressReport(report)
+	if err != nil {
+		return nil, err
+	}
+	if len(compressed) <= maxRecordedReportSize {
+		record.Spec.Report = compressed
+	}
+	return record, nil
+}
+
+func compressReport(report ReceivedReport) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(w).Encode(report.Report); err != nil {
+		return nil, fmt.Errorf("failed to encode chalk report: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress chalk report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// recordReport creates the [chalkularv1beta1.ChalkReport] for the report. Failing
+// to record a report is logged and does not stop pipelines from being created,
+// in which case nil is returned.
+func (s *Scheduler) recordReport(ctx context.Context, actionID string, report ReceivedReport) *chalkularv1beta1.ChalkReport {
+	l := logf.FromContext(ctx)
+
+	record, err := newChalkReport(actionID, report)
+	if err != nil {
+		l.Error(err, "unable to build chalk report record")
+		return nil
+	}
+	if record.Spec.ReportRef == nil && record.Spec.Report == nil {
+		l.Info("compressed chalk report is too large to be recorded, only recording the results")
+	}
+
+	if err := s.mgrClient.Create(ctx, record); err != nil {
+		l.Error(err, "unable to create chalk report record")
+		return nil
+	}
+	return record
+}
+
+// findReports returns the recorded reports with the action ID, oldest first. Reports are read
+// from the API server, since recorded reports are not cached. Only action IDs that are valid
+// label values can be found, otherwise [ErrInvalidActionID] is returned.
+func (s *Scheduler) findReports(ctx context.Context, actionID string) ([]chalkularv1beta1.ChalkReport, error) {
+	if errs := validation.IsValidLabelValue(actionID); actionID == "" || len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidActionID, strings.Join(errs, ", "))
+	}
+
+	list := &chalkularv1beta1.ChalkReportList{}
+	if err := s.apiReader.List(ctx, list, client.MatchingLabels{
+		chalkularv1beta1.ChalkReportActionIDLabel: actionID,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list chalk reports: %w", err)
+	}
+
+	slices.SortFunc(list.Items, func(a, b chalkularv1beta1.ChalkReport) int {
+		return a.Spec.ReceivedTime.Compare(b.Spec.ReceivedTime.Time)
+	})
+	return list.Items, nil
+}
+
+// updateReportStatus sets the status of the recorded report to the policy results.
+func (s *Scheduler) updateReportStatus(ctx context.Context, record *chalkularv1beta1.ChalkReport, results []chalkularv1beta1.ChalkReportPolicyResult) {
+	if record == nil {
+		return
+	}
+
+	now := metav1.Now()
+	record.Status = chalkularv1beta1.ChalkReportStatus{
+		Policies:      results,
+		ProcessedTime: &now,
+	}
+	if err := s.mgrClient.Status().Update(ctx, record); err != nil {
+		logf.FromContext(ctx).Error(err, "unable to update chalk report record status", "chalkreport", record.Name)
+	}
+}
+
+// pruneReportHistory deletes the recorded reports older than the history TTL
+// every [reportPruneInterval] until the context is done
+func (s *Scheduler) pruneReportHistory(ctx context.Context) {
+	ticker := time.NewTicker(reportPruneInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.pruneReports(ctx, time.Now()); err != nil {
+			logf.FromContext(ctx).Error(err, "unable to prune recorded reports")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pruneReports deletes the recorded reports created before the history TTL, returning the amount
+// deleted. Only the metadata of the reports is listed, since the reports themselves can be large.
+func (s *Scheduler) pruneReports(ctx context.Context, now time.Time) (int, error) {
+	cutoff := now.Add(-s.reportHistoryTTL)
+	var (
+		pruned int
+		next   string
+	)
+	for {
+		list := &metav1.PartialObjectMetadataList{}
+		list.SetGroupVersionKind(chalkularv1beta1.GroupVersion.WithKind("ChalkReportList"))
+		if err := s.apiReader.List(ctx, list,
+			client.MatchingLabels{schedulerLabel: schedulerValue},
+			client.Limit(reportPrunePageSize),
+			client.Continue(next),
+		); err != nil {
+			return pruned, fmt.Errorf("failed to list chalk reports: %w", err)
+		}
+		for i := range list.Items {
+			record := &list.Items[i]
+			if !record.CreationTimestamp.Time.Before(cutoff) {
+				continue
+			}
+			record.SetGroupVersionKind(chalkularv1beta1.GroupVersion.WithKind("ChalkReport"))
+			if err := s.mgrClient.Delete(ctx, record); client.IgnoreNotFound(err) != nil {
+				return pruned, fmt.Errorf("failed to delete chalk report %s: %w", record.Name, err)
+			}
+			pruned++
+		}
+		if next = list.Continue; next == "" {
+			break
+		}
+	}
+	if pruned > 0 {
+		schedulerReportsPruned.Add(float64(pruned))
+		logf.FromContext(ctx).Info(fmt.Sprintf("deleted %d recorded reports older than %s", pruned, s.reportHistoryTTL),
+			"pruned", pruned, "ttl", s.reportHistoryTTL)
+	}
+	return pruned, nil
+}
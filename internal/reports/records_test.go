@@ -0,0 +1,141 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"time"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	"github.com/crashappsec/chalkular/internal/policy"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("newChalkReport", func() {
+	report := chalk.Report{
+		"_ACTION_ID": "a1b2c3d4",
+		"_CHALKS":    []any{map[string]any{"CHALK_ID": "chalk-id"}},
+	}
+
+	It("should store the compressed report", func() {
+		record, err := newChalkReport("a1b2c3d4", ReceivedReport{
+			Report: report,
+			Source: policy.Source{
+				Method: policy.SourceMethodHTTP,
+				HTTP:   policy.HTTPSource{User: "uploader"},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(record.GenerateName).To(Equal("a1b2c3d4-"))
+		Expect(record.Labels).To(HaveKeyWithValue(chalkularv1beta1.ChalkReportActionIDLabel, "a1b2c3d4"))
+		Expect(record.Labels).To(HaveKeyWithValue(schedulerLabel, schedulerValue))
+		Expect(record.Spec.ActionID).To(Equal("a1b2c3d4"))
+		Expect(record.Spec.Source).To(Equal(chalkularv1beta1.ChalkReportSource{Method: "http", User: "uploader"}))
+		Expect(record.Spec.ReportRef).To(BeNil())
+
+		By("decompressing the stored report")
+		r, err := gzip.NewReader(bytes.NewReader(record.Spec.Report))
+		Expect(err).NotTo(HaveOccurred())
+		var decoded chalk.Report
+		Expect(json.NewDecoder(r).Decode(&decoded)).To(Succeed())
+		Expect(decoded).To(HaveKeyWithValue("_ACTION_ID", "a1b2c3d4"))
+	})
+
+	It("should store a reference for reports read from S3", func() {
+		record, err := newChalkReport("a1b2c3d4", ReceivedReport{
+			Report: report,
+			Source: policy.Source{
+				Method: policy.SourceMethodSQS,
+				SQS:    policy.SQSSource{QueueURL: "https://sqs.example.com/queue", MessageID: "msg-1"},
+				S3:     policy.S3Source{Bucket: "reports", Key: "report.json"},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(record.Spec.Report).To(BeNil())
+		Expect(record.Spec.ReportRef).To(Equal(&chalkularv1beta1.ChalkReportObjectReference{
+			Bucket: "reports",
+			Key:    "report.json",
+		}))
+		Expect(record.Spec.Source.MessageID).To(Equal("msg-1"))
+	})
+
+	It("should not use an invalid action ID as the name or label", func() {
+		record, err := newChalkReport("not a valid/name", ReceivedReport{Report: report})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(record.GenerateName).To(Equal("chalkreport-"))
+		Expect(record.Labels).NotTo(HaveKey(chalkularv1beta1.ChalkReportActionIDLabel))
+	})
+})
+
+var _ = Describe("findReports", func() {
+	It("should return the recorded reports with the action ID, oldest first", func(ctx SpecContext) {
+		newer := &chalkularv1beta1.ChalkReport{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "a1b2c3d4-newer",
+				Labels: map[string]string{chalkularv1beta1.ChalkReportActionIDLabel: "a1b2c3d4"},
+			},
+			Spec: chalkularv1beta1.ChalkReportSpec{ActionID: "a1b2c3d4", ReceivedTime: metav1.NewTime(time.Now())},
+		}
+		older := newer.DeepCopy()
+		older.Name = "a1b2c3d4-older"
+		older.Spec.ReceivedTime = metav1.NewTime(time.Now().Add(-time.Hour))
+		other := newer.DeepCopy()
+		other.Name = "e5f6a7b8"
+		other.Labels[chalkularv1beta1.ChalkReportActionIDLabel] = "e5f6a7b8"
+
+		s, _ := newTestScheduler(newer, older, other)
+		records, err := s.findReports(ctx, "a1b2c3d4")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records).To(HaveLen(2))
+		Expect(records[0].Name).To(Equal("a1b2c3d4-older"))
+		Expect(records[1].Name).To(Equal("a1b2c3d4-newer"))
+	})
+
+	It("should reject action IDs that are not valid label values", func(ctx SpecContext) {
+		s, _ := newTestScheduler()
+		_, err := s.findReports(ctx, "not a valid/label")
+		Expect(err).To(MatchError(ErrInvalidActionID))
+		_, err = s.findReports(ctx, "")
+		Expect(err).To(MatchError(ErrInvalidActionID))
+	})
+})
+
+var _ = Describe("pruneReports", func() {
+	It("should delete the recorded reports older than the history TTL", func(ctx SpecContext) {
+		now := time.Now()
+		newReport := func(name string, created time.Time) *chalkularv1beta1.ChalkReport {
+			return &chalkularv1beta1.ChalkReport{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              name,
+					Labels:            map[string]string{schedulerLabel: schedulerValue},
+					CreationTimestamp: metav1.NewTime(created),
+				},
+				Spec: chalkularv1beta1.ChalkReportSpec{ActionID: name, ReceivedTime: metav1.NewTime(created)},
+			}
+		}
+		s, c := newTestScheduler(
+			newReport("expired", now.Add(-2*time.Hour)),
+			newReport("retained", now.Add(-30*time.Minute)),
+		)
+		s.reportHistoryTTL = time.Hour
+
+		pruned, err := s.pruneReports(ctx, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pruned).To(Equal(1))
+
+		records := &chalkularv1beta1.ChalkReportList{}
+		Expect(c.List(ctx, records)).To(Succeed())
+		Expect(records.Items).To(ConsistOf(HaveField("Name", "retained")))
+	})
+})
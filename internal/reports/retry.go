@@ -0,0 +1,270 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// defaultCreateRetryBackoff is the delay before the first retry of creating
+	// a pipeline, if the scheduler is not configured with a backoff
+	defaultCreateRetryBackoff = time.Second
+	// maxCreateRetryBackoff is the maximum delay between retries of creating a pipeline
+	maxCreateRetryBackoff = 5 * time.Minute
+
+	abandonReasonPermanentError = "permanent-error"
+	abandonReasonMaxRetries     = "max-retries"
+)
+
+// retryPipeline is a pipeline that failed to be created with a
+// retryable error, to be created again once its backoff has passed
+type retryPipeline struct {
+	actionID string
+	// policyName and policyUID identify the policy that generated the pipeline,
+	// which is in the namespace of the pipeline. The pipeline is dropped if the
+	// policy is deleted before it is created.
+	policyName string
+	policyUID  types.UID
+	pipeline   *ocularv1beta1.Pipeline
+	// attempts is the amount of times creating the pipeline failed
+	attempts int
+	next     time.Time
+}
+
+// createRetries holds the pipelines waiting to be created again. Pipelines are
+// only kept in memory, so pipelines waiting for a retry are lost if the controller restarts.
+type createRetries struct {
+	maxRetries int
+	backoff    time.Duration
+	notify     chan struct{}
+
+	mu    sync.Mutex
+	items []retryPipeline
+}
+
+func newCreateRetries(maxRetries int, backoff time.Duration) *createRetries {
+	if backoff <= 0 {
+		backoff = defaultCreateRetryBackoff
+	}
+	return &createRetries{
+		maxRetries: max(maxRetries, 0),
+		backoff:    backoff,
+		notify:     make(chan struct{}, 1),
+	}
+}
+
+// delay returns the backoff after the failed attempts, which
+// doubles for each attempt up to [maxCreateRetryBackoff]
+func (r *createRetries) delay(attempts int) time.Duration {
+	return backoffDelay(r.backoff, maxCreateRetryBackoff, attempts)
+}
+
+// backoffDelay returns the delay after the failed attempts, starting
+// at the backoff and doubling for each attempt up to the maximum
+func backoffDelay(backoff, maxBackoff time.Duration, attempts int) time.Duration {
+	d := backoff
+	for range attempts - 1 {
+		if d >= maxBackoff {
+			break
+		}
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// add schedules the pipeline to be created again once the backoff of its attempts has passed
+func (r *createRetries) add(p retryPipeline, now time.Time) time.Duration {
+	delay := r.delay(p.attempts)
+	p.next = now.Add(delay)
+
+	r.mu.Lock()
+	r.items = append(r.items, p)
+	r.mu.Unlock()
+
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+	return delay
+}
+
+// due removes and returns the pipelines with a backoff that has passed, along with
+// the time the next remaining pipeline is due, which is zero if none remain
+func (r *createRetries) due(now time.Time) ([]retryPipeline, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		due  []retryPipeline
+		next time.Time
+	)
+	r.items = slices.DeleteFunc(r.items, func(p retryPipeline) bool {
+		if !p.next.After(now) {
+			due = append(due, p)
+			return true
+		}
+		if next.IsZero() || p.next.Before(next) {
+			next = p.next
+		}
+		return false
+	})
+	return due, next
+}
+
+// hasKey returns true if a pipeline waiting to be created again has the dedup key
+func (r *createRetries) hasKey(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.ContainsFunc(r.items, func(p retryPipeline) bool {
+		return p.pipeline.Labels[dedupKeyLabel] == key
+	})
+}
+
+// isRetryableCreateError returns true if creating a pipeline failed with an error that is
+// likely transient, such as a conflict, a timeout or an unavailable admission webhook
+// (returned as an internal error). Other errors returned by the API server, i.e. an invalid
+// spec or a forbidden request, are permanent. Errors without a status from the API server,
+// i.e. a refused connection, are retried.
+func isRetryableCreateError(err error) bool {
+	switch {
+	case apierrors.IsConflict(err),
+		// the name generated for the pipeline already exists
+		apierrors.IsAlreadyExists(err),
+		apierrors.IsServerTimeout(err),
+		apierrors.IsTimeout(err),
+		apierrors.IsTooManyRequests(err),
+		apierrors.IsServiceUnavailable(err),
+		apierrors.IsInternalError(err),
+		apierrors.IsUnexpectedServerError(err):
+		return true
+	case errors.Is(err, context.Canceled):
+		return false
+	}
+	var status apierrors.APIStatus
+	return !errors.As(err, &status)
+}
+
+// retryCreate schedules the pipeline that failed to be created with the error to be
+// created again, returning true if it was scheduled. The pipeline is abandoned if the
+// error is permanent, or creating it failed more than the maximum amount of retries.
+func (s *Scheduler) retryCreate(ctx context.Context, reportPolicy *chalkularv1beta1.ChalkReportPolicy, p retryPipeline, err error) bool {
+	l := logf.FromContext(ctx).WithValues("policy", p.policyName, "namespace", p.pipeline.Namespace,
+		"action-id", p.actionID, "attempts", p.attempts)
+
+	reason := abandonReasonPermanentError
+	if isRetryableCreateError(err) {
+		if p.attempts <= s.retries.maxRetries {
+			delay := s.retries.add(p, time.Now())
+			l.Info("retrying pipeline creation after backoff", "backoff", delay, "error", err.Error())
+			s.recorder.Eventf(reportPolicy, nil,
+				corev1.EventTypeWarning,
+				"FailedToCreatePipeline",
+				"CreatePipelineFromReport",
+				"failed to create pipeline for report '%s', retrying in %s (attempt %d/%d): %s",
+				p.actionID, delay, p.attempts, s.retries.maxRetries+1, err)
+			return true
+		}
+		reason = abandonReasonMaxRetries
+	}
+
+	l.Info("abandoning pipeline that failed to be created", "reason", reason, "error", err.Error())
+	schedulerPipelinesAbandoned.With(prometheus.Labels{
+		"policy":    p.policyName,
+		"namespace": p.pipeline.Namespace,
+		"reason":    reason,
+	}).Inc()
+	s.recorder.Eventf(reportPolicy, nil,
+		corev1.EventTypeWarning,
+		"FailedToCreatePipeline",
+		"CreatePipelineFromReport",
+		"failed to create pipeline for report '%s' after %d attempts, abandoning pipeline: %s", p.actionID, p.attempts, err)
+	return false
+}
+
+// retryPipelines creates the pipelines waiting to be created again until the context is done,
+// whenever the backoff of a pipeline has passed or a pipeline is scheduled to be retried
+func (s *Scheduler) retryPipelines(ctx context.Context) {
+	timer := time.NewTimer(maxCreateRetryBackoff)
+	defer timer.Stop()
+	for {
+		due, next := s.retries.due(time.Now())
+		for _, p := range due {
+			if ctx.Err() != nil {
+				return
+			}
+			s.createRetry(ctx, p)
+		}
+
+		wait := maxCreateRetryBackoff
+		if !next.IsZero() {
+			wait = time.Until(next)
+		}
+		timer.Reset(wait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-s.retries.notify:
+		}
+	}
+}
+
+// createRetry creates the pipeline again if its policy still exists, scheduling
+// another retry if it fails to be created with a retryable error. The pipeline
+// was already admitted by the active pipeline threshold and limits, so they
+// are not checked again.
+func (s *Scheduler) createRetry(ctx context.Context, p retryPipeline) {
+	l := logf.FromContext(ctx).WithValues("policy", p.policyName, "namespace", p.pipeline.Namespace,
+		"action-id", p.actionID, "attempts", p.attempts)
+
+	reportPolicy := &chalkularv1beta1.ChalkReportPolicy{}
+	err := s.mgrClient.Get(ctx, client.ObjectKey{Namespace: p.pipeline.Namespace, Name: p.policyName}, reportPolicy)
+	if apierrors.IsNotFound(err) || (err == nil && reportPolicy.UID != p.policyUID) {
+		l.Info("policy of pipeline to retry was deleted, dropping pipeline")
+		return
+	} else if err != nil {
+		l.Error(err, "unable to get policy of pipeline to retry")
+		s.retries.add(p, time.Now())
+		return
+	}
+
+	pipeline := p.pipeline.DeepCopy()
+	if err := s.mgrClient.Create(ctx, pipeline); err != nil {
+		l.Error(err, "unable to create pipeline on retry")
+		p.attempts++
+		s.retryCreate(ctx, reportPolicy, p, err)
+		return
+	}
+
+	schedulerPipelinesCreated.With(prometheus.Labels{"profile": pipeline.Spec.ProfileRef.Name, "policy": p.policyName, "namespace": pipeline.Namespace}).Inc()
+	s.recordCreated(ctx, pipeline, s.dedupWindowFor(reportPolicy))
+	// the pipeline was not reserved, but counts towards the threshold
+	// and active pipeline limits until it is in the cache
+	(&thresholdReservation{s: s}).created(pipeline)
+	(&quotaReservation{s: s}).created(pipeline)
+	s.recorder.Eventf(reportPolicy, nil,
+		corev1.EventTypeNormal,
+		"RetriedPipelineCreated",
+		"CreatePipelineFromReport",
+		"report '%s' created pipeline %s after %d attempts", p.actionID, pipeline.Name, p.attempts+1)
+}
@@ -0,0 +1,141 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	"github.com/crashappsec/chalkular/internal/policy"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var pipelineResource = schema.GroupResource{Group: "ocular.crashoverride.run", Resource: "pipelines"}
+
+// failPipelineCreates makes creating pipelines with the scheduler fail with the errors
+// in order, after which pipelines are created as usual. A nil error creates the pipeline.
+func failPipelineCreates(s *Scheduler, c client.Client, errs ...error) {
+	s.mgrClient = interceptor.NewClient(c.(client.WithWatch), interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if _, ok := obj.(*ocularv1beta1.Pipeline); ok && len(errs) > 0 {
+				err := errs[0]
+				errs = errs[1:]
+				if err != nil {
+					return err
+				}
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	})
+}
+
+var _ = Describe("Pipeline create retries", func() {
+	It("should only retry transient errors", func() {
+		Expect(isRetryableCreateError(apierrors.NewConflict(pipelineResource, "p", errors.New("conflict")))).To(BeTrue())
+		Expect(isRetryableCreateError(apierrors.NewServerTimeout(pipelineResource, "create", 1))).To(BeTrue())
+		Expect(isRetryableCreateError(apierrors.NewTooManyRequests("slow down", 1))).To(BeTrue())
+		Expect(isRetryableCreateError(apierrors.NewInternalError(errors.New("failed calling webhook")))).To(BeTrue())
+		Expect(isRetryableCreateError(errors.New("connection refused"))).To(BeTrue())
+
+		Expect(isRetryableCreateError(apierrors.NewInvalid(
+			schema.GroupKind{Group: "ocular.crashoverride.run", Kind: "Pipeline"}, "p",
+			field.ErrorList{field.Required(field.NewPath("spec", "profileRef"), "")}))).To(BeFalse())
+		Expect(isRetryableCreateError(apierrors.NewForbidden(pipelineResource, "p", errors.New("denied")))).To(BeFalse())
+		Expect(isRetryableCreateError(context.Canceled)).To(BeFalse())
+	})
+
+	It("should double the backoff for each attempt up to the maximum", func() {
+		r := newCreateRetries(10, time.Second)
+		Expect(r.delay(1)).To(Equal(time.Second))
+		Expect(r.delay(2)).To(Equal(2 * time.Second))
+		Expect(r.delay(4)).To(Equal(8 * time.Second))
+		Expect(r.delay(20)).To(Equal(maxCreateRetryBackoff))
+	})
+
+	It("should create a pipeline that failed with a retryable error after the backoff", func(ctx SpecContext) {
+		s, c := newTestScheduler(newTestPolicy("scans", "images"))
+		s.retries = newCreateRetries(3, time.Second)
+		failPipelineCreates(s, c, apierrors.NewServerTimeout(pipelineResource, "create", 1))
+
+		results, err := processTestReports(ctx, s, ReceivedReport{Report: chalk.Report{"_ACTION_ID": "a1b2c3d4"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results[0].Policies).To(ConsistOf(And(
+			HaveField("RetryingPipelines", 1),
+			HaveField("Pipelines", BeEmpty()),
+			HaveField("Error", BeEmpty()),
+		)))
+
+		By("deduplicating the pipeline while it is waiting to be retried")
+		Expect(s.retries.hasKey(dedupKey("uid-images", "a1b2c3d4", policy.PipelineValues{
+			Target: ocularv1beta1.Target{Identifier: "a1b2c3d4", Version: "latest"},
+		}))).To(BeTrue())
+
+		due, next := s.retries.due(time.Now())
+		Expect(due).To(BeEmpty())
+		Expect(next).NotTo(BeZero())
+
+		due, _ = s.retries.due(next)
+		Expect(due).To(HaveLen(1))
+		s.createRetry(ctx, due[0])
+
+		pipelines := &ocularv1beta1.PipelineList{}
+		Expect(c.List(ctx, pipelines)).To(Succeed())
+		Expect(pipelines.Items).To(HaveLen(1))
+	})
+
+	It("should abandon a pipeline that failed with a permanent error", func(ctx SpecContext) {
+		s, c := newTestScheduler(newTestPolicy("scans", "images"))
+		s.retries = newCreateRetries(3, time.Second)
+		failPipelineCreates(s, c, apierrors.NewForbidden(pipelineResource, "", errors.New("denied")))
+
+		results, err := processTestReports(ctx, s, ReceivedReport{Report: chalk.Report{"_ACTION_ID": "a1b2c3d4"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results[0].Policies).To(ConsistOf(And(
+			HaveField("RetryingPipelines", 0),
+			HaveField("Error", ContainSubstring("failed to create 1/1 pipelines")),
+		)))
+		due, next := s.retries.due(time.Now().Add(maxCreateRetryBackoff))
+		Expect(due).To(BeEmpty())
+		Expect(next).To(BeZero())
+	})
+
+	It("should abandon a pipeline once it exceeds the maximum retries", func(ctx SpecContext) {
+		s, c := newTestScheduler(newTestPolicy("scans", "images"))
+		s.retries = newCreateRetries(1, time.Second)
+		failPipelineCreates(s, c,
+			apierrors.NewConflict(pipelineResource, "", errors.New("conflict")),
+			apierrors.NewConflict(pipelineResource, "", errors.New("conflict")),
+		)
+
+		results, err := processTestReports(ctx, s, ReceivedReport{Report: chalk.Report{"_ACTION_ID": "a1b2c3d4"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results[0].Policies).To(ConsistOf(HaveField("RetryingPipelines", 1)))
+
+		due, _ := s.retries.due(time.Now().Add(maxCreateRetryBackoff))
+		Expect(due).To(HaveLen(1))
+		s.createRetry(ctx, due[0])
+
+		due, next := s.retries.due(time.Now().Add(maxCreateRetryBackoff))
+		Expect(due).To(BeEmpty())
+		Expect(next).To(BeZero())
+
+		pipelines := &ocularv1beta1.PipelineList{}
+		Expect(c.List(ctx, pipelines)).To(Succeed())
+		Expect(pipelines.Items).To(BeEmpty())
+	})
+})
@@ -12,6 +12,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
@@ -20,6 +23,7 @@ import (
 	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
 	"github.com/prometheus/client_golang/prometheus"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/events"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -28,11 +32,18 @@ import (
 )
 
 const (
-	schedulerLabel = "chalk.ocular.crashoverride.run/scheduled-by"
-	schedulerValue = "chalkular-controller"
+	schedulerLabel = chalkularv1beta1.PipelineScheduledByLabel
+	schedulerValue = chalkularv1beta1.PipelineScheduledByValue
 )
 
 var (
+	schedulerPipelinesAudited = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scheduler_pipelines_audited",
+			Help: "Total number of pipelines not created, since the policy that generated them is in audit mode",
+		},
+		[]string{"policy", "namespace"},
+	)
 	schedulerPipelinesCreated = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "scheduler_pipelines_created",
@@ -58,6 +69,66 @@ var (
 			Help: "Total number of pipelines created",
 		},
 	)
+	schedulerPolicyEvalBudgetExceeded = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scheduler_policy_eval_budget_exceeded",
+			Help: "Total number of policy evaluations that exceeded the CEL cost limit or timeout",
+		},
+		[]string{"policy", "namespace"},
+	)
+	schedulerDuplicatePipelinesSkipped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scheduler_duplicate_pipelines_skipped",
+			Help: "Total number of pipelines not created, since a pipeline with the same dedup key exists within the dedup window",
+		},
+		[]string{"policy", "namespace"},
+	)
+	schedulerDuplicateReportsSkipped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scheduler_duplicate_reports_skipped",
+			Help: "Total number of reports a policy did not create pipelines for, since they were already created within the dedup window",
+		},
+		[]string{"policy", "namespace"},
+	)
+	schedulerPipelinesOverLimit = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scheduler_pipelines_over_limit",
+			Help: "Total number of pipelines not created, since the active pipeline limit of the policy or namespace was reached",
+		},
+		[]string{"policy", "namespace"},
+	)
+	schedulerPipelinesDeferred = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scheduler_pipelines_deferred",
+			Help: "Total number of pipelines added to the backlog, since the active pipeline threshold or limits were reached",
+		},
+		[]string{"policy", "namespace"},
+	)
+	schedulerPipelinesAbandoned = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scheduler_pipelines_abandoned",
+			Help: "Total number of pipelines not created, since creating them failed with a permanent error or exceeded the maximum retries",
+		},
+		[]string{"policy", "namespace", "reason"},
+	)
+	schedulerReportsPruned = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "scheduler_reports_pruned",
+			Help: "Total number of recorded reports deleted, since they were older than the report history TTL",
+		},
+	)
+	schedulerQueueItemRetries = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "scheduler_queue_item_retries",
+			Help: "Total number of times queued reports were processed again, since they could not be processed",
+		},
+	)
+	schedulerPendingPipelines = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "scheduler_pending_pipelines",
+			Help: "Current number of pipelines in the backlog waiting to be created",
+		},
+	)
 	schedulerEventProcessingDurationSeconds = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Name: "scheduler_event_processing_duration_seconds",
@@ -69,28 +140,104 @@ var (
 func init() {
 	// Register custom metrics with the global prometheus registry
 	metrics.Registry.MustRegister(
+		schedulerDuplicatePipelinesSkipped,
+		schedulerDuplicateReportsSkipped,
 		schedulerEventProcessingDurationSeconds,
 		schedulerEventsRecieved,
+		schedulerPendingPipelines,
+		schedulerPipelinesAbandoned,
+		schedulerPipelinesAudited,
 		schedulerPipelinesCreated,
+		schedulerPipelinesDeferred,
+		schedulerPipelinesOverLimit,
+		schedulerPolicyEvalBudgetExceeded,
+		schedulerQueueItemRetries,
 		schedulerReportErrors,
+		schedulerReportsPruned,
 		schedulerReportsRecieved,
 	)
 }
 
+// SchedulerOptions configures a [Scheduler]
+type SchedulerOptions struct {
+	// RejectPipelineThreshold is the amount of active pipelines at which
+	// reports are rejected. 0 or less indicates no threshold.
+	RejectPipelineThreshold int
+	// MaxPipelinesPerPolicy is the maximum amount of pipelines a
+	// policy can create for a report. 0 or less indicates no maximum.
+	MaxPipelinesPerPolicy int
+	// Workers is the amount of queued items processed concurrently.
+	// Defaults to 1 if not positive.
+	Workers int
+	// DedupWindow is the duration after a policy creates a pipeline that pipelines
+	// with the same dedup key are skipped, for policies that do not set their own
+	// window. 0 or less disables skipping duplicate pipelines.
+	DedupWindow time.Duration
+	// Backlog stores the pipelines that are deferred once the active pipeline
+	// threshold or limits are reached, to be created as capacity frees up.
+	// If nil, reports are rejected at the threshold and pipelines over a limit are skipped.
+	Backlog BacklogStore
+	// MaxPendingPipelines is the maximum amount of pipelines in the backlog, after
+	// which reports are rejected. 0 or less disables the backlog.
+	MaxPendingPipelines int
+	// CreateRetries is the maximum amount of times creating a pipeline is retried after
+	// a retryable error, such as a conflict or timeout. 0 or less disables retries.
+	CreateRetries int
+	// CreateRetryBackoff is the delay before the first retry of creating a pipeline, which
+	// doubles for each retry up to 5 minutes. Defaults to 1 second if not positive.
+	CreateRetryBackoff time.Duration
+	// ReportHistoryTTL is the duration after a report is recorded that its
+	// ChalkReport is deleted. 0 or less keeps recorded reports forever.
+	ReportHistoryTTL time.Duration
+}
+
 type Scheduler struct {
-	eventBus                eventBus
+	queue                   Queue
 	rejectPipelineThreshold int
 	maxPipelinesPerPolicy   int
+	workers                 int
+	dedupWindow             time.Duration
+	reportHistoryTTL        time.Duration
 
 	mgrClient client.Client
+	apiReader client.Reader
 	recorder  events.EventRecorder
 
 	policyCompiler *policy.Compiler
-}
 
-func NewScheduler(mgr manager.Manager, policyCompiler *policy.Compiler, rejectPipelineThreshold, maxPipelinesPerPolicy int) (*Scheduler, error) {
-	e := make(eventBus)
+	// waiters are the results of the queued items
+	// that were enqueued since the scheduler started
+	waitersMu sync.Mutex
+	waiters   map[string]chan SchedulerResult
+
+	sequencer sequencer
+
+	// reserved is the amount of pipelines being created by
+	// workers, that count towards the pipeline threshold
+	reservedMu sync.Mutex
+	reserved   int
+	// uncached are the pipelines created by the scheduler that
+	// are not in the cache of the client yet, by the time created
+	uncached map[types.NamespacedName]time.Time
+
+	recent recentKeys
+	quotas quotaReservations
+
+	// backlog is nil if deferring pipelines is disabled
+	backlog *pipelineBacklog
+
+	retries *createRetries
+
+	// itemRetryBackoff is the delay before processing a queued
+	// item again, after it could not be processed
+	itemRetryBackoff time.Duration
+	// itemAttempts are the amount of times the queued items
+	// without a waiter could not be processed
+	itemAttemptsMu sync.Mutex
+	itemAttempts   map[string]int
+}
 
+func NewScheduler(mgr manager.Manager, policyCompiler *policy.Compiler, queue Queue, opts SchedulerOptions) (*Scheduler, error) {
 	if err := mgr.GetFieldIndexer().IndexField(
 		context.Background(),
 		&ocularv1beta1.Pipeline{},
@@ -101,15 +248,27 @@ func NewScheduler(mgr manager.Manager, policyCompiler *policy.Compiler, rejectPi
 	}
 
 	scheduler := &Scheduler{
-		eventBus: e,
+		queue:   queue,
+		waiters: make(map[string]chan SchedulerResult),
 
-		maxPipelinesPerPolicy:   maxPipelinesPerPolicy,
-		rejectPipelineThreshold: rejectPipelineThreshold,
+		maxPipelinesPerPolicy:   opts.MaxPipelinesPerPolicy,
+		rejectPipelineThreshold: opts.RejectPipelineThreshold,
+		workers:                 max(opts.Workers, 1),
+		dedupWindow:             opts.DedupWindow,
+		reportHistoryTTL:        opts.ReportHistoryTTL,
 
 		policyCompiler: policyCompiler,
 
 		mgrClient: mgr.GetClient(),
+		apiReader: mgr.GetAPIReader(),
 		recorder:  mgr.GetEventRecorder("chalkular-report-scheduler"),
+
+		retries:          newCreateRetries(opts.CreateRetries, opts.CreateRetryBackoff),
+		itemRetryBackoff: defaultItemRetryBackoff,
+		itemAttempts:     make(map[string]int),
+	}
+	if opts.Backlog != nil && opts.MaxPendingPipelines > 0 {
+		scheduler.backlog = newPipelineBacklog(opts.Backlog, opts.MaxPendingPipelines)
 	}
 
 	return scheduler, nil
@@ -117,111 +276,549 @@ func NewScheduler(mgr manager.Manager, policyCompiler *policy.Compiler, rejectPi
 
 func (s *Scheduler) GetClient() SchedulerClient {
 	return &schedulerClient{
-		eventBus: s.eventBus,
+		scheduler: s,
+	}
+}
+
+const (
+	// defaultItemRetryBackoff is the delay before the first time a
+	// queued item that could not be processed is processed again
+	defaultItemRetryBackoff = time.Second
+	// maxItemRetryBackoff is the maximum delay between processing a queued item again
+	maxItemRetryBackoff = time.Minute
+)
+
+var (
+	ErrPipelineThreshold   = errors.New("rejecting report, active pipeline count at or above threshold")
+	ErrActivePipelineLimit = errors.New("active pipeline limit reached")
+	ErrInvalidReport       = errors.New("invalid chalk report")
+	ErrInvalidActionID     = errors.New("invalid action ID")
+)
+
+// thresholdReservation are the pipelines reserved below the active pipeline threshold,
+// that count towards the threshold until they are created or the reservation is released
+type thresholdReservation struct {
+	s     *Scheduler
+	count int
+}
+
+// created moves a reserved pipeline that was created to the pipelines
+// that count towards the threshold until they are in the cache of the client
+func (r *thresholdReservation) created(pipeline *ocularv1beta1.Pipeline) {
+	if r.s.rejectPipelineThreshold <= 0 {
+		return
+	}
+	r.s.reservedMu.Lock()
+	defer r.s.reservedMu.Unlock()
+	if r.count > 0 {
+		r.count--
+		r.s.reserved--
+	}
+	r.s.trackUncached(pipeline)
+}
+
+// release releases the reserved pipelines that were not created
+func (r *thresholdReservation) release() {
+	r.s.reservedMu.Lock()
+	defer r.s.reservedMu.Unlock()
+	r.s.reserved -= r.count
+	r.count = 0
+}
+
+// reservePipelines checks the active pipeline threshold, and reserves the pipelines that are
+// about to be created until they are created or the reservation is released. Reserved pipelines
+// count towards the threshold, so that workers cannot exceed the threshold together.
+func (s *Scheduler) reservePipelines(ctx context.Context, count int) (*thresholdReservation, error) {
+	if s.rejectPipelineThreshold <= 0 || count == 0 {
+		return &thresholdReservation{s: s}, nil
+	}
+
+	s.reservedMu.Lock()
+	defer s.reservedMu.Unlock()
+
+	active, err := s.countActivePipelines(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list active pipelines: %w", err)
+	}
+	if active+s.reserved >= s.rejectPipelineThreshold {
+		logf.FromContext(ctx).Info("rejecting reports, pipeline threshold hit")
+		return nil, fmt.Errorf("%w: currently %d active and %d being created which exceeds threshold of %d, rejecting event",
+			ErrPipelineThreshold, active, s.reserved, s.rejectPipelineThreshold)
 	}
+
+	s.reserved += count
+	return &thresholdReservation{s: s, count: count}, nil
 }
 
-var ErrPipelineThreshold = errors.New("rejecting report, active pipeline count at or above threshold")
+// reserveThreshold reserves up to count pipelines that can be created below the active pipeline
+// threshold until they are created or the reservation is released, returning the amount reserved.
+// Active pipelines are counted with the 'status.active' index, along with the pipelines reserved
+// by workers and the pipelines created that are not in the cache yet.
+func (s *Scheduler) reserveThreshold(ctx context.Context, count int) (int, *thresholdReservation, error) {
+	if s.rejectPipelineThreshold <= 0 || count == 0 {
+		return count, &thresholdReservation{s: s}, nil
+	}
+
+	s.reservedMu.Lock()
+	defer s.reservedMu.Unlock()
+
+	active, err := s.countActivePipelines(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("unable to list active pipelines: %w", err)
+	}
+	allowed := min(max(s.rejectPipelineThreshold-active-s.reserved, 0), count)
 
+	s.reserved += allowed
+	return allowed, &thresholdReservation{s: s, count: allowed}, nil
+}
+
+// sequencedQueueItem is a queued item handed to a worker
+type sequencedQueueItem struct {
+	QueueItem
+	sequenced *sequencedItem
+}
+
+// Start pops items from the queue and processes them with a pool of
+// workers. Items are popped in order, and the pipelines for items that
+// share an action ID or policy are created in the order they were queued.
 func (s *Scheduler) Start(ctx context.Context) error {
 	l := logf.FromContext(ctx)
 
+	var workers sync.WaitGroup
+	if s.backlog != nil {
+		if err := s.backlog.load(ctx); err != nil {
+			return fmt.Errorf("unable to load pending pipelines: %w", err)
+		}
+		workers.Go(func() { s.drainBacklog(ctx) })
+	}
+	workers.Go(func() { s.retryPipelines(ctx) })
+	workers.Go(func() { s.pruneDedupLeases(ctx) })
+	if s.reportHistoryTTL > 0 {
+		workers.Go(func() { s.pruneReportHistory(ctx) })
+	}
+
+	items := make(chan sequencedQueueItem)
+	for range s.workers {
+		workers.Go(func() {
+			for item := range items {
+				s.processItem(ctx, item)
+			}
+		})
+	}
+	defer func() {
+		close(items)
+		workers.Wait()
+	}()
+
 	for {
+		item, err := s.queue.Pop(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		} else if err != nil {
+			l.Error(err, "unable to read reports from queue")
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		sequenced := s.sequencer.add()
 		select {
 		case <-ctx.Done():
+			s.sequencer.finish(sequenced)
 			return ctx.Err()
-		case e := <-s.eventBus:
-			l.Info("chalk reports received, scheduling")
-			schedulerEventsRecieved.Inc()
-			schedulerReportsRecieved.Add(float64(len(e.Reports)))
-			start := time.Now()
-			err := s.processReports(ctx, e.Reports)
-			e.Result <- err
-			close(e.Result)
-			duration := time.Since(start)
-			schedulerEventProcessingDurationSeconds.Observe(duration.Seconds())
-			if err != nil {
-				schedulerReportErrors.Add(1)
-			}
-			time.Sleep(time.Second)
+		case items <- sequencedQueueItem{QueueItem: item, sequenced: sequenced}:
 		}
 	}
 }
 
-func (s *Scheduler) processReports(ctx context.Context, reports []chalk.Report) error {
-	l := logf.FromContext(ctx)
+func (s *Scheduler) processItem(ctx context.Context, item sequencedQueueItem) {
+	l := logf.FromContext(ctx).WithValues("queue-item", item.ID)
+	ctx = logf.IntoContext(ctx, l)
+
 	l.Info("chalk reports received, scheduling")
+	schedulerEventsRecieved.Inc()
+	schedulerReportsRecieved.Add(float64(len(item.Reports)))
+	start := time.Now()
+	results, err := s.processReports(ctx, item.sequenced, item.Reports)
+	s.sequencer.finish(item.sequenced)
+	duration := time.Since(start)
+	schedulerEventProcessingDurationSeconds.Observe(duration.Seconds())
+	if ctx.Err() != nil {
+		// leave the item in the queue to be processed after restarting
+		return
+	}
+	if err != nil {
+		schedulerReportErrors.Add(1)
+		// the waiter enqueues the reports again (i.e. the SQS message is received again),
+		// so the item is removed. Otherwise the item is processed again after a backoff.
+		if s.sendResult(item.ID, SchedulerResult{Err: err}) {
+			l.Error(err, "unable to process reports, returning error to waiter")
+			s.ackItem(ctx, item.ID)
+			return
+		}
+		s.requeueItem(ctx, item.ID, err)
+		return
+	}
 
-	if s.rejectPipelineThreshold > 0 {
-		active, err := s.countActivePipelines(ctx)
-		if err != nil {
-			return fmt.Errorf("unable to list active pipelines: %w", err)
+	s.sendResult(item.ID, SchedulerResult{Reports: results})
+	for _, r := range results {
+		if r.Err != nil {
+			schedulerReportErrors.Add(1)
+		}
+	}
+	s.ackItem(ctx, item.ID)
+}
+
+// ackItem removes the processed item from the queue
+func (s *Scheduler) ackItem(ctx context.Context, id string) {
+	s.itemAttemptsMu.Lock()
+	delete(s.itemAttempts, id)
+	s.itemAttemptsMu.Unlock()
+
+	if err := s.queue.Ack(ctx, id); err != nil {
+		logf.FromContext(ctx).Error(err, "unable to remove processed reports from queue")
+	}
+}
+
+// requeueItem returns the item without a waiter that could not be processed to the queue
+// once the backoff of its attempts has passed, without holding a worker in the meantime.
+// Items for the same action IDs or policies that were queued later may be processed first.
+func (s *Scheduler) requeueItem(ctx context.Context, id string, err error) {
+	s.itemAttemptsMu.Lock()
+	s.itemAttempts[id]++
+	attempts := s.itemAttempts[id]
+	s.itemAttemptsMu.Unlock()
+
+	schedulerQueueItemRetries.Inc()
+	delay := backoffDelay(s.itemRetryBackoff, maxItemRetryBackoff, attempts)
+	l := logf.FromContext(ctx)
+	l.Error(err, "unable to process reports, processing again after backoff", "backoff", delay, "attempts", attempts)
+	time.AfterFunc(delay, func() {
+		if ctx.Err() != nil {
+			return
 		}
-		if active >= s.rejectPipelineThreshold {
-			l.Info("rejecting reports, pipeline threshold hit")
-			return fmt.Errorf("%w: currently %d active which exceeds threshold of %d, rejecting event",
-				ErrPipelineThreshold, active, s.rejectPipelineThreshold)
+		if err := s.queue.Requeue(ctx, id); err != nil {
+			l.Error(err, "unable to requeue reports")
 		}
+	})
+}
+
+// sendResult sends the result of processing the queued item to its waiter, returning
+// false if there is none. Items recovered from a durable queue have no waiter.
+func (s *Scheduler) sendResult(id string, result SchedulerResult) bool {
+	s.waitersMu.Lock()
+	waiter, ok := s.waiters[id]
+	delete(s.waiters, id)
+	s.waitersMu.Unlock()
+
+	if ok {
+		waiter <- result
+		close(waiter)
 	}
+	return ok
+}
+
+// processedReport is a report that passed validation, along
+// with the results of evaluating the policies against it
+type processedReport struct {
+	// index is the index of the report in the queued item
+	index     int
+	actionID  string
+	report    ReceivedReport
+	generated []policyGeneratedPipelines
+	// audited are the pipelines generated by policies in audit
+	// mode, which are only recorded and never created
+	audited []policyGeneratedPipelines
+	failed  []failedPolicy
+}
+
+// processReports evaluates the policies against the reports and creates the pipelines, returning
+// the result of each report. Reports that are invalid are skipped and have their error set
+// in the result. An error is returned if none of the reports could be processed, in which case
+// no events are recorded and no pipelines are created, so the reports can be processed again.
+func (s *Scheduler) processReports(ctx context.Context, sequenced *sequencedItem, reports []ReceivedReport) ([]ReportResult, error) {
+	l := logf.FromContext(ctx)
+	l.Info("chalk reports received, scheduling")
 
 	policies := &chalkularv1beta1.ChalkReportPolicyList{}
 	if err := s.mgrClient.List(ctx, policies); err != nil {
-		return fmt.Errorf("unable to list chalk report policies: %w", err)
+		return nil, fmt.Errorf("unable to list chalk report policies: %w", err)
 	}
+	policy.SortByPriority(policies.Items)
 
 	// group generated pipelines by report + policy
 	// so that we can write events to policies if templated pipeline
 	// fails to be created
-	var generatedPipelines []policyGeneratedPipelines
-	for _, report := range reports {
-		actionID, exist := report[chalk.KeyActionID]
+	var (
+		processed []processedReport
+		keys      []string
+		count     int
+		results   = make([]ReportResult, len(reports))
+	)
+	for i, report := range reports {
+		actionID, exist := report.Report[chalk.KeyActionID]
 		actionIDStr, valid := actionID.(string)
 		if !exist || !valid {
-			l.Error(fmt.Errorf("missing or invalid key \"%s\" found in report", chalk.KeyActionID), "action ID string was not found for report")
-			return fmt.Errorf("invalid chalk report, missing or invalid key %s found", chalk.KeyActionID)
+			err := fmt.Errorf("%w, missing or invalid key %s found", ErrInvalidReport, chalk.KeyActionID)
+			l.Error(err, "action ID string was not found for report, skipping", "index", i)
+			results[i].Err = err
+			continue
 		}
+		results[i].ActionID = actionIDStr
 		reportL := l.WithValues("action-id", actionID)
 		reportCtx := logf.IntoContext(ctx, reportL)
 
-		generated := s.createPipelinesForReport(reportCtx, policies.Items, actionIDStr, report)
-		generatedPipelines = append(generatedPipelines, generated...)
+		generated, failed := s.createPipelinesForReport(reportCtx, policies.Items, actionIDStr, report)
+		var audited []policyGeneratedPipelines
+		generated = slices.DeleteFunc(generated, func(g policyGeneratedPipelines) bool {
+			if g.policy.Spec.Mode == chalkularv1beta1.PolicyModeAudit {
+				audited = append(audited, g)
+				return true
+			}
+			return false
+		})
+		processed = append(processed, processedReport{
+			index:     i,
+			actionID:  actionIDStr,
+			report:    report,
+			generated: generated,
+			audited:   audited,
+			failed:    failed,
+		})
+		keys = append(keys, actionKey(actionIDStr))
+		for _, g := range generated {
+			keys = append(keys, policyKey(g.policy.Namespace, g.policy.Name))
+		}
+	}
+
+	// wait for earlier reports with the same action ID or
+	// policies to be created, so pipelines are created in order
+	if err := s.sequencer.wait(ctx, sequenced, keys); err != nil {
+		return nil, err
+	}
+
+	// skip the pipelines with a dedup key that already has a pipeline within the
+	// dedup window, including pipelines of reports uploaded more than once in this batch,
+	// then skip the pipelines that would exceed the active pipeline limits of the policy
+	defer func() {
+		for _, p := range processed {
+			for _, g := range p.generated {
+				if g.quota != nil {
+					g.quota.release()
+				}
+			}
+		}
+	}()
+	batchKeys := make(map[string]struct{})
+	for _, p := range processed {
+		for i := range p.generated {
+			g := &p.generated[i]
+			if window := s.dedupWindowFor(&g.policy); window > 0 {
+				// keys identify a single pipeline, so are added to the batch right away to
+				// also skip the other pipelines of the report for the same extracted key
+				g.pipelines = slices.DeleteFunc(g.pipelines, func(pipeline *ocularv1beta1.Pipeline) bool {
+					key := pipeline.Labels[dedupKeyLabel]
+					if _, inBatch := batchKeys[key]; inBatch || s.isDuplicate(ctx, g.policy.Namespace, key, window) {
+						g.skipped++
+						return true
+					}
+					batchKeys[key] = struct{}{}
+					return false
+				})
+			}
+
+			allowed, quota, err := s.reserveQuota(ctx, &g.policy, len(g.pipelines))
+			g.quota = quota
+			if err != nil {
+				if s.backlog != nil && errors.Is(err, ErrActivePipelineLimit) {
+					g.deferred = append(g.deferred, g.pipelines[allowed:]...)
+				} else {
+					g.overLimit = len(g.pipelines) - allowed
+					g.limitErr = err
+				}
+				g.pipelines = g.pipelines[:allowed]
+			}
+			count += len(g.pipelines)
+		}
+	}
+
+	reservation := &thresholdReservation{s: s}
+	if s.backlog != nil {
+		// pipelines are only created directly if none are pending,
+		// so that pending pipelines are created first
+		var allowed int
+		if s.backlog.len() == 0 {
+			reserved, r, err := s.reserveThreshold(ctx, count)
+			if err != nil {
+				return nil, err
+			}
+			defer r.release()
+			allowed, reservation = reserved, r
+		}
+		// the pipelines over the threshold are deferred in the order of the reports
+		for _, p := range processed {
+			for i := range p.generated {
+				g := &p.generated[i]
+				n := min(allowed, len(g.pipelines))
+				allowed -= n
+				g.deferred = append(g.deferred, g.pipelines[n:]...)
+				g.pipelines = g.pipelines[:n]
+			}
+		}
+		if err := s.deferPipelines(ctx, processed); err != nil {
+			return nil, err
+		}
+	} else {
+		r, err := s.reservePipelines(ctx, count)
+		if err != nil {
+			return nil, err
+		}
+		defer r.release()
+		reservation = r
 	}
 
-	// this is separate incase we fail to process one report,
+	// this is separate incase we fail to process the reports,
 	// we reject before pipelines are created in order to allow
 	// the message to be requeued. If pipelines fail to be created
 	// the errors will be logged to the poilicies
 	// events and is considered an error with the policy,
 	// not the report.
 	var createdPipelines []*ocularv1beta1.Pipeline
-	for _, g := range generatedPipelines {
-		var policyPipelines []*ocularv1beta1.Pipeline
-		for i, pipeline := range g.pipelines {
-			err := s.mgrClient.Create(ctx, pipeline)
-			if err != nil {
-				l.Error(err, "unable to create pipeline for policy",
-					"pipeline", pipeline.Name, "namespace", g.policy.Namespace, "policy", g.policy.Name)
+	for _, p := range processed {
+		reportCtx := logf.IntoContext(ctx, l.WithValues("action-id", p.actionID))
+		record := s.recordReport(reportCtx, p.actionID, p.report)
+
+		var policyResults []chalkularv1beta1.ChalkReportPolicyResult
+		for _, f := range p.failed {
+			policyResults = append(policyResults, s.recordFailedPolicy(f))
+		}
+		for _, g := range p.generated {
+			result := chalkularv1beta1.ChalkReportPolicyResult{
+				Name:      g.policy.Name,
+				Namespace: g.policy.Namespace,
+			}
+			if g.excluded > 0 {
+				l.Info("excluding pipelines claimed by exclusive policies", "policy", g.policy.Name, "namespace", g.policy.Namespace,
+					"excluded", g.excluded, "claimedBy", g.excludedBy)
+				s.recorder.Eventf(&g.policy, nil,
+					corev1.EventTypeNormal,
+					"PipelinesExcluded",
+					"CreatePipelineFromReport",
+					"report '%s' excluded %d pipelines with targets claimed by exclusive policies: %s",
+					g.actionID, g.excluded, strings.Join(g.excludedBy, ", "))
+				result.ExcludedPipelines = g.excluded
+			}
+			if g.skipped > 0 {
+				window := s.dedupWindowFor(&g.policy)
+				l.Info("skipping duplicate pipelines for policy", "policy", g.policy.Name, "namespace", g.policy.Namespace,
+					"skipped", g.skipped)
+				schedulerDuplicatePipelinesSkipped.With(prometheus.Labels{"policy": g.policy.Name, "namespace": g.policy.Namespace}).Add(float64(g.skipped))
+				result.SkippedPipelines = g.skipped
+				if len(g.pipelines)+len(g.deferred)+g.overLimit == 0 {
+					schedulerDuplicateReportsSkipped.With(prometheus.Labels{"policy": g.policy.Name, "namespace": g.policy.Namespace}).Inc()
+					s.recorder.Eventf(&g.policy, nil,
+						corev1.EventTypeNormal,
+						"DuplicateReportSkipped",
+						"CreatePipelineFromReport",
+						"report '%s' already created pipelines within %s, skipping %d pipelines", g.actionID, window, g.skipped)
+					result.Duplicate = true
+					policyResults = append(policyResults, result)
+					continue
+				}
+				s.recorder.Eventf(&g.policy, nil,
+					corev1.EventTypeNormal,
+					"DuplicatePipelinesSkipped",
+					"CreatePipelineFromReport",
+					"report '%s' skipped %d pipelines with a dedup key already used within %s", g.actionID, g.skipped, window)
+			}
+			if len(g.deferred) > 0 {
+				l.Info("deferring pipelines for policy", "policy", g.policy.Name, "namespace", g.policy.Namespace,
+					"deferred", len(g.deferred))
+				schedulerPipelinesDeferred.With(prometheus.Labels{"policy": g.policy.Name, "namespace": g.policy.Namespace}).Add(float64(len(g.deferred)))
+				s.recorder.Eventf(&g.policy, nil,
+					corev1.EventTypeNormal,
+					"PipelinesDeferred",
+					"CreatePipelineFromReport",
+					"report '%s' deferred %d pipelines until active pipelines complete", g.actionID, len(g.deferred))
+				result.DeferredPipelines = len(g.deferred)
+				results[p.index].DeferredInMemory = !s.backlog.durable
+			}
+			if g.limitErr != nil {
+				l.Info("skipping pipelines for policy", "policy", g.policy.Name, "namespace", g.policy.Namespace,
+					"skipped", g.overLimit, "reason", g.limitErr.Error())
+				schedulerPipelinesOverLimit.With(prometheus.Labels{"policy": g.policy.Name, "namespace": g.policy.Namespace}).Add(float64(g.overLimit))
+				reason := "FailedToCheckPipelineLimits"
+				if errors.Is(g.limitErr, ErrActivePipelineLimit) {
+					reason = "ActivePipelineLimitReached"
+				}
 				s.recorder.Eventf(&g.policy, nil,
 					corev1.EventTypeWarning,
-					"FailedToCreatePipeline",
+					reason,
+					"CreatePipelineFromReport",
+					"report '%s' skipped %d/%d pipelines: %s", g.actionID, g.overLimit, g.overLimit+len(g.pipelines), g.limitErr)
+				result.Error = fmt.Sprintf("skipped %d/%d pipelines: %s", g.overLimit, g.overLimit+len(g.pipelines), g.limitErr)
+				if len(g.pipelines) == 0 {
+					policyResults = append(policyResults, result)
+					continue
+				}
+			}
+			var (
+				policyPipelines []*ocularv1beta1.Pipeline
+				createErrs      []error
+			)
+			for _, pipeline := range g.pipelines {
+				err := s.mgrClient.Create(ctx, pipeline)
+				if err != nil {
+					l.Error(err, "unable to create pipeline for policy",
+						"pipeline", pipeline.Name, "namespace", g.policy.Namespace, "policy", g.policy.Name)
+					retry := retryPipeline{
+						actionID:   g.actionID,
+						policyName: g.policy.Name,
+						policyUID:  g.policy.UID,
+						pipeline:   pipeline.DeepCopy(),
+						attempts:   1,
+					}
+					if s.retryCreate(ctx, &g.policy, retry, err) {
+						result.RetryingPipelines++
+					} else {
+						createErrs = append(createErrs, err)
+					}
+				} else {
+					schedulerPipelinesCreated.With(prometheus.Labels{"profile": pipeline.Spec.ProfileRef.Name, "policy": g.policy.Name, "namespace": pipeline.Namespace}).Inc()
+					s.recordCreated(ctx, pipeline, s.dedupWindowFor(&g.policy))
+					reservation.created(pipeline)
+					g.quota.created(pipeline)
+					policyPipelines = append(policyPipelines, pipeline)
+					result.Pipelines = append(result.Pipelines, pipeline.Name)
+				}
+			}
+			if len(createErrs) > 0 {
+				if result.Error != "" {
+					result.Error += "; "
+				}
+				result.Error += fmt.Sprintf("failed to create %d/%d pipelines: %s",
+					len(createErrs), len(g.pipelines), errors.Join(createErrs...))
+			}
+			if len(policyPipelines) > 0 {
+				s.recorder.Eventf(&g.policy, nil,
+					corev1.EventTypeNormal,
+					"PipelinesCreated",
 					"CreatePipelineFromReport",
-					"failed to generate pipeline (%d/%d) for report '%s': %s", i, len(g.pipelines), g.actionID, err)
-			} else {
-				schedulerPipelinesCreated.With(prometheus.Labels{"profile": pipeline.Spec.ProfileRef.Name, "policy": g.policy.Name, "namespace": pipeline.Namespace})
-				policyPipelines = append(policyPipelines, pipeline)
+					"report '%s' created %d pipeline", g.actionID, len(policyPipelines))
+				createdPipelines = append(createdPipelines, policyPipelines...)
 			}
+			policyResults = append(policyResults, result)
 		}
-		if len(policyPipelines) > 0 {
-			s.recorder.Eventf(&g.policy, nil,
-				corev1.EventTypeNormal,
-				"PipelinesCreated",
-				"CreatePipelineFromReport",
-				"report '%s' created %d pipeline", g.actionID, len(createdPipelines))
-			createdPipelines = append(createdPipelines, policyPipelines...)
+		for _, g := range p.audited {
+			policyResults = append(policyResults, s.auditPipelines(reportCtx, g))
 		}
 
+		s.updateReportStatus(reportCtx, record, policyResults)
+		results[p.index].Policies = policyResults
 	}
 
 	l.Info(fmt.Sprintf("created %d pipelines", len(createdPipelines)), "pipelines", len(createdPipelines))
-	return nil
+	return results, nil
 }
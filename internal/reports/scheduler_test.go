@@ -0,0 +1,797 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	"github.com/crashappsec/chalkular/internal/policy"
+	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// newTestPolicy returns a ready policy that creates
+// a single pipeline for every report it is evaluated against
+func newTestPolicy(namespace, name string) *chalkularv1beta1.ChalkReportPolicy {
+	return &chalkularv1beta1.ChalkReportPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			UID:       types.UID("uid-" + name),
+		},
+		Spec: chalkularv1beta1.ChalkReportPolicySpec{
+			MatchCondition: "true",
+			Extraction: chalkularv1beta1.ChalkReportPolicyExtraction{
+				Target: "{'identifier': report._ACTION_ID, 'version': 'latest'}",
+			},
+			PipelineTemplate: ocularv1beta1.PipelineTemplate{
+				Spec: ocularv1beta1.PipelineSpec{
+					ProfileRef:    ocularv1beta1.ParameterizedLocalObjectReference{Name: "profile"},
+					DownloaderRef: ocularv1beta1.ParameterizedLocalObjectReference{Name: "downloader"},
+				},
+			},
+		},
+		Status: chalkularv1beta1.ChalkReportPolicyStatus{
+			Conditions: []metav1.Condition{{
+				Type:   "Ready",
+				Status: metav1.ConditionTrue,
+				Reason: "Ready",
+			}},
+		},
+	}
+}
+
+// newTestScheduler returns a scheduler using a fake client with the objects
+func newTestScheduler(objs ...client.Object) (*Scheduler, client.Client) {
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(chalkularv1beta1.AddToScheme(scheme)).To(Succeed())
+	Expect(ocularv1beta1.AddToScheme(scheme)).To(Succeed())
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&chalkularv1beta1.ChalkReport{}, &chalkularv1beta1.ChalkReportPolicy{}).
+		WithIndex(&ocularv1beta1.Pipeline{}, "status.active", isPipelineActiveIndexer).
+		Build()
+
+	compiler, err := policy.NewCompiler(16, policy.CompilerOptions{})
+	Expect(err).NotTo(HaveOccurred())
+
+	return &Scheduler{
+		queue:            NewMemoryQueue(),
+		waiters:          make(map[string]chan SchedulerResult),
+		workers:          1,
+		mgrClient:        c,
+		apiReader:        c,
+		recorder:         events.NewFakeRecorder(100),
+		policyCompiler:   compiler,
+		retries:          newCreateRetries(0, 0),
+		itemRetryBackoff: time.Millisecond,
+		itemAttempts:     make(map[string]int),
+	}, c
+}
+
+// processTestReports processes the reports as a single queued item
+func processTestReports(ctx context.Context, s *Scheduler, reports ...ReceivedReport) ([]ReportResult, error) {
+	item := s.sequencer.add()
+	defer s.sequencer.finish(item)
+	return s.processReports(ctx, item, reports)
+}
+
+// countPipelines returns the amount of pipelines in the 'scans' namespace
+func countPipelines(ctx context.Context, c client.Client) int {
+	pipelines := &ocularv1beta1.PipelineList{}
+	Expect(c.List(ctx, pipelines, client.InNamespace("scans"))).To(Succeed())
+	return len(pipelines.Items)
+}
+
+// newUncachedScheduler returns a scheduler that never finds pipelines
+// in its client, as if the cache was not updated after creating them
+func newUncachedScheduler(objs ...client.Object) (*Scheduler, client.Client) {
+	s, c := newTestScheduler(objs...)
+	s.mgrClient = interceptor.NewClient(c.(client.WithWatch), interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			// widen the window between reserving and creating pipelines
+			time.Sleep(time.Millisecond)
+			return c.Create(ctx, obj, opts...)
+		},
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if _, ok := obj.(*ocularv1beta1.Pipeline); ok {
+				return apierrors.NewNotFound(pipelineResource, key.Name)
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if _, ok := list.(*ocularv1beta1.PipelineList); ok {
+				return nil
+			}
+			return c.List(ctx, list, opts...)
+		},
+	})
+	return s, c
+}
+
+var _ = Describe("Scheduler", func() {
+	Context("processReports", func() {
+		It("should return the result of each report, skipping invalid reports", func(ctx SpecContext) {
+			s, c := newTestScheduler(newTestPolicy("scans", "images"))
+
+			results, err := processTestReports(ctx, s,
+				ReceivedReport{Report: chalk.Report{"_ACTION_ID": "a1b2c3d4"}},
+				ReceivedReport{Report: chalk.Report{"_CHALKS": []any{}}},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+
+			Expect(results[0].ActionID).To(Equal("a1b2c3d4"))
+			Expect(results[0].Err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(And(
+				HaveField("Name", "images"),
+				HaveField("Namespace", "scans"),
+				HaveField("Pipelines", HaveLen(1)),
+				HaveField("Error", BeEmpty()),
+			)))
+			Expect(results[0].Pipelines()).To(Equal(1))
+
+			Expect(results[1].ActionID).To(BeEmpty())
+			Expect(results[1].Err).To(MatchError(ErrInvalidReport))
+
+			pipelines := &ocularv1beta1.PipelineList{}
+			Expect(c.List(ctx, pipelines, client.InNamespace("scans"))).To(Succeed())
+			Expect(pipelines.Items).To(HaveLen(1))
+			Expect(pipelines.Items[0].Name).To(Equal(results[0].Policies[0].Pipelines[0]))
+			Expect(pipelines.Items[0].Labels).To(And(
+				HaveKeyWithValue(chalkularv1beta1.PipelinePolicyLabel, "images"),
+				HaveKeyWithValue(chalkularv1beta1.PipelinePolicyUIDLabel, "uid-images"),
+				HaveKeyWithValue(chalkularv1beta1.PipelineActionIDLabel, "a1b2c3d4"),
+			))
+		})
+	})
+
+	Context("deduplication", func() {
+		report := ReceivedReport{Report: chalk.Report{"_ACTION_ID": "a1b2c3d4"}}
+		// the values extracted for the report by the test policy
+		reportValues := policy.PipelineValues{Target: ocularv1beta1.Target{Identifier: "a1b2c3d4", Version: "latest"}}
+
+		It("should skip reports that the policy already created pipelines for", func(ctx SpecContext) {
+			s, c := newTestScheduler(newTestPolicy("scans", "images"))
+			s.dedupWindow = time.Hour
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(HaveField("Duplicate", BeFalse())))
+
+			results, err = processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(And(
+				HaveField("Duplicate", BeTrue()),
+				HaveField("Pipelines", BeEmpty()),
+			)))
+			Expect(countPipelines(ctx, c)).To(Equal(1))
+		})
+
+		It("should skip duplicate reports in the same batch", func(ctx SpecContext) {
+			s, c := newTestScheduler(newTestPolicy("scans", "images"))
+			s.dedupWindow = time.Hour
+
+			results, err := processTestReports(ctx, s, report, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(HaveField("Duplicate", BeFalse())))
+			Expect(results[1].Policies).To(ConsistOf(HaveField("Duplicate", BeTrue())))
+			Expect(countPipelines(ctx, c)).To(Equal(1))
+		})
+
+		It("should detect duplicates from the pipelines in the cluster", func(ctx SpecContext) {
+			reportPolicy := newTestPolicy("scans", "images")
+			completed := metav1.NewTime(time.Now().Add(-time.Minute))
+			existing := &ocularv1beta1.Pipeline{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "chalkular-a1b2c3d4-existing",
+					Namespace:         "scans",
+					CreationTimestamp: metav1.NewTime(time.Now().Add(-2 * time.Minute)),
+					Labels:            map[string]string{dedupKeyLabel: dedupKey(reportPolicy.UID, "a1b2c3d4", reportValues)},
+				},
+				Status: ocularv1beta1.PipelineStatus{CompletionTime: &completed},
+			}
+			s, _ := newTestScheduler(reportPolicy, existing)
+			s.dedupWindow = time.Hour
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(HaveField("Duplicate", BeTrue())))
+
+			By("not skipping reports once the window has passed")
+			s.dedupWindow = time.Second
+			results, err = processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(HaveField("Duplicate", BeFalse())))
+		})
+
+		It("should detect duplicates from the dedup lease once the pipeline is deleted", func(ctx SpecContext) {
+			s, c := newTestScheduler(newTestPolicy("scans", "images"))
+			s.dedupWindow = time.Hour
+
+			_, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.DeleteAllOf(ctx, &ocularv1beta1.Pipeline{}, client.InNamespace("scans"))).To(Succeed())
+
+			By("detecting the duplicate after a restart")
+			s.recent = recentKeys{}
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(HaveField("Duplicate", BeTrue())))
+			Expect(countPipelines(ctx, c)).To(BeZero())
+
+			By("pruning the lease once the window has passed")
+			pruned, err := s.pruneExpiredLeases(ctx, time.Now())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pruned).To(BeZero())
+			pruned, err = s.pruneExpiredLeases(ctx, time.Now().Add(2*time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pruned).To(Equal(1))
+		})
+
+		It("should create the missing pipelines of a report that was partially created", func(ctx SpecContext) {
+			reportPolicy := newTestPolicy("scans", "images")
+			reportPolicy.Spec.Extraction.ForEach = new("['a', 'b']")
+			reportPolicy.Spec.Extraction.Target = "{'identifier': each}"
+			s, c := newTestScheduler(reportPolicy)
+			s.dedupWindow = time.Hour
+			failPipelineCreates(s, c, nil, apierrors.NewForbidden(pipelineResource, "", errors.New("denied")))
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(HaveField("Pipelines", HaveLen(1))))
+
+			By("creating only the pipeline that failed when the report is redelivered")
+			results, err = processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(And(
+				HaveField("Duplicate", BeFalse()),
+				HaveField("SkippedPipelines", 1),
+				HaveField("Pipelines", HaveLen(1)),
+			)))
+			Expect(countPipelines(ctx, c)).To(Equal(2))
+
+			By("skipping the report once all its pipelines exist")
+			results, err = processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(HaveField("Duplicate", BeTrue())))
+		})
+
+		It("should skip reports while the existing pipeline has not completed", func(ctx SpecContext) {
+			reportPolicy := newTestPolicy("scans", "images")
+			existing := &ocularv1beta1.Pipeline{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "chalkular-a1b2c3d4-existing",
+					Namespace:         "scans",
+					CreationTimestamp: metav1.NewTime(time.Now().Add(-2 * time.Hour)),
+					Labels:            map[string]string{dedupKeyLabel: dedupKey(reportPolicy.UID, "a1b2c3d4", reportValues)},
+				},
+			}
+			s, _ := newTestScheduler(reportPolicy, existing)
+			s.dedupWindow = time.Hour
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(HaveField("Duplicate", BeTrue())))
+		})
+
+		It("should use the dedup window of the policy", func(ctx SpecContext) {
+			reportPolicy := newTestPolicy("scans", "images")
+			reportPolicy.Spec.DedupWindow = &metav1.Duration{}
+			s, c := newTestScheduler(reportPolicy)
+			s.dedupWindow = time.Hour
+
+			_, err := processTestReports(ctx, s, report, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(countPipelines(ctx, c)).To(Equal(2))
+		})
+
+		It("should skip pipelines with a dedup key extracted from the report", func(ctx SpecContext) {
+			reportPolicy := newTestPolicy("scans", "images")
+			reportPolicy.Spec.Extraction.ForEach = new("report._CHALKS")
+			reportPolicy.Spec.Extraction.Target = "{'identifier': each.HASH}"
+			reportPolicy.Spec.Extraction.DedupKey = new("each.HASH")
+			s, c := newTestScheduler(reportPolicy)
+			s.dedupWindow = time.Hour
+
+			results, err := processTestReports(ctx, s, ReceivedReport{Report: chalk.Report{
+				"_ACTION_ID": "a1b2c3d4",
+				"_CHALKS":    []any{map[string]any{"HASH": "aaaa"}},
+			}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(HaveField("SkippedPipelines", 0)))
+
+			By("skipping only the artifacts already scanned for a different report")
+			results, err = processTestReports(ctx, s, ReceivedReport{Report: chalk.Report{
+				"_ACTION_ID": "e5f6a7b8",
+				"_CHALKS":    []any{map[string]any{"HASH": "aaaa"}, map[string]any{"HASH": "bbbb"}},
+			}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(And(
+				HaveField("Duplicate", BeFalse()),
+				HaveField("SkippedPipelines", 1),
+				HaveField("Pipelines", HaveLen(1)),
+			)))
+			Expect(countPipelines(ctx, c)).To(Equal(2))
+		})
+
+		It("should skip pipelines of the same report with the same extracted dedup key", func(ctx SpecContext) {
+			reportPolicy := newTestPolicy("scans", "images")
+			reportPolicy.Spec.Extraction.ForEach = new("report._CHALKS")
+			reportPolicy.Spec.Extraction.Target = "{'identifier': each._REPO_TAGS[0]}"
+			reportPolicy.Spec.Extraction.DedupKey = new("each._IMAGE_DIGEST")
+			s, c := newTestScheduler(reportPolicy)
+			s.dedupWindow = time.Hour
+
+			results, err := processTestReports(ctx, s, ReceivedReport{Report: chalk.Report{
+				"_ACTION_ID": "a1b2c3d4",
+				"_CHALKS": []any{
+					map[string]any{"_IMAGE_DIGEST": "sha256:aaaa", "_REPO_TAGS": []any{"example.com/app:v1"}},
+					map[string]any{"_IMAGE_DIGEST": "sha256:aaaa", "_REPO_TAGS": []any{"example.com/app:latest"}},
+				},
+			}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(And(
+				HaveField("SkippedPipelines", 1),
+				HaveField("Pipelines", HaveLen(1)),
+			)))
+			Expect(countPipelines(ctx, c)).To(Equal(1))
+		})
+
+		It("should not skip reports if deduplication is disabled", func(ctx SpecContext) {
+			s, c := newTestScheduler(newTestPolicy("scans", "images"))
+
+			_, err := processTestReports(ctx, s, report, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(countPipelines(ctx, c)).To(Equal(2))
+		})
+	})
+
+	Context("processItem", func() {
+		var (
+			running *ocularv1beta1.Pipeline
+			s       *Scheduler
+			c       client.Client
+			dir     string
+		)
+		BeforeEach(func() {
+			started := metav1.Now()
+			running = &ocularv1beta1.Pipeline{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "chalkular-running",
+					Namespace: "scans",
+					Labels:    map[string]string{schedulerLabel: schedulerValue},
+				},
+				Status: ocularv1beta1.PipelineStatus{StartTime: &started},
+			}
+			failing := newTestPolicy("scans", "failing")
+			failing.Spec.MatchCondition = "report['_X_MISSING'] == 'x'"
+			audit := newTestPolicy("scans", "audit")
+			audit.Spec.Mode = chalkularv1beta1.PolicyModeAudit
+			s, c = newTestScheduler(newTestPolicy("scans", "images"), failing, audit, running)
+			s.rejectPipelineThreshold = 1
+			dir = GinkgoT().TempDir()
+			queue, err := NewFileQueue(dir)
+			Expect(err).NotTo(HaveOccurred())
+			s.queue = queue
+		})
+
+		// completeRunning completes the active pipeline, so the threshold is no longer reached
+		completeRunning := func(ctx context.Context) {
+			completed := metav1.Now()
+			running.Status.CompletionTime = &completed
+			Expect(c.Update(ctx, running)).To(Succeed())
+		}
+
+		It("should return the error to the waiter and remove the item", func(ctx SpecContext) {
+			done, err := s.GetClient().Enqueue(ctx, []ReceivedReport{{Report: chalk.Report{"_ACTION_ID": "a1b2c3d4"}}})
+			Expect(err).NotTo(HaveOccurred())
+			item, err := s.queue.Pop(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			s.processItem(ctx, sequencedQueueItem{QueueItem: item, sequenced: s.sequencer.add()})
+
+			var result SchedulerResult
+			Expect(done).To(Receive(&result))
+			Expect(result.Err).To(MatchError(ErrPipelineThreshold))
+			Expect(filepath.Join(dir, item.ID+fileQueueExt)).NotTo(BeAnExistingFile())
+			Expect(s.recorder.(*events.FakeRecorder).Events).To(BeEmpty())
+		})
+
+		It("should process an item without a waiter again after a backoff", func(ctx SpecContext) {
+			Expect(s.GetClient().Submit(ctx, []ReceivedReport{{Report: chalk.Report{"_ACTION_ID": "a1b2c3d4"}}})).To(Succeed())
+			item, err := s.queue.Pop(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			s.processItem(ctx, sequencedQueueItem{QueueItem: item, sequenced: s.sequencer.add()})
+
+			By("keeping the item without recording events or audits while at the threshold")
+			Expect(filepath.Join(dir, item.ID+fileQueueExt)).To(BeAnExistingFile())
+			Expect(s.recorder.(*events.FakeRecorder).Events).To(BeEmpty())
+			audit := &chalkularv1beta1.ChalkReportPolicy{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: "scans", Name: "audit"}, audit)).To(Succeed())
+			Expect(audit.Status.Audit.MatchedReports).To(BeZero())
+
+			By("processing the requeued item once the active pipeline completes")
+			completeRunning(ctx)
+			requeued, err := s.queue.Pop(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requeued.ID).To(Equal(item.ID))
+			s.processItem(ctx, sequencedQueueItem{QueueItem: requeued, sequenced: s.sequencer.add()})
+			Expect(filepath.Join(dir, item.ID+fileQueueExt)).NotTo(BeAnExistingFile())
+			Expect(countPipelines(ctx, c)).To(Equal(2))
+			Expect(s.itemAttempts).To(BeEmpty())
+
+			By("recording the events and audits once")
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: "scans", Name: "audit"}, audit)).To(Succeed())
+			Expect(audit.Status.Audit.MatchedReports).To(BeEquivalentTo(1))
+			var reasons []string
+			for len(s.recorder.(*events.FakeRecorder).Events) > 0 {
+				reasons = append(reasons, <-s.recorder.(*events.FakeRecorder).Events)
+			}
+			Expect(reasons).To(ConsistOf(
+				ContainSubstring("PolicyEvalFailed"),
+				ContainSubstring("PipelinesCreated"),
+				ContainSubstring("PipelinesAudited"),
+			))
+		})
+	})
+
+	Context("active pipeline threshold", func() {
+		It("should count pipelines that have not started as active", func(ctx SpecContext) {
+			s, _ := newTestScheduler(newTestPolicy("scans", "images"))
+			s.rejectPipelineThreshold = 1
+
+			_, err := processTestReports(ctx, s, ReceivedReport{Report: chalk.Report{"_ACTION_ID": "a1b2c3d4"}})
+			Expect(err).NotTo(HaveOccurred())
+			s.uncached = nil
+
+			_, err = processTestReports(ctx, s, ReceivedReport{Report: chalk.Report{"_ACTION_ID": "e5f6a7b8"}})
+			Expect(err).To(MatchError(ErrPipelineThreshold))
+		})
+
+		It("should count created pipelines that are not in the cache as active", func(ctx SpecContext) {
+			s, c := newUncachedScheduler(newTestPolicy("scans", "images"))
+			s.rejectPipelineThreshold = 1
+
+			_, err := processTestReports(ctx, s, ReceivedReport{Report: chalk.Report{"_ACTION_ID": "a1b2c3d4"}})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = processTestReports(ctx, s, ReceivedReport{Report: chalk.Report{"_ACTION_ID": "e5f6a7b8"}})
+			Expect(err).To(MatchError(ErrPipelineThreshold))
+			Expect(countPipelines(ctx, c)).To(Equal(1))
+
+			By("no longer counting the pipeline once it is in the cache and completed")
+			pipelines := &ocularv1beta1.PipelineList{}
+			Expect(c.List(ctx, pipelines)).To(Succeed())
+			completed := metav1.Now()
+			pipelines.Items[0].Status.CompletionTime = &completed
+			Expect(c.Update(ctx, &pipelines.Items[0])).To(Succeed())
+			s.mgrClient = c
+			_, err = processTestReports(ctx, s, ReceivedReport{Report: chalk.Report{"_ACTION_ID": "e5f6a7b8"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.uncached).To(HaveLen(1))
+		})
+
+		It("should never exceed the threshold with concurrent workers", func(ctx SpecContext) {
+			const (
+				threshold = 3
+				workers   = 10
+			)
+			var objs []client.Object
+			for i := range workers {
+				// a policy for each report, so that the workers are not sequenced by policy
+				reportPolicy := newTestPolicy("scans", fmt.Sprintf("images-%d", i))
+				reportPolicy.Spec.MatchCondition = fmt.Sprintf("report._ACTION_ID == 'report-%d'", i)
+				objs = append(objs, reportPolicy)
+			}
+			s, c := newUncachedScheduler(objs...)
+			s.rejectPipelineThreshold = threshold
+
+			var (
+				wg       sync.WaitGroup
+				mu       sync.Mutex
+				rejected int
+			)
+			for i := range workers {
+				wg.Go(func() {
+					defer GinkgoRecover()
+					_, err := processTestReports(ctx, s, ReceivedReport{Report: chalk.Report{"_ACTION_ID": fmt.Sprintf("report-%d", i)}})
+					if err != nil {
+						Expect(err).To(MatchError(ErrPipelineThreshold))
+						mu.Lock()
+						rejected++
+						mu.Unlock()
+					}
+				})
+			}
+			wg.Wait()
+
+			Expect(countPipelines(ctx, c)).To(Equal(threshold))
+			Expect(rejected).To(Equal(workers - threshold))
+		})
+	})
+
+	Context("active pipeline limits", func() {
+		report := ReceivedReport{Report: chalk.Report{
+			"_ACTION_ID": "a1b2c3d4",
+			"_CHALKS":    []any{map[string]any{"HASH": "aaaa"}, map[string]any{"HASH": "bbbb"}},
+		}}
+
+		newForEachPolicy := func(name string) *chalkularv1beta1.ChalkReportPolicy {
+			p := newTestPolicy("scans", name)
+			p.Spec.Extraction.ForEach = new("report._CHALKS")
+			p.Spec.Extraction.Target = "{'identifier': each.HASH}"
+			return p
+		}
+
+		It("should only skip the pipelines of the policy over its limit", func(ctx SpecContext) {
+			limited := newForEachPolicy("limited")
+			limited.Spec.Limits = &chalkularv1beta1.ChalkReportPolicyLimits{MaxActivePipelines: new(int32(1))}
+			s, _ := newTestScheduler(limited, newForEachPolicy("unlimited"))
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(
+				And(
+					HaveField("Name", "limited"),
+					HaveField("Pipelines", HaveLen(1)),
+					HaveField("Error", ContainSubstring(ErrActivePipelineLimit.Error())),
+				),
+				And(
+					HaveField("Name", "unlimited"),
+					HaveField("Pipelines", HaveLen(2)),
+					HaveField("Error", BeEmpty()),
+				),
+			))
+
+			By("counting the pipelines of the policy that have not completed")
+			results, err = processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ContainElement(And(
+				HaveField("Name", "limited"),
+				HaveField("Pipelines", BeEmpty()),
+				HaveField("Error", ContainSubstring("skipped 2/2 pipelines")),
+			)))
+		})
+
+		It("should not count completed pipelines towards the limit", func(ctx SpecContext) {
+			limited := newForEachPolicy("limited")
+			limited.Spec.Limits = &chalkularv1beta1.ChalkReportPolicyLimits{MaxActivePipelines: new(int32(2))}
+			completed := metav1.Now()
+			existing := &ocularv1beta1.Pipeline{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "chalkular-existing",
+					Namespace: "scans",
+					Labels:    map[string]string{schedulerLabel: schedulerValue, policyUIDLabel: string(limited.UID)},
+				},
+				Status: ocularv1beta1.PipelineStatus{CompletionTime: &completed},
+			}
+			s, _ := newTestScheduler(limited, existing)
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(And(
+				HaveField("Pipelines", HaveLen(2)),
+				HaveField("Error", BeEmpty()),
+			)))
+		})
+
+		It("should limit the pipelines of every policy in a namespace with a quota", func(ctx SpecContext) {
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:        "scans",
+				Annotations: map[string]string{namespaceQuotaAnnotation: "3"},
+			}}
+			s, c := newTestScheduler(namespace, newForEachPolicy("first"), newForEachPolicy("second"))
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(
+				HaveField("Pipelines", HaveLen(2)),
+				And(
+					HaveField("Pipelines", HaveLen(1)),
+					HaveField("Error", ContainSubstring("namespace scans has")),
+				),
+			))
+
+			pipelines := &ocularv1beta1.PipelineList{}
+			Expect(c.List(ctx, pipelines, client.InNamespace("scans"))).To(Succeed())
+			Expect(pipelines.Items).To(HaveLen(3))
+		})
+
+		It("should count created pipelines that are not in the cache towards the limits", func(ctx SpecContext) {
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:        "scans",
+				Annotations: map[string]string{namespaceQuotaAnnotation: "3"},
+			}}
+			limited := newForEachPolicy("limited")
+			limited.Spec.Limits = &chalkularv1beta1.ChalkReportPolicyLimits{MaxActivePipelines: new(int32(1))}
+			s, c := newUncachedScheduler(namespace, limited)
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(HaveField("Pipelines", HaveLen(1))))
+
+			results, err = processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(And(
+				HaveField("Pipelines", BeEmpty()),
+				HaveField("Error", ContainSubstring("policy has 1 active pipelines")),
+			)))
+			Expect(countPipelines(ctx, c)).To(Equal(1))
+
+			By("counting the uncached pipelines towards the quota of the namespace")
+			Expect(c.Create(ctx, newForEachPolicy("other"))).To(Succeed())
+			results, err = processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ContainElement(And(
+				HaveField("Name", "other"),
+				HaveField("Pipelines", HaveLen(2)),
+			)))
+			results, err = processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ContainElement(And(
+				HaveField("Name", "other"),
+				HaveField("Pipelines", BeEmpty()),
+				HaveField("Error", ContainSubstring("namespace scans has 3 active pipelines")),
+			)))
+			Expect(countPipelines(ctx, c)).To(Equal(3))
+		})
+
+		It("should ignore an invalid namespace quota", func(ctx SpecContext) {
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:        "scans",
+				Annotations: map[string]string{namespaceQuotaAnnotation: "many"},
+			}}
+			s, _ := newTestScheduler(namespace, newForEachPolicy("images"))
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(HaveField("Pipelines", HaveLen(2))))
+		})
+	})
+
+	Context("policy priority", func() {
+		report := ReceivedReport{Report: chalk.Report{
+			"_ACTION_ID": "a1b2c3d4",
+			"_CHALKS":    []any{map[string]any{"HASH": "aaaa"}, map[string]any{"HASH": "bbbb"}},
+		}}
+
+		It("should only create pipelines for the highest priority exclusive policy", func(ctx SpecContext) {
+			exclusive := newTestPolicy("scans", "exclusive")
+			exclusive.Spec.Priority = 10
+			exclusive.Spec.MatchPolicy = chalkularv1beta1.MatchPolicyExclusive
+			exclusive.Spec.Extraction.Target = "{'identifier': report._CHALKS[0].HASH}"
+			lower := newTestPolicy("scans", "lower")
+			lower.Spec.MatchPolicy = chalkularv1beta1.MatchPolicyExclusive
+			lower.Spec.Extraction.ForEach = new("report._CHALKS")
+			lower.Spec.Extraction.Target = "{'identifier': each.HASH}"
+			s, c := newTestScheduler(lower, exclusive)
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(HaveExactElements(
+				And(
+					HaveField("Name", "exclusive"),
+					HaveField("Pipelines", HaveLen(1)),
+					HaveField("ExcludedPipelines", BeZero()),
+				),
+				And(
+					HaveField("Name", "lower"),
+					HaveField("Pipelines", HaveLen(1)),
+					HaveField("ExcludedPipelines", 1),
+				),
+			))
+
+			pipelines := &ocularv1beta1.PipelineList{}
+			Expect(c.List(ctx, pipelines, client.InNamespace("scans"))).To(Succeed())
+			Expect(pipelines.Items).To(HaveLen(2))
+		})
+
+		It("should create pipelines for every additive policy", func(ctx SpecContext) {
+			high := newTestPolicy("scans", "high")
+			high.Spec.Priority = 10
+			s, _ := newTestScheduler(newTestPolicy("scans", "low"), high)
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(HaveExactElements(
+				And(HaveField("Name", "high"), HaveField("Pipelines", HaveLen(1))),
+				And(HaveField("Name", "low"), HaveField("Pipelines", HaveLen(1))),
+			))
+		})
+
+		It("should record the policies that had every pipeline excluded", func(ctx SpecContext) {
+			exclusive := newTestPolicy("scans", "exclusive")
+			exclusive.Spec.MatchPolicy = chalkularv1beta1.MatchPolicyExclusive
+			s, _ := newTestScheduler(exclusive, newTestPolicy("scans", "additive"))
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(
+				And(HaveField("Name", "exclusive"), HaveField("Pipelines", HaveLen(1))),
+				And(
+					HaveField("Name", "additive"),
+					HaveField("Pipelines", BeEmpty()),
+					HaveField("ExcludedPipelines", 1),
+					HaveField("Duplicate", BeFalse()),
+				),
+			))
+		})
+	})
+
+	Context("audit mode", func() {
+		report := ReceivedReport{Report: chalk.Report{"_ACTION_ID": "a1b2c3d4"}}
+
+		It("should record the pipelines of a policy in audit mode without creating them", func(ctx SpecContext) {
+			audit := newTestPolicy("scans", "audit")
+			audit.Spec.Mode = chalkularv1beta1.PolicyModeAudit
+			s, c := newTestScheduler(audit)
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(And(
+				HaveField("Name", "audit"),
+				HaveField("Pipelines", BeEmpty()),
+				HaveField("AuditedPipelines", 1),
+			)))
+
+			pipelines := &ocularv1beta1.PipelineList{}
+			Expect(c.List(ctx, pipelines, client.InNamespace("scans"))).To(Succeed())
+			Expect(pipelines.Items).To(BeEmpty())
+
+			updated := &chalkularv1beta1.ChalkReportPolicy{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(audit), updated)).To(Succeed())
+			Expect(updated.Status.Audit.MatchedReports).To(BeEquivalentTo(1))
+			Expect(updated.Status.Audit.Pipelines).To(BeEquivalentTo(1))
+			Expect(updated.Status.Audit.LastMatch).NotTo(BeNil())
+			Expect(updated.Status.Audit.LastMatch.ActionID).To(Equal("a1b2c3d4"))
+			Expect(updated.Status.Audit.LastMatch.Targets).To(Equal([]string{"a1b2c3d4@latest"}))
+
+			By("recording the report again, since audited pipelines are not deduplicated")
+			_, err = processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(audit), updated)).To(Succeed())
+			Expect(updated.Status.Audit.MatchedReports).To(BeEquivalentTo(2))
+		})
+
+		It("should not exclude the targets of lower priority policies", func(ctx SpecContext) {
+			audit := newTestPolicy("scans", "audit")
+			audit.Spec.Mode = chalkularv1beta1.PolicyModeAudit
+			audit.Spec.Priority = 10
+			audit.Spec.MatchPolicy = chalkularv1beta1.MatchPolicyExclusive
+			s, _ := newTestScheduler(audit, newTestPolicy("scans", "enforce"))
+
+			results, err := processTestReports(ctx, s, report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[0].Policies).To(ConsistOf(
+				And(HaveField("Name", "audit"), HaveField("AuditedPipelines", 1)),
+				And(HaveField("Name", "enforce"), HaveField("Pipelines", HaveLen(1)), HaveField("ExcludedPipelines", BeZero())),
+			))
+		})
+	})
+})
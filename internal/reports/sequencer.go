@@ -0,0 +1,102 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// sequencer orders the queued items processed concurrently by the workers
+// of the [Scheduler]. Items are added in the order they were popped from the
+// queue, and an item waits for every earlier item that shares one of its keys
+// (i.e. an action ID or a policy) to finish before creating its pipelines.
+// Items that share no keys are processed concurrently.
+type sequencer struct {
+	mu    sync.Mutex
+	items []*sequencedItem
+}
+
+// sequencedItem is an item that is being processed by a worker
+type sequencedItem struct {
+	// keys are set before evaluated is closed
+	keys      []string
+	evaluated chan struct{}
+	once      sync.Once
+	done      chan struct{}
+}
+
+func (i *sequencedItem) setKeys(keys []string) {
+	i.once.Do(func() {
+		i.keys = keys
+		close(i.evaluated)
+	})
+}
+
+// add returns a new item, which is ordered after every item already added.
+// [sequencer.finish] should be called with the item once it is processed.
+func (s *sequencer) add() *sequencedItem {
+	item := &sequencedItem{
+		evaluated: make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	s.mu.Lock()
+	s.items = append(s.items, item)
+	s.mu.Unlock()
+	return item
+}
+
+// wait sets the keys of the item, then blocks until every
+// earlier item that shares one of the keys has finished
+func (s *sequencer) wait(ctx context.Context, item *sequencedItem, keys []string) error {
+	item.setKeys(keys)
+
+	s.mu.Lock()
+	earlier := slices.Clone(s.items[:slices.Index(s.items, item)])
+	s.mu.Unlock()
+
+	for _, e := range earlier {
+		// the keys of an earlier item are only
+		// known once it has been evaluated
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-e.evaluated:
+		}
+		if !slices.ContainsFunc(e.keys, func(k string) bool { return slices.Contains(keys, k) }) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-e.done:
+		}
+	}
+	return nil
+}
+
+// finish releases the items waiting on the item
+func (s *sequencer) finish(item *sequencedItem) {
+	// items that failed before being evaluated block nothing
+	item.setKeys(nil)
+	close(item.done)
+
+	s.mu.Lock()
+	s.items = slices.DeleteFunc(s.items, func(i *sequencedItem) bool { return i == item })
+	s.mu.Unlock()
+}
+
+func actionKey(actionID string) string {
+	return "action/" + actionID
+}
+
+func policyKey(namespace, name string) string {
+	return "policy/" + namespace + "/" + name
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2025-2026 Crash Override, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the FSF, either version 3 of the License, or (at your option) any later version.
+// See the LICENSE file in the root of this repository for full license text or
+// visit: <https://www.gnu.org/licenses/gpl-3.0.html>.
+
+package reports
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("sequencer", func() {
+	var seq *sequencer
+
+	BeforeEach(func() {
+		seq = &sequencer{}
+	})
+
+	// waitAsync calls wait in a goroutine, returning
+	// a channel that is closed once wait returns
+	waitAsync := func(ctx context.Context, item *sequencedItem, keys ...string) chan error {
+		done := make(chan error, 1)
+		go func() {
+			done <- seq.wait(ctx, item, keys)
+		}()
+		return done
+	}
+
+	It("should wait for earlier items that share a key", func(ctx SpecContext) {
+		first := seq.add()
+		second := seq.add()
+
+		Expect(seq.wait(ctx, first, []string{actionKey("a"), policyKey("ns", "p")})).To(Succeed())
+		secondDone := waitAsync(ctx, second, policyKey("ns", "p"))
+		Consistently(secondDone, "100ms").ShouldNot(Receive())
+
+		seq.finish(first)
+		Eventually(secondDone).Should(Receive(BeNil()))
+		seq.finish(second)
+	})
+
+	It("should not wait for earlier items that share no keys", func(ctx SpecContext) {
+		first := seq.add()
+		second := seq.add()
+
+		Expect(seq.wait(ctx, first, []string{policyKey("ns", "a")})).To(Succeed())
+		Eventually(waitAsync(ctx, second, policyKey("ns", "b"))).Should(Receive(BeNil()))
+		seq.finish(first)
+		seq.finish(second)
+	})
+
+	It("should wait until earlier items are evaluated", func(ctx SpecContext) {
+		first := seq.add()
+		second := seq.add()
+
+		secondDone := waitAsync(ctx, second, actionKey("a"))
+		Consistently(secondDone, "100ms").ShouldNot(Receive())
+
+		By("evaluating the first item with different keys")
+		Expect(seq.wait(ctx, first, []string{actionKey("b")})).To(Succeed())
+		Eventually(secondDone).Should(Receive(BeNil()))
+		seq.finish(first)
+		seq.finish(second)
+	})
+
+	It("should release items waiting on an item that failed before being evaluated", func(ctx SpecContext) {
+		first := seq.add()
+		second := seq.add()
+
+		secondDone := waitAsync(ctx, second, actionKey("a"))
+		seq.finish(first)
+		Eventually(secondDone).Should(Receive(BeNil()))
+		seq.finish(second)
+		Expect(seq.items).To(BeEmpty())
+	})
+
+	It("should stop waiting when the context is done", func(ctx SpecContext) {
+		first := seq.add()
+		second := seq.add()
+		Expect(seq.wait(ctx, first, []string{actionKey("a")})).To(Succeed())
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		Expect(seq.wait(cancelled, second, []string{actionKey("a")})).To(MatchError(context.Canceled))
+		seq.finish(first)
+		seq.finish(second)
+	})
+})
@@ -10,11 +10,15 @@ package sqs
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/crashappsec/chalkular/internal/policy"
 	"github.com/crashappsec/chalkular/internal/reports"
 	"github.com/prometheus/client_golang/prometheus"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -70,6 +74,8 @@ type SQSClientAPI interface {
 	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
 
 	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+
+	ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
 }
 
 // A Listener is an SQS listener that will listen
@@ -86,7 +92,10 @@ type Listener struct {
 	scheduler     reports.SchedulerClient
 	waitTime      time.Duration
 	visbilityTime time.Duration
-	reportParser  ChalkReportParser
+	// thresholdRetryDelay is the delay before a message is received again,
+	// after its reports were rejected since the active pipeline threshold was reached
+	thresholdRetryDelay time.Duration
+	reportParser        ChalkReportParser
 }
 
 // NewListener will construct a new Listener that will listen on the given queue URL.
@@ -104,6 +113,8 @@ func NewListener(sqsClient SQSClientAPI, scheduler reports.SchedulerClient, queu
 		visbilityTime: time.Minute,
 		scheduler:     scheduler,
 		reportParser:  reportParser,
+
+		thresholdRetryDelay: 30 * time.Second,
 	}, nil
 }
 
@@ -117,10 +128,11 @@ func (l *Listener) Start(ctx context.Context) error {
 			return nil
 		default:
 			result, err := l.sqsClient.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
-				QueueUrl:            aws.String(l.queueURL),
-				MaxNumberOfMessages: 10,
-				WaitTimeSeconds:     int32(l.waitTime.Seconds()),
-				VisibilityTimeout:   int32(l.visbilityTime.Seconds()),
+				QueueUrl:              aws.String(l.queueURL),
+				MaxNumberOfMessages:   10,
+				MessageAttributeNames: []string{"All"},
+				WaitTimeSeconds:       int32(l.waitTime.Seconds()),
+				VisibilityTimeout:     int32(l.visbilityTime.Seconds()),
 			})
 
 			if err != nil {
@@ -155,16 +167,47 @@ func (l *Listener) Start(ctx context.Context) error {
 					continue
 				}
 
-				result := l.scheduler.Enqueue(msgCtx, rs)
+				for i := range rs {
+					rs[i].Source.Method = policy.SourceMethodSQS
+					rs[i].Source.SQS = messageSource(l.queueURL, msg)
+				}
+
+				result, err := l.scheduler.Enqueue(msgCtx, rs)
+				if err != nil {
+					msgLogger.Error(err, "failed to queue reports from message, not deleting message")
+					sqsMessageProcessingDurationSeconds.Observe(time.Since(processingStartTime).Seconds())
+					sqsMessagesProcessedTotal.With(prometheus.Labels{"status": "failure"}).Add(1)
+					continue
+				}
 				go func() {
 					msgLogger.Info("reports scheduled, awaiting result")
-					messageErr := <-result
+					messageResult := <-result
 					sqsMessageProcessingDurationSeconds.Observe(time.Since(processingStartTime).Seconds())
-					if messageErr != nil {
+					if messageResult.Err != nil {
 						sqsMessagesProcessedTotal.With(prometheus.Labels{"status": "failure"}).Add(1)
-						msgLogger.Error(messageErr, "failed to evaluate reports from message, not deleting message")
+						msgLogger.Error(messageResult.Err, "failed to evaluate reports from message, not deleting message")
+						if errors.Is(messageResult.Err, reports.ErrPipelineThreshold) {
+							l.retryAfter(msgCtx, msg)
+						}
 					} else {
-						sqsMessagesProcessedTotal.With(prometheus.Labels{"status": "success"}).Add(1)
+						// invalid reports would fail again if the message
+						// is redelivered, so the message is still deleted
+						status := "success"
+						for i, r := range messageResult.Reports {
+							if r.Err != nil {
+								status = "failure"
+								msgLogger.Error(r.Err, "failed to process report from message", "index", i)
+							} else {
+								msgLogger.Info("processed report from message", "action-id", r.ActionID, "pipelines", r.Pipelines())
+							}
+						}
+						sqsMessagesProcessedTotal.With(prometheus.Labels{"status": status}).Add(1)
+						// pipelines deferred to a backlog that is not persisted are lost if the
+						// controller restarts, so the message is redelivered once visible again
+						if slices.ContainsFunc(messageResult.Reports, func(r reports.ReportResult) bool { return r.DeferredInMemory }) {
+							msgLogger.Info("pipelines deferred to a backlog that is not persisted, not deleting message")
+							return
+						}
 						_, err := l.sqsClient.DeleteMessage(ctx, &sqs.DeleteMessageInput{
 							QueueUrl:      aws.String(l.queueURL),
 							ReceiptHandle: msg.ReceiptHandle,
@@ -181,3 +224,36 @@ func (l *Listener) Start(ctx context.Context) error {
 	}
 
 }
+
+// retryAfter makes the message visible again once the threshold retry delay has passed,
+// instead of once the visibility timeout passes. The reports of the message were removed
+// from the queue of the scheduler, so they are only queued again once it is received again.
+func (l *Listener) retryAfter(ctx context.Context, msg sqstypes.Message) {
+	msgLogger := logf.FromContext(ctx)
+	_, err := l.sqsClient.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(l.queueURL),
+		ReceiptHandle:     msg.ReceiptHandle,
+		VisibilityTimeout: int32(l.thresholdRetryDelay.Seconds()),
+	})
+	if err != nil {
+		msgLogger.Error(err, "unable to change visibility of message, message is received again once the visibility timeout passes")
+	} else {
+		msgLogger.Info("active pipeline threshold reached, message is received again after delay", "delay", l.thresholdRetryDelay)
+	}
+}
+
+// messageSource returns the SQS source for reports received in the message.
+// Only the string and number message attributes are included.
+func messageSource(queueURL string, msg sqstypes.Message) policy.SQSSource {
+	attributes := make(map[string]string, len(msg.MessageAttributes))
+	for k, v := range msg.MessageAttributes {
+		if v.StringValue != nil {
+			attributes[k] = aws.ToString(v.StringValue)
+		}
+	}
+	return policy.SQSSource{
+		QueueURL:          queueURL,
+		MessageID:         aws.ToString(msg.MessageId),
+		MessageAttributes: attributes,
+	}
+}
@@ -17,7 +17,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
 	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
+	"github.com/crashappsec/chalkular/internal/policy"
 	"github.com/crashappsec/chalkular/internal/reports"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -27,8 +29,9 @@ import (
 
 // fakeSQSClient implements SQSClientAPI for tests.
 type fakeSQSClient struct {
-	receive func(context.Context, *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error)
-	delete  func(context.Context, *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error)
+	receive    func(context.Context, *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error)
+	delete     func(context.Context, *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error)
+	visibility func(context.Context, *sqs.ChangeMessageVisibilityInput) (*sqs.ChangeMessageVisibilityOutput, error)
 }
 
 func (f *fakeSQSClient) ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
@@ -42,23 +45,47 @@ func (f *fakeSQSClient) DeleteMessage(ctx context.Context, params *sqs.DeleteMes
 	return f.delete(ctx, params)
 }
 
+func (f *fakeSQSClient) ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, _ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	if f.visibility == nil {
+		return &sqs.ChangeMessageVisibilityOutput{}, nil
+	}
+	return f.visibility(ctx, params)
+}
+
 // fakeScheduler implements reports.SchedulerClient for tests.
 // Adjust the Enqueue signature here if the real interface differs.
 type fakeScheduler struct {
-	enqueue func(context.Context, []chalk.Report) reports.SchedulerResult
+	enqueue func(context.Context, []reports.ReceivedReport) <-chan reports.SchedulerResult
+	// enqueueErr, if set, is returned instead of calling enqueue
+	enqueueErr error
 }
 
 var _ reports.SchedulerClient = &fakeScheduler{}
 
-func (f *fakeScheduler) Enqueue(ctx context.Context, rs []chalk.Report) reports.SchedulerResult {
-	return f.enqueue(ctx, rs)
+func (f *fakeScheduler) Enqueue(ctx context.Context, rs []reports.ReceivedReport) (<-chan reports.SchedulerResult, error) {
+	if f.enqueueErr != nil {
+		return nil, f.enqueueErr
+	}
+	return f.enqueue(ctx, rs), nil
+}
+
+func (f *fakeScheduler) Submit(context.Context, []reports.ReceivedReport) error {
+	return f.enqueueErr
+}
+
+func (f *fakeScheduler) Evaluate(context.Context, string, []reports.ReceivedReport) ([]reports.PolicyEvaluation, error) {
+	return nil, nil
+}
+
+func (f *fakeScheduler) Lookup(context.Context, string) ([]chalkularv1beta1.ChalkReport, error) {
+	return nil, nil
 }
 
 // schedulerResult returns a channel pre-loaded with the given result,
 // mimicking a scheduler that has finished evaluating the reports.
-func schedulerResult(err error) reports.SchedulerResult {
-	ch := make(chan error, 1)
-	ch <- err
+func schedulerResult(result reports.SchedulerResult) <-chan reports.SchedulerResult {
+	ch := make(chan reports.SchedulerResult, 1)
+	ch <- result
 	return ch
 }
 
@@ -122,6 +149,9 @@ var _ = Describe("Listener.Start", func() {
 			MessageId:     aws.String("msg-1"),
 			ReceiptHandle: aws.String("rh-1"),
 			Body:          aws.String(`{"CHALK_ID":"abc123"}`),
+			MessageAttributes: map[string]sqstypes.MessageAttributeValue{
+				"team": {DataType: aws.String("String"), StringValue: aws.String("platform")},
+			},
 		}
 	)
 
@@ -140,18 +170,18 @@ var _ = Describe("Listener.Start", func() {
 			receive: serveOnce(),
 		}
 		scheduler = &fakeScheduler{
-			enqueue: func(context.Context, []chalk.Report) reports.SchedulerResult {
-				return schedulerResult(nil)
+			enqueue: func(context.Context, []reports.ReceivedReport) <-chan reports.SchedulerResult {
+				return schedulerResult(reports.SchedulerResult{})
 			},
 		}
 		// nolint:unparam
-		parse = func(context.Context, sqstypes.Message) ([]chalk.Report, error) {
-			return []chalk.Report{report}, nil
+		parse = func(context.Context, sqstypes.Message) ([]reports.ReceivedReport, error) {
+			return []reports.ReceivedReport{{Report: report}}, nil
 		}
 
 		var err error
 		listener, err = NewListener(client, scheduler, queueURL,
-			func(ctx context.Context, m sqstypes.Message) ([]chalk.Report, error) {
+			func(ctx context.Context, m sqstypes.Message) ([]reports.ReceivedReport, error) {
 				return parse(ctx, m)
 			})
 		Expect(err).NotTo(HaveOccurred())
@@ -184,6 +214,7 @@ var _ = Describe("Listener.Start", func() {
 			Eventually(inputs).Should(Receive(&input))
 			Expect(aws.ToString(input.QueueUrl)).To(Equal(queueURL))
 			Expect(input.MaxNumberOfMessages).To(Equal(int32(10)))
+			Expect(input.MessageAttributeNames).To(ConsistOf("All"))
 			Expect(input.WaitTimeSeconds).To(Equal(int32(20)))
 			Expect(input.VisibilityTimeout).To(Equal(int32(60)))
 
@@ -231,10 +262,10 @@ var _ = Describe("Listener.Start", func() {
 			deleted := counterDelta(sqsMessagesDeletedTotal)
 
 			client.receive = serveOnce(message)
-			enqueued := make(chan []chalk.Report, 1)
-			scheduler.enqueue = func(_ context.Context, rs []chalk.Report) reports.SchedulerResult {
+			enqueued := make(chan []reports.ReceivedReport, 1)
+			scheduler.enqueue = func(_ context.Context, rs []reports.ReceivedReport) <-chan reports.SchedulerResult {
 				enqueued <- rs
-				return schedulerResult(nil)
+				return schedulerResult(reports.SchedulerResult{})
 			}
 			deletes := make(chan *sqs.DeleteMessageInput, 1)
 			client.delete = func(_ context.Context, in *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
@@ -243,7 +274,17 @@ var _ = Describe("Listener.Start", func() {
 			}
 			done := startListener()
 
-			Eventually(enqueued).Should(Receive(ConsistOf(report)))
+			Eventually(enqueued).Should(Receive(ConsistOf(reports.ReceivedReport{
+				Report: report,
+				Source: policy.Source{
+					Method: policy.SourceMethodSQS,
+					SQS: policy.SQSSource{
+						QueueURL:          queueURL,
+						MessageID:         "msg-1",
+						MessageAttributes: map[string]string{"team": "platform"},
+					},
+				},
+			})))
 
 			var input *sqs.DeleteMessageInput
 			Eventually(deletes).Should(Receive(&input))
@@ -264,13 +305,13 @@ var _ = Describe("Listener.Start", func() {
 			failed := counterDelta(sqsMessagesProcessedTotal.With(prometheus.Labels{"status": "failure"}))
 
 			client.receive = serveOnce(message)
-			parse = func(context.Context, sqstypes.Message) ([]chalk.Report, error) {
+			parse = func(context.Context, sqstypes.Message) ([]reports.ReceivedReport, error) {
 				return nil, errors.New("bad report")
 			}
 			var enqueueCalled, deleteCalled atomic.Bool
-			scheduler.enqueue = func(context.Context, []chalk.Report) reports.SchedulerResult {
+			scheduler.enqueue = func(context.Context, []reports.ReceivedReport) <-chan reports.SchedulerResult {
 				enqueueCalled.Store(true)
-				return schedulerResult(nil)
+				return schedulerResult(reports.SchedulerResult{})
 			}
 			client.delete = func(context.Context, *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
 				deleteCalled.Store(true)
@@ -292,9 +333,111 @@ var _ = Describe("Listener.Start", func() {
 			failed := counterDelta(sqsMessagesProcessedTotal.With(prometheus.Labels{"status": "failure"}))
 
 			client.receive = serveOnce(message)
-			scheduler.enqueue = func(context.Context, []chalk.Report) reports.SchedulerResult {
-				return schedulerResult(errors.New("evaluation failed"))
+			scheduler.enqueue = func(context.Context, []reports.ReceivedReport) <-chan reports.SchedulerResult {
+				return schedulerResult(reports.SchedulerResult{Err: errors.New("evaluation failed")})
+			}
+			var deleteCalled atomic.Bool
+			client.delete = func(context.Context, *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
+				deleteCalled.Store(true)
+				return &sqs.DeleteMessageOutput{}, nil
+			}
+			done := startListener()
+
+			Eventually(failed).Should(Equal(1.0))
+			Consistently(deleteCalled.Load).Should(BeFalse())
+
+			cancel()
+			Eventually(done).Should(Receive(BeNil()))
+		})
+	})
+
+	When("the active pipeline threshold is reached", func() {
+		It("should make the message visible again after a delay without deleting it", func() {
+			client.receive = serveOnce(message)
+			var enqueued atomic.Int32
+			scheduler.enqueue = func(context.Context, []reports.ReceivedReport) <-chan reports.SchedulerResult {
+				enqueued.Add(1)
+				return schedulerResult(reports.SchedulerResult{Err: reports.ErrPipelineThreshold})
+			}
+			visibilities := make(chan *sqs.ChangeMessageVisibilityInput, 1)
+			client.visibility = func(_ context.Context, in *sqs.ChangeMessageVisibilityInput) (*sqs.ChangeMessageVisibilityOutput, error) {
+				visibilities <- in
+				return &sqs.ChangeMessageVisibilityOutput{}, nil
+			}
+			var deleteCalled atomic.Bool
+			client.delete = func(context.Context, *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
+				deleteCalled.Store(true)
+				return &sqs.DeleteMessageOutput{}, nil
 			}
+			done := startListener()
+
+			var input *sqs.ChangeMessageVisibilityInput
+			Eventually(visibilities).Should(Receive(&input))
+			Expect(aws.ToString(input.ReceiptHandle)).To(Equal("rh-1"))
+			Expect(input.VisibilityTimeout).To(Equal(int32(30)))
+			Consistently(deleteCalled.Load).Should(BeFalse())
+			Expect(enqueued.Load()).To(Equal(int32(1)))
+
+			cancel()
+			Eventually(done).Should(Receive(BeNil()))
+		})
+	})
+
+	When("a report in the message is invalid", func() {
+		It("should record the failure and delete the message", func() {
+			failed := counterDelta(sqsMessagesProcessedTotal.With(prometheus.Labels{"status": "failure"}))
+			deleted := counterDelta(sqsMessagesDeletedTotal)
+
+			client.receive = serveOnce(message)
+			scheduler.enqueue = func(context.Context, []reports.ReceivedReport) <-chan reports.SchedulerResult {
+				return schedulerResult(reports.SchedulerResult{Reports: []reports.ReportResult{
+					{ActionID: "a1b2c3d4"},
+					{Err: reports.ErrInvalidReport},
+				}})
+			}
+			done := startListener()
+
+			Eventually(failed).Should(Equal(1.0))
+			Eventually(deleted).Should(Equal(1.0))
+
+			cancel()
+			Eventually(done).Should(Receive(BeNil()))
+		})
+	})
+
+	When("pipelines are deferred to a backlog that is not persisted", func() {
+		It("should leave the message on the queue", func() {
+			succeeded := counterDelta(sqsMessagesProcessedTotal.With(prometheus.Labels{"status": "success"}))
+			deleted := counterDelta(sqsMessagesDeletedTotal)
+
+			client.receive = serveOnce(message)
+			scheduler.enqueue = func(context.Context, []reports.ReceivedReport) <-chan reports.SchedulerResult {
+				return schedulerResult(reports.SchedulerResult{Reports: []reports.ReportResult{{
+					ActionID: "a1b2c3d4",
+					Policies: []chalkularv1beta1.ChalkReportPolicyResult{{
+						Name:              "images",
+						Namespace:         "scans",
+						DeferredPipelines: 1,
+					}},
+					DeferredInMemory: true,
+				}}})
+			}
+			done := startListener()
+
+			Eventually(succeeded).Should(Equal(1.0))
+			Consistently(deleted).Should(BeZero())
+
+			cancel()
+			Eventually(done).Should(Receive(BeNil()))
+		})
+	})
+
+	When("the reports cannot be queued", func() {
+		It("should leave the message on the queue", func() {
+			failed := counterDelta(sqsMessagesProcessedTotal.With(prometheus.Labels{"status": "failure"}))
+
+			client.receive = serveOnce(message)
+			scheduler.enqueueErr = errors.New("queue unavailable")
 			var deleteCalled atomic.Bool
 			client.delete = func(context.Context, *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
 				deleteCalled.Store(true)
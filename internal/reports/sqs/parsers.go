@@ -18,19 +18,24 @@ import (
 	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"github.com/crashappsec/chalkular/api/v1beta1/chalk"
 	sqsapi "github.com/crashappsec/chalkular/api/v1beta1/sqs"
+	"github.com/crashappsec/chalkular/internal/policy"
+	"github.com/crashappsec/chalkular/internal/reports"
 	"github.com/hashicorp/go-multierror"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-type ChalkReportParser = func(context.Context, sqstypes.Message) ([]chalk.Report, error)
+// ChalkReportParser parses the chalk reports from an SQS message. The SQS
+// source of the reports is set by the [Listener], parsers should only set
+// the source of the report if it was read from elsewhere (i.e. S3)
+type ChalkReportParser = func(context.Context, sqstypes.Message) ([]reports.ReceivedReport, error)
 
-func RawReportParser(ctx context.Context, msg sqstypes.Message) ([]chalk.Report, error) {
+func RawReportParser(ctx context.Context, msg sqstypes.Message) ([]reports.ReceivedReport, error) {
 	l := logf.FromContext(ctx)
 	l.Info("parsing report from message body")
 
 	report := make(chalk.Report)
 	err := json.Unmarshal([]byte(*msg.Body), &report)
-	return []chalk.Report{report}, err
+	return []reports.ReceivedReport{{Report: report}}, err
 }
 
 var _ ChalkReportParser = RawReportParser
@@ -40,7 +45,7 @@ type S3ClientAPI interface {
 }
 
 func S3EventReportParser(s3client S3ClientAPI) ChalkReportParser {
-	return func(ctx context.Context, msg sqstypes.Message) ([]chalk.Report, error) {
+	return func(ctx context.Context, msg sqstypes.Message) ([]reports.ReceivedReport, error) {
 		l := logf.FromContext(ctx)
 		l.Info("parsing S3 notification from message body")
 
@@ -52,8 +57,8 @@ func S3EventReportParser(s3client S3ClientAPI) ChalkReportParser {
 		}
 		l.Info("processing S3 records", "records-count", len(event.Records))
 		var (
-			merr    *multierror.Error
-			reports []chalk.Report
+			merr     *multierror.Error
+			received []reports.ReceivedReport
 		)
 		for _, record := range event.Records {
 			object := record.S3.Object
@@ -82,10 +87,19 @@ func S3EventReportParser(s3client S3ClientAPI) ChalkReportParser {
 				merr = multierror.Append(err, err)
 				continue
 			}
-			reports = append(reports, objectReports...)
+			source := policy.Source{
+				S3: policy.S3Source{
+					Bucket:    bucket.Name,
+					Key:       object.URLDecodedKey,
+					EventTime: record.EventTime,
+				},
+			}
+			for _, report := range objectReports {
+				received = append(received, reports.ReceivedReport{Report: report, Source: source})
+			}
 
 		}
 
-		return reports, merr.ErrorOrNil()
+		return received, merr.ErrorOrNil()
 	}
 }
@@ -13,10 +13,12 @@ import (
 	"errors"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/crashappsec/chalkular/internal/policy"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -50,7 +52,7 @@ var _ = Describe("RawReportParser", func() {
 			reports, err := RawReportParser(ctx, msgWithBody(`{"CHALK_ID":"abc123"}`))
 			Expect(err).NotTo(HaveOccurred())
 			Expect(reports).To(HaveLen(1))
-			Expect(reports[0]).To(HaveKeyWithValue("CHALK_ID", "abc123"))
+			Expect(reports[0].Report).To(HaveKeyWithValue("CHALK_ID", "abc123"))
 		})
 	})
 	When("when the message body is invalid JSON", func() {
@@ -88,7 +90,7 @@ var _ = Describe("RawReportParser", func() {
 var _ = Describe("S3EventReportParser", func() {
 	const (
 		oneRecordEvent = `{"Records":[
-			{"s3":{"bucket":{"name":"reports"},"object":{"key":"report.json","eTag":"etag-1"}}}
+			{"eventTime":"2026-01-02T03:04:05Z","s3":{"bucket":{"name":"reports"},"object":{"key":"report.json","eTag":"etag-1"}}}
 		]}`
 		twoRecordEvent = `{"Records":[
 			{"s3":{"bucket":{"name":"reports"},"object":{"key":"a.json","eTag":"etag-a"}}},
@@ -137,8 +139,15 @@ var _ = Describe("S3EventReportParser", func() {
 
 			Expect(err).NotTo(HaveOccurred())
 			Expect(reports).To(HaveLen(2))
-			Expect(reports[0]).To(HaveKeyWithValue("CHALK_ID", "a"))
-			Expect(reports[1]).To(HaveKeyWithValue("CHALK_ID", "b"))
+			Expect(reports[0].Report).To(HaveKeyWithValue("CHALK_ID", "a"))
+			Expect(reports[1].Report).To(HaveKeyWithValue("CHALK_ID", "b"))
+			for _, r := range reports {
+				Expect(r.Source.S3).To(Equal(policy.S3Source{
+					Bucket:    "reports",
+					Key:       "report.json",
+					EventTime: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+				}))
+			}
 
 			Expect(input).NotTo(BeNil())
 			Expect(aws.ToString(input.Bucket)).To(Equal("reports"))
@@ -186,7 +195,7 @@ var _ = Describe("S3EventReportParser", func() {
 
 			Expect(err).To(MatchError(ContainSubstring("boom")))
 			Expect(reports).To(HaveLen(1))
-			Expect(reports[0]).To(HaveKeyWithValue("CHALK_ID", "b"))
+			Expect(reports[0].Report).To(HaveKeyWithValue("CHALK_ID", "b"))
 		})
 	})
 })
@@ -19,6 +19,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	chalkocularcrashoverriderunv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/internal/policy"
 )
 
 // nolint:unused
@@ -26,9 +27,11 @@ import (
 var chalkreportpolicylog = logf.Log.WithName("chalkreportpolicy-resource")
 
 // SetupChalkReportPolicyWebhookWithManager registers the webhook for ChalkReportPolicy in the manager.
-func SetupChalkReportPolicyWebhookWithManager(mgr ctrl.Manager, clusterDownloaderName string) error {
+func SetupChalkReportPolicyWebhookWithManager(mgr ctrl.Manager, clusterDownloaderName string, policyCompiler *policy.Compiler) error {
 	return ctrl.NewWebhookManagedBy(mgr, &chalkocularcrashoverriderunv1beta1.ChalkReportPolicy{}).
-		WithValidator(&ChalkReportPolicyCustomValidator{}).
+		WithValidator(&ChalkReportPolicyCustomValidator{
+			policyCompiler: policyCompiler,
+		}).
 		WithDefaulter(&ChalkReportPolicyCustomDefaulter{
 			downloader:     clusterDownloaderName,
 			downloaderKind: "ClusterDownloader",
@@ -60,7 +63,9 @@ func (d *ChalkReportPolicyCustomDefaulter) Default(_ context.Context, obj *chalk
 
 // ChalkReportPolicyCustomValidator struct is responsible for validating the ChalkReportPolicy resource
 // when it is created, updated, or deleted.
-type ChalkReportPolicyCustomValidator struct{}
+type ChalkReportPolicyCustomValidator struct {
+	policyCompiler *policy.Compiler
+}
 
 // ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type ChalkReportPolicy.
 func (v *ChalkReportPolicyCustomValidator) ValidateCreate(_ context.Context, obj *chalkocularcrashoverriderunv1beta1.ChalkReportPolicy) (admission.Warnings, error) {
@@ -79,20 +84,22 @@ func (v *ChalkReportPolicyCustomValidator) ValidateUpdate(_ context.Context, old
 	return nil, nil
 }
 
-func (v *ChalkReportPolicyCustomValidator) validate(policy *chalkocularcrashoverriderunv1beta1.ChalkReportPolicy) (admission.Warnings, error) {
+func (v *ChalkReportPolicyCustomValidator) validate(reportPolicy *chalkocularcrashoverriderunv1beta1.ChalkReportPolicy) (admission.Warnings, error) {
 	var allErrs field.ErrorList
-	target := policy.Spec.PipelineTemplate.Spec.Target
+	target := reportPolicy.Spec.PipelineTemplate.Spec.Target
 	if target.Identifier != "" || target.Version != "" {
 		path := field.NewPath("spec").Child("pipelineTemplate").Child("spec").Child("target")
 		allErrs = append(allErrs,
 			field.Invalid(path, target, "target should not bet set and instead should be specified by 'extraction.target'"))
 	}
 
+	allErrs = append(allErrs, v.policyCompiler.Validate(reportPolicy)...)
+
 	if len(allErrs) == 0 {
 		return nil, nil
 	}
 
-	return nil, apierrors.NewInvalid(schema.GroupKind{Group: "chalk.ocular.crashoverride.run", Kind: "ChalkReportPolicy"}, policy.Name, allErrs)
+	return nil, apierrors.NewInvalid(schema.GroupKind{Group: "chalk.ocular.crashoverride.run", Kind: "ChalkReportPolicy"}, reportPolicy.Name, allErrs)
 }
 
 // ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type ChalkReportPolicy.
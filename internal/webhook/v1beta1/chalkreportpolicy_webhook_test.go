@@ -13,6 +13,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	chalkularv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/internal/policy"
 	ocularv1beta1 "github.com/crashappsec/ocular/api/v1beta1"
 )
 
@@ -27,7 +28,11 @@ var _ = Describe("ChalkReportPolicy Webhook", func() {
 	BeforeEach(func() {
 		obj = &chalkularv1beta1.ChalkReportPolicy{}
 		oldObj = &chalkularv1beta1.ChalkReportPolicy{}
-		validator = ChalkReportPolicyCustomValidator{}
+		policyCompiler, err := policy.NewCompiler(5, policy.CompilerOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		validator = ChalkReportPolicyCustomValidator{
+			policyCompiler: policyCompiler,
+		}
 		Expect(validator).NotTo(BeNil(), "Expected validator to be initialized")
 		defaulter = ChalkReportPolicyCustomDefaulter{
 			downloader:     testClusterDownloader,
@@ -61,6 +66,42 @@ var _ = Describe("ChalkReportPolicy Webhook", func() {
 			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
 		})
 
+		It("Should admit creation if all CEL expressions compile", func() {
+			By("setting valid CEL expressions")
+			obj.Spec.MatchCondition = "report._ACTION_ID == 'test'"
+			obj.Spec.Extraction = chalkularv1beta1.ChalkReportPolicyExtraction{
+				ForEach:          new("report._CHALKS"),
+				Target:           "{'identifier': each._REPO}",
+				DownloaderParams: new("report._X_DL_PARAMS"),
+				ProfileParams:    new("{'KEY': 'value'}"),
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny creation if a CEL expression fails to compile", func() {
+			By("setting an invalid match condition")
+			obj.Spec.MatchCondition = "invalid CEL expression"
+			obj.Spec.Extraction.Target = "{'identifier': 'test'}"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.matchCondition")))
+		})
+
+		It("Should deny creation if a CEL expression returns the wrong type", func() {
+			By("setting expressions with output types that can never match")
+			obj.Spec.MatchCondition = "'not a boolean'"
+			obj.Spec.Extraction = chalkularv1beta1.ChalkReportPolicyExtraction{
+				ForEach:       new("{'not': 'a list'}"),
+				Target:        "{'identifier': 1}",
+				ProfileParams: new("[1, 2, 3]"),
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.matchCondition"))
+			Expect(err.Error()).To(ContainSubstring("spec.extraction.forEach"))
+			Expect(err.Error()).To(ContainSubstring("spec.extraction.target"))
+			Expect(err.Error()).To(ContainSubstring("spec.extraction.profileParams"))
+		})
+
 		// It("Should deny creation if no media types are set", func() {
 		// 	By("not setting the target")
 		// 	obj.Spec.PipelineTemplate.Spec.Target = v1beta1.Target{}
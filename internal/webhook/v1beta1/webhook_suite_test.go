@@ -32,6 +32,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	chalkularocularcrashoverriderunv1beta1 "github.com/crashappsec/chalkular/api/v1beta1"
+	"github.com/crashappsec/chalkular/internal/policy"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -105,7 +106,10 @@ var _ = BeforeSuite(func() {
 	})
 	Expect(err).NotTo(HaveOccurred())
 
-	err = SetupChalkReportPolicyWebhookWithManager(mgr, testClusterDownloader)
+	policyCompiler, err := policy.NewCompiler(5, policy.CompilerOptions{})
+	Expect(err).NotTo(HaveOccurred())
+
+	err = SetupChalkReportPolicyWebhookWithManager(mgr, testClusterDownloader, policyCompiler)
 	Expect(err).NotTo(HaveOccurred())
 
 	// +kubebuilder:scaffold:webhook
@@ -19,6 +19,7 @@ import (
 
 type ApiV1beta1Interface interface {
 	RESTClient() rest.Interface
+	ChalkReportsGetter
 	ChalkReportPoliciesGetter
 }
 
@@ -27,6 +28,10 @@ type ApiV1beta1Client struct {
 	restClient rest.Interface
 }
 
+func (c *ApiV1beta1Client) ChalkReports() ChalkReportInterface {
+	return newChalkReports(c)
+}
+
 func (c *ApiV1beta1Client) ChalkReportPolicies(namespace string) ChalkReportPolicyInterface {
 	return newChalkReportPolicies(c, namespace)
 }
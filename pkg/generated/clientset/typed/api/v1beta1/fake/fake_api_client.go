@@ -19,6 +19,10 @@ type FakeApiV1beta1 struct {
 	*testing.Fake
 }
 
+func (c *FakeApiV1beta1) ChalkReports() v1beta1.ChalkReportInterface {
+	return newFakeChalkReports(c)
+}
+
 func (c *FakeApiV1beta1) ChalkReportPolicies(namespace string) v1beta1.ChalkReportPolicyInterface {
 	return newFakeChalkReportPolicies(c, namespace)
 }
@@ -9,4 +9,6 @@
 
 package v1beta1
 
+type ChalkReportExpansion interface{}
+
 type ChalkReportPolicyExpansion interface{}